@@ -35,7 +35,12 @@ func initGeminiExplainer() (*geminiExplainer, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newGeminiExplainer(context.Background(), apiKey, model, timeout)
+	explainer, err := newGeminiExplainer(context.Background(), apiKey, model, timeout)
+	if err != nil {
+		return nil, err
+	}
+	explainer.fallbackModels = loadGeminiFallbackModels()
+	return explainer, nil
 }
 
 func loadGeminiTimeout() (time.Duration, error) {
@@ -56,6 +61,7 @@ func loadGeminiTimeout() (time.Duration, error) {
 }
 
 func askHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	ctx = withChatPersona(ctx, update.Message.Chat.ID)
 	if textExplainer == nil {
 		appotel.RecordOutcome(ctx, "not_configured")
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
@@ -121,7 +127,7 @@ func askHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 
 	respondInBurmese := shouldRespondInBurmese(update.Message.Text, quoted)
 
-	explanation, err := answerAskQuestion(ctx, b, update.Message, repliedPhoto, quoted, question, respondInBurmese)
+	explanation, sources, err := answerAskQuestion(ctx, b, update.Message, repliedPhoto, quoted, question, respondInBurmese)
 	if errors.Is(err, errAskPhotoDownload) {
 		appotel.RecordOutcome(ctx, "error")
 		log.Error().Err(err).Msg("Failed to download replied photo")
@@ -144,7 +150,8 @@ func askHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	}
 
 	appotel.RecordOutcome(ctx, "success")
-	sendOrEditExplainResult(ctx, b, update, thinkingMsg, thinkingErr, explanation)
+	sendOrEditExplainResultWithMarkup(ctx, b, update, thinkingMsg, thinkingErr, explanation,
+		rememberGroundedAnswer(explanation, sources))
 }
 
 // askUsageText explains how to ask a question. Direct messages need no mention,
@@ -161,6 +168,8 @@ func askUsageText(message *models.Message) string {
 
 var errAskPhotoDownload = errors.New("download replied photo")
 
+// answerAskQuestion returns the answer and, for grounded answers, the web
+// results it was grounded in.
 func answerAskQuestion(
 	ctx context.Context,
 	b *bot.Bot,
@@ -168,53 +177,82 @@ func answerAskQuestion(
 	photo *models.PhotoSize,
 	quoted, question string,
 	respondInBurmese bool,
-) (string, error) {
+) (string, []promptWebResult, error) {
 	if photo == nil {
 		return answerTextQuestion(ctx, message, quoted, question, respondInBurmese)
 	}
 
 	imageBytes, mimeType, err := downloadTelegramPhoto(ctx, b, photo.FileID)
 	if err != nil {
-		return "", fmt.Errorf("%w: %w", errAskPhotoDownload, err)
+		return "", nil, fmt.Errorf("%w: %w", errAskPhotoDownload, err)
 	}
+	var explanation string
 	if quoted != "" {
-		return textExplainer.explainWithTextAndImage(ctx, quoted, imageBytes, mimeType, question, respondInBurmese)
+		explanation, err = textExplainer.explainWithTextAndImage(ctx, quoted, imageBytes, mimeType, question, respondInBurmese)
+	} else {
+		explanation, err = textExplainer.explainWithImage(ctx, imageBytes, mimeType, question, respondInBurmese)
 	}
-	return textExplainer.explainWithImage(ctx, imageBytes, mimeType, question, respondInBurmese)
+	return explanation, nil, err
 }
 
 // answerTextQuestion answers a text-only ask request. When the question or
-// quoted message references a URL and Parallel Extract is configured, the
-// answer is grounded in the extracted page content — checked first, since an
-// explicit link makes freshness classification unnecessary. Otherwise, when
-// Parallel Search is configured and Gemini judges the question to need fresh
-// web data, the answer is grounded in Parallel Search excerpts. Retrieval
-// failures on either path fall back to the plain Gemini answer so users never
-// see a retrieval error; once an extraction succeeds, though, an explainer
-// failure (blocked, timeout, ...) propagates instead of retrying ungrounded,
-// which would silently discard a safety verdict.
-func answerTextQuestion(ctx context.Context, message *models.Message, quoted string, question string, respondInBurmese bool) (string, error) {
-	if extractor := newParallelExtractor(); extractor != nil {
-		urls, strippedQuestion := extractQuestionURLs(message, question, quoted, extractor.maxURLs)
+// quoted message references a URL, the answer is grounded in the extracted
+// page content (see extractPages) — checked first, since an explicit link
+// makes freshness classification unnecessary. YouTube links are grounded in
+// their transcript, or in Gemini watching the first video when none has
+// usable captions and no other page was extracted. GitHub links are read
+// from the GitHub API, falling back to page extraction. Otherwise, when a
+// web search provider is configured and the question needs fresh web data
+// (see planSearch), the answer is grounded in excerpts from the first
+// provider in the WEB_SEARCH_PROVIDERS chain that returns results. Retrieval
+// failures fall back to the plain Gemini answer; once an extraction
+// succeeds, though, an explainer failure (blocked, timeout, ...) propagates
+// instead of retrying ungrounded, which would silently discard a safety
+// verdict. Grounded answers also return the results they used, for the
+// "Sources" button. Links ruled out by the domain allow/deny lists are
+// skipped, and the answer opens with a note naming their sites.
+func answerTextQuestion(ctx context.Context, message *models.Message, quoted string, question string, respondInBurmese bool) (answer string, sources []promptWebResult, err error) {
+	if loadExtractEnabled() {
+		urls, denied, strippedQuestion := extractQuestionURLs(message, question, quoted, loadExtractMaxURLs())
+		if len(denied) > 0 {
+			log.Info().Int("denied_count", len(denied)).Msg("Question references links from blocked domains; skipping them")
+			recordDeniedURLs(ctx, denied)
+			defer func() {
+				if err == nil {
+					answer = deniedDomainsNote(denied) + "\n\n" + answer
+				}
+			}()
+		}
 		if len(urls) > 0 {
-			objective := extractObjectiveFor(strippedQuestion)
 			log.Info().
 				Int("url_count", len(urls)).
-				Msg("Question references URLs; running Parallel extract")
-			results, extractErr := extractor.extract(ctx, urls, objective)
-			switch {
-			case extractErr != nil:
-				log.Warn().Err(extractErr).Msg("Parallel extract failed; answering without page content")
-			case len(results) > 0:
-				return textExplainer.explainWithExtractResults(ctx, quoted, question, results, respondInBurmese)
-			default:
-				log.Warn().Msg("Parallel extract returned no usable excerpts; answering without page content")
+				Msg("Question references URLs; extracting page content")
+			videos, pages := splitYouTubeURLs(urls)
+			githubURLs, pages := splitGitHubURLs(pages)
+			objective := extractObjectiveFor(strippedQuestion)
+			results := newYouTubeTranscriptFetcher().fetchTranscripts(ctx, videos, objective, transcriptLanguages(respondInBurmese))
+			githubResults, unresolved := newGitHubLinkResolver().resolve(ctx, githubURLs)
+			results = append(results, githubResults...)
+			pages = append(pages, unresolved...)
+			if len(pages) > 0 {
+				results = append(results, extractPages(ctx, domainPolicyFor(message), pages, objective)...)
+			}
+			if len(results) > 0 {
+				answer, err := textExplainer.explainWithExtractResults(ctx, quoted, question, results, respondInBurmese)
+				return answer, toPromptWebResults(results), err
+			}
+			if len(videos) > 0 {
+				answer, err := textExplainer.explainWithVideo(ctx, videos[0], quoted, strippedQuestion, respondInBurmese)
+				if err == nil || errors.Is(err, ErrExplainBlocked) || errors.Is(err, ErrExplainTimeout) {
+					return answer, nil, err
+				}
+				log.Warn().Err(err).Msg("Gemini video understanding failed; answering without the video")
 			}
 		}
 	}
 
-	if searcher := newParallelSearcher(); searcher != nil {
-		plan, err := textExplainer.classifySearchNeed(ctx, quoted, question)
+	if providers := loadWebSearchProviders(); len(providers) > 0 {
+		plan, err := planSearch(ctx, quoted, question)
 		switch {
 		case err != nil:
 			log.Warn().Err(err).Msg("Search-need classification failed; answering without web search")
@@ -222,18 +260,48 @@ func answerTextQuestion(ctx context.Context, message *models.Message, quoted str
 			log.Info().
 				Str("objective", plan.Objective).
 				Strs("search_queries", plan.SearchQueries).
-				Msg("Question needs fresh information; running Parallel search")
-			results, searchErr := searcher.search(ctx, plan.Objective, plan.SearchQueries)
-			switch {
-			case searchErr != nil:
-				log.Warn().Err(searchErr).Msg("Parallel search failed; answering without web search")
-			case len(results) > 0:
-				return textExplainer.explainWithSearchResults(ctx, quoted, question, results, respondInBurmese)
+				Msg("Question needs fresh information; running web search")
+			if results := searchWebChain(ctx, providers, plan.Objective, plan.SearchQueries); len(results) > 0 {
+				answer, err := textExplainer.explainWithWebResults(ctx, quoted, question, results, respondInBurmese)
+				return answer, results, err
 			}
+			log.Warn().Msg("Web search produced no results; answering without web search")
 		}
 	}
 
-	return textExplainer.explainWithLanguage(ctx, quoted, question, respondInBurmese)
+	answer, err = textExplainer.explainWithLanguage(ctx, quoted, question, respondInBurmese)
+	return answer, nil, err
+}
+
+// extractPages fetches page content for urls, trying Parallel Extract first
+// and falling back to the local fetcher when Parallel is unconfigured, fails,
+// or returns nothing usable. The local fetcher follows redirects only within
+// policy. It returns nil when neither produced excerpts.
+func extractPages(ctx context.Context, policy domainPolicy, urls []string, objective string) []parallelExtractResult {
+	if extractor := newParallelExtractor(); extractor != nil {
+		results, err := extractor.extract(ctx, urls, objective)
+		switch {
+		case err != nil:
+			log.Warn().Err(err).Msg("Parallel extract failed; trying local page fetch")
+		case len(results) > 0:
+			return results
+		default:
+			log.Warn().Msg("Parallel extract returned no usable excerpts; trying local page fetch")
+		}
+	}
+
+	if extractor := newLocalExtractor(policy); extractor != nil {
+		results, err := extractor.extract(ctx, urls, objective)
+		switch {
+		case err != nil:
+			log.Warn().Err(err).Msg("Local extract failed; answering without page content")
+		case len(results) > 0:
+			return results
+		default:
+			log.Warn().Msg("Local extract returned no usable excerpts; answering without page content")
+		}
+	}
+	return nil
 }
 
 func allowExplainRequest(message *models.Message) (bool, time.Duration) {
@@ -260,15 +328,30 @@ func sendOrEditExplainResult(
 	thinkingMsg *models.Message,
 	thinkingErr error,
 	text string,
+) {
+	sendOrEditExplainResultWithMarkup(ctx, b, update, thinkingMsg, thinkingErr, text, nil)
+}
+
+// sendOrEditExplainResultWithMarkup is sendOrEditExplainResult with an
+// inline keyboard attached to whichever message ends up holding the text.
+func sendOrEditExplainResultWithMarkup(
+	ctx context.Context,
+	b *bot.Bot,
+	update *models.Update,
+	thinkingMsg *models.Message,
+	thinkingErr error,
+	text string,
+	markup models.ReplyMarkup,
 ) {
 	formatted := formatTelegramMarkdown(text)
 
 	if thinkingErr == nil && thinkingMsg != nil {
 		_, editErr := b.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    update.Message.Chat.ID,
-			MessageID: thinkingMsg.ID,
-			Text:      formatted,
-			ParseMode: models.ParseModeMarkdown,
+			ChatID:      update.Message.Chat.ID,
+			MessageID:   thinkingMsg.ID,
+			Text:        formatted,
+			ParseMode:   models.ParseModeMarkdown,
+			ReplyMarkup: markup,
 		})
 		if editErr == nil {
 			return
@@ -280,9 +363,10 @@ func sendOrEditExplainResult(
 			Msg("Failed to edit markdown response; trying escaped fallback")
 
 		_, escapedEditErr := b.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    update.Message.Chat.ID,
-			MessageID: thinkingMsg.ID,
-			Text:      text,
+			ChatID:      update.Message.Chat.ID,
+			MessageID:   thinkingMsg.ID,
+			Text:        text,
+			ReplyMarkup: markup,
 		})
 		if escapedEditErr == nil {
 			return
@@ -299,6 +383,7 @@ func sendOrEditExplainResult(
 		MessageThreadID: update.Message.MessageThreadID,
 		Text:            formatted,
 		ParseMode:       models.ParseModeMarkdown,
+		ReplyMarkup:     markup,
 		ReplyParameters: &models.ReplyParameters{
 			MessageID:                update.Message.ID,
 			AllowSendingWithoutReply: true,
@@ -316,6 +401,7 @@ func sendOrEditExplainResult(
 		ChatID:          update.Message.Chat.ID,
 		MessageThreadID: update.Message.MessageThreadID,
 		Text:            text,
+		ReplyMarkup:     markup,
 		ReplyParameters: &models.ReplyParameters{
 			MessageID:                update.Message.ID,
 			AllowSendingWithoutReply: true,
@@ -712,6 +798,7 @@ func shouldHandlePhotoAsk(update *models.Update) bool {
 }
 
 func photoAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	ctx = withChatPersona(ctx, update.Message.Chat.ID)
 	if textExplainer == nil {
 		appotel.RecordOutcome(ctx, "not_configured")
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
@@ -0,0 +1,274 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	// sourcesCallbackPrefix starts the callback data of the "Sources" button
+	// under grounded answers: "src:show:<token>" or "src:hide:<token>".
+	sourcesCallbackPrefix = "src:"
+	sourcesActionShow     = "show"
+	sourcesActionHide     = "hide"
+
+	// Grounded answers are kept this long so their button keeps working;
+	// older buttons answer with a short "expired" notice instead.
+	askSourcesTTL        = 24 * time.Hour
+	askSourcesMaxEntries = 1000
+
+	maxListedSources     = 10
+	maxSourceTitleLength = 80
+
+	// telegramMaxMessageLength is Telegram's cap on message text. An
+	// expanded answer keeps at least minSourcesRoom of it for the list.
+	telegramMaxMessageLength = 4096
+	minSourcesRoom           = 600
+)
+
+// groundedAnswer is what a "Sources" button needs to redraw its message:
+// Telegram callback data is capped at 64 bytes, so the answer and its
+// sources stay server-side behind a short token.
+type groundedAnswer struct {
+	text    string
+	sources []promptWebResult
+}
+
+var askSourcesStore = newTTLCache[groundedAnswer]("ask_sources", askSourcesTTL, askSourcesMaxEntries)
+
+// rememberGroundedAnswer stores a grounded answer and returns the keyboard
+// to attach to it, or nil when there is nothing to list.
+func rememberGroundedAnswer(text string, sources []promptWebResult) models.ReplyMarkup {
+	sources = dedupeSources(sources)
+	if len(sources) == 0 {
+		return nil
+	}
+	token, err := generateNonce()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to create sources token; sending answer without sources button")
+		return nil
+	}
+	askSourcesStore.set(token, groundedAnswer{text: text, sources: sources})
+	return sourcesKeyboard(token, len(sources), false)
+}
+
+// dedupeSources drops repeated URLs (search and extract can both return the
+// same page) and caps the list at maxListedSources.
+func dedupeSources(sources []promptWebResult) []promptWebResult {
+	seen := make(map[string]struct{}, len(sources))
+	out := make([]promptWebResult, 0, min(len(sources), maxListedSources))
+	for _, s := range sources {
+		key := strings.TrimSpace(s.URL)
+		if key == "" {
+			key = strings.TrimSpace(s.Title)
+		}
+		if key == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, s)
+		if len(out) == maxListedSources {
+			break
+		}
+	}
+	return out
+}
+
+func sourcesKeyboard(token string, count int, expanded bool) *models.InlineKeyboardMarkup {
+	label := fmt.Sprintf("Sources (%d)", count)
+	action := sourcesActionShow
+	if expanded {
+		label = "Hide sources"
+		action = sourcesActionHide
+	}
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{{
+			{Text: label, CallbackData: sourcesCallbackPrefix + action + ":" + token},
+		}},
+	}
+}
+
+// parseSourcesCallback splits "src:<action>:<token>" callback data.
+func parseSourcesCallback(data string) (action string, token string, ok bool) {
+	rest, found := strings.CutPrefix(data, sourcesCallbackPrefix)
+	if !found {
+		return "", "", false
+	}
+	action, token, found = strings.Cut(rest, ":")
+	if !found || token == "" || (action != sourcesActionShow && action != sourcesActionHide) {
+		return "", "", false
+	}
+	return action, token, true
+}
+
+// sourceHost returns the URL's host without a leading "www.", or "" when
+// the URL does not parse.
+func sourceHost(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// sourceLine renders one source as "title — host · date", leaving out the
+// parts that are missing.
+func sourceLine(source promptWebResult) string {
+	host := sourceHost(source.URL)
+	title := strings.Join(strings.Fields(source.Title), " ")
+	if title == "" {
+		title = host
+	}
+	if runeLen(title) > maxSourceTitleLength {
+		title = truncateRunes(title, maxSourceTitleLength-1) + "…"
+	}
+
+	var details []string
+	if host != "" && host != title {
+		details = append(details, host)
+	}
+	if date := strings.TrimSpace(source.PublishDate); date != "" {
+		details = append(details, date)
+	}
+	if len(details) == 0 {
+		return title
+	}
+	return title + " — " + strings.Join(details, " · ")
+}
+
+// formatSourcesMarkdown renders the source list as a MarkdownV2 blockquote
+// to append below the answer, listing only the sources that fit in budget
+// runes.
+func formatSourcesMarkdown(sources []promptWebResult, budget int) string {
+	return formatSourceList(">*Sources*", "\n>", bot.EscapeMarkdown, sources, budget)
+}
+
+func formatSourcesPlain(sources []promptWebResult, budget int) string {
+	return formatSourceList("Sources:", "\n", func(s string) string { return s }, sources, budget)
+}
+
+// formatSourceList writes header and one prefixed line per source. Sources
+// past budget collapse into "…and N more"; the list is empty when not
+// even that fits.
+func formatSourceList(header, prefix string, escape func(string) string, sources []promptWebResult, budget int) string {
+	more := func(n int) string { return prefix + escape(fmt.Sprintf("…and %d more", n)) }
+
+	var sb strings.Builder
+	sb.WriteString(header)
+	used := runeLen(header)
+	for i, source := range sources {
+		line := prefix + escape(strconv.Itoa(i+1)+". "+sourceLine(source))
+		reserve := 0
+		if i < len(sources)-1 {
+			reserve = runeLen(more(len(sources) - i - 1))
+		}
+		if used+runeLen(line)+reserve > budget {
+			if i == 0 && used+runeLen(more(len(sources))) > budget {
+				return ""
+			}
+			sb.WriteString(more(len(sources) - i))
+			return sb.String()
+		}
+		sb.WriteString(line)
+		used += runeLen(line)
+	}
+	return sb.String()
+}
+
+// withSources appends the source list to an answer, keeping the message
+// within telegramMaxMessageLength. An answer too long to leave
+// minSourcesRoom for the list is trimmed first.
+func withSources(text string, sources []promptWebResult, format func(string) string, list func([]promptWebResult, int) string) string {
+	body := format(text)
+	if over := runeLen(body) + minSourcesRoom - telegramMaxMessageLength; over > 0 {
+		body = format(truncateRunes(text, max(runeLen(text)-over-1, 0)) + "…")
+	}
+	budget := telegramMaxMessageLength - runeLen(body) - len("\n\n")
+	if block := list(sources, budget); block != "" {
+		return body + "\n\n" + block
+	}
+	return body
+}
+
+// sourcesCallbackHandler expands or collapses the source list under a
+// grounded answer by editing the message in place.
+func sourcesCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	query := update.CallbackQuery
+	action, token, ok := parseSourcesCallback(query.Data)
+	if !ok {
+		appotel.RecordOutcome(ctx, "error")
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		return
+	}
+
+	message := query.Message.Message
+	answer, found := askSourcesStore.get(ctx, token)
+	if !found || message == nil {
+		appotel.RecordOutcome(ctx, "error")
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "These sources are no longer available.",
+		})
+		return
+	}
+
+	expanded := action == sourcesActionShow
+	markup := sourcesKeyboard(token, len(answer.sources), expanded)
+
+	formatted := formatTelegramMarkdown(answer.text)
+	plain := answer.text
+	if expanded {
+		formatted = withSources(answer.text, answer.sources, formatTelegramMarkdown, formatSourcesMarkdown)
+		plain = withSources(answer.text, answer.sources, func(s string) string { return s }, formatSourcesPlain)
+	}
+
+	_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      message.Chat.ID,
+		MessageID:   message.ID,
+		Text:        formatted,
+		ParseMode:   models.ParseModeMarkdown,
+		ReplyMarkup: markup,
+	})
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Int64("chat_id", message.Chat.ID).
+			Int("message_id", message.ID).
+			Msg("Failed to edit markdown sources; trying plain-text fallback")
+		_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      message.Chat.ID,
+			MessageID:   message.ID,
+			Text:        plain,
+			ReplyMarkup: markup,
+		})
+	}
+	if err != nil {
+		appotel.RecordOutcome(ctx, "error")
+		log.Warn().
+			Err(err).
+			Int64("chat_id", message.Chat.ID).
+			Int("message_id", message.ID).
+			Msg("Failed to edit grounded answer sources")
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "Couldn't update the sources. Please try again.",
+		})
+		return
+	}
+
+	appotel.RecordOutcome(ctx, "success")
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+}
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestParseSourcesCallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		data       string
+		wantAction string
+		wantToken  string
+		wantOK     bool
+	}{
+		{data: "src:show:abc123", wantAction: sourcesActionShow, wantToken: "abc123", wantOK: true},
+		{data: "src:hide:abc123", wantAction: sourcesActionHide, wantToken: "abc123", wantOK: true},
+		{data: "src:show:", wantOK: false},
+		{data: "src:toggle:abc", wantOK: false},
+		{data: "other:show:abc", wantOK: false},
+	}
+	for _, tt := range tests {
+		action, token, ok := parseSourcesCallback(tt.data)
+		if action != tt.wantAction || token != tt.wantToken || ok != tt.wantOK {
+			t.Errorf("parseSourcesCallback(%q) = %q, %q, %v", tt.data, action, token, ok)
+		}
+	}
+}
+
+func TestSourceLineAndFormatting(t *testing.T) {
+	t.Parallel()
+
+	sources := dedupeSources([]promptWebResult{
+		{Title: "Go 1.27 released", URL: "https://www.go.dev/blog/go1.27", PublishDate: "2026-08-12"},
+		{Title: "duplicate", URL: "https://www.go.dev/blog/go1.27"},
+		{URL: "https://example.com/untitled"},
+		{},
+	})
+	if len(sources) != 2 {
+		t.Fatalf("expected duplicates and empty entries dropped, got %+v", sources)
+	}
+
+	if got := sourceLine(sources[0]); got != "Go 1.27 released — go.dev · 2026-08-12" {
+		t.Errorf("sourceLine() = %q", got)
+	}
+	if got := sourceLine(sources[1]); got != "example.com" {
+		t.Errorf("expected host to stand in for a missing title, got %q", got)
+	}
+	long := sourceLine(promptWebResult{Title: strings.Repeat("a", 200)})
+	if runeLen(long) != maxSourceTitleLength || !strings.HasSuffix(long, "…") {
+		t.Errorf("expected long titles truncated, got %q", long)
+	}
+
+	markdown := formatSourcesMarkdown(sources, telegramMaxMessageLength)
+	if !strings.HasPrefix(markdown, ">*Sources*\n>1\\. Go 1\\.27 released") {
+		t.Errorf("unexpected markdown %q", markdown)
+	}
+	if !strings.Contains(markdown, "2026\\-08\\-12") {
+		t.Errorf("expected MarkdownV2 escaping, got %q", markdown)
+	}
+	if plain := formatSourcesPlain(sources, telegramMaxMessageLength); !strings.Contains(plain, "2. example.com") {
+		t.Errorf("unexpected plain list %q", plain)
+	}
+}
+
+func TestRememberGroundedAnswer(t *testing.T) {
+	t.Parallel()
+
+	if markup := rememberGroundedAnswer("answer", nil); markup != nil {
+		t.Fatalf("expected no keyboard without sources, got %+v", markup)
+	}
+
+	markup := rememberGroundedAnswer("answer", []promptWebResult{{Title: "t", URL: "https://example.com"}})
+	keyboard, ok := markup.(*models.InlineKeyboardMarkup)
+	if !ok {
+		t.Fatalf("expected inline keyboard, got %T", markup)
+	}
+	button := keyboard.InlineKeyboard[0][0]
+	if button.Text != "Sources (1)" || len(button.CallbackData) > 64 {
+		t.Fatalf("unexpected button %+v", button)
+	}
+	_, token, _ := parseSourcesCallback(button.CallbackData)
+	if stored, found := askSourcesStore.get(context.Background(), token); !found || stored.text != "answer" {
+		t.Fatalf("expected answer stored under %q", token)
+	}
+}
+
+func sourcesCallbackUpdate(data string) *models.Update {
+	return &models.Update{CallbackQuery: &models.CallbackQuery{
+		ID:   "cb1",
+		From: models.User{ID: 7},
+		Data: data,
+		Message: models.MaybeInaccessibleMessage{
+			Type:    models.MaybeInaccessibleMessageTypeMessage,
+			Message: &models.Message{ID: 42, Chat: models.Chat{ID: -100, Type: models.ChatTypeSupergroup}},
+		},
+	}}
+}
+
+func TestSourcesCallbackHandlerTogglesList(t *testing.T) {
+	b, srv := newTestBot(t)
+
+	markup := rememberGroundedAnswer("Go 1.27 is out.", []promptWebResult{
+		{Title: "Go 1.27 released", URL: "https://go.dev/blog/go1.27", PublishDate: "2026-08-12"},
+	})
+	showData := markup.(*models.InlineKeyboardMarkup).InlineKeyboard[0][0].CallbackData
+
+	sourcesCallbackHandler(context.Background(), b, sourcesCallbackUpdate(showData))
+	srv.mu.Lock()
+	expanded, expandedMarkup := srv.lastMessage, srv.lastMarkup
+	srv.mu.Unlock()
+	if !strings.Contains(expanded, "Go 1\\.27 is out") || !strings.Contains(expanded, "go\\.dev") {
+		t.Fatalf("expected answer with sources, got %q", expanded)
+	}
+	if !strings.Contains(expandedMarkup, "Hide sources") || !strings.Contains(expandedMarkup, "src:hide:") {
+		t.Fatalf("expected collapse button, got %q", expandedMarkup)
+	}
+	if !strings.HasSuffix(srv.lastMethod(), "answerCallbackQuery") {
+		t.Fatalf("expected the callback to be answered, got %q", srv.lastMethod())
+	}
+
+	hideData := strings.Replace(showData, sourcesActionShow, sourcesActionHide, 1)
+	sourcesCallbackHandler(context.Background(), b, sourcesCallbackUpdate(hideData))
+	srv.mu.Lock()
+	collapsed, collapsedMarkup := srv.lastMessage, srv.lastMarkup
+	srv.mu.Unlock()
+	if strings.Contains(collapsed, "Sources") || !strings.Contains(collapsed, "Go 1\\.27 is out") {
+		t.Fatalf("expected answer without sources, got %q", collapsed)
+	}
+	if !strings.Contains(collapsedMarkup, "Sources (1)") {
+		t.Fatalf("expected expand button, got %q", collapsedMarkup)
+	}
+}
+
+func TestSourcesCallbackHandlerExpired(t *testing.T) {
+	b, srv := newTestBot(t)
+
+	sourcesCallbackHandler(context.Background(), b, sourcesCallbackUpdate("src:show:missing"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requestLog) != 1 || !strings.HasSuffix(srv.requestLog[0], "answerCallbackQuery") {
+		t.Fatalf("expected only a callback answer, got %v", srv.requestLog)
+	}
+}
+
+func TestFormatSourcesWithinBudget(t *testing.T) {
+	t.Parallel()
+
+	sources := make([]promptWebResult, 5)
+	for i := range sources {
+		sources[i] = promptWebResult{Title: strings.Repeat("t", 60), URL: "https://example.com/" + strconv.Itoa(i)}
+	}
+	full := formatSourcesPlain(sources, telegramMaxMessageLength)
+	if strings.Contains(full, "more") {
+		t.Fatalf("expected every source within the full budget, got %q", full)
+	}
+
+	plain := formatSourcesPlain(sources, 200)
+	if runeLen(plain) > 200 || !strings.Contains(plain, "1. ") || !strings.HasSuffix(plain, "…and 3 more") {
+		t.Fatalf("formatSourcesPlain(200) = %q, want two sources and a remainder line", plain)
+	}
+	if got := formatSourcesMarkdown(sources, 5); got != "" {
+		t.Fatalf("formatSourcesMarkdown(5) = %q, want no list", got)
+	}
+
+	long := strings.Repeat("word ", 1000)
+	expanded := withSources(long, sources, formatTelegramMarkdown, formatSourcesMarkdown)
+	if runeLen(expanded) > telegramMaxMessageLength || !strings.Contains(expanded, ">*Sources*") {
+		t.Fatalf("withSources() = %d runes, want the list within %d", runeLen(expanded), telegramMaxMessageLength)
+	}
+}
+
+func TestSendOrEditExplainResultAttachesMarkup(t *testing.T) {
+	b, srv := newTestBot(t)
+
+	markup := rememberGroundedAnswer("answer", []promptWebResult{{Title: "t", URL: "https://example.com"}})
+	update := groupTextUpdate("@bot question")
+	sendOrEditExplainResultWithMarkup(context.Background(), b, update, &models.Message{ID: 5}, nil, "answer", markup)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.lastMarkup, "src:show:") {
+		t.Fatalf("expected sources button on the answer, got %q", srv.lastMarkup)
+	}
+}
@@ -53,6 +53,7 @@ func wireOTelTransports() {
 	appotel.WrapClient(httpClient)
 	appotel.WrapClient(histHTTPClient)
 	appotel.WrapClient(parallelHTTPClient)
+	appotel.WrapClient(localExtractHTTPClient)
 }
 
 // telegramPollTimeout matches the go-telegram/bot default: the long-poll
@@ -132,7 +133,7 @@ func recordRateLimited(ctx context.Context, feature string) {
 const (
 	finnhubBaseURL       = "https://finnhub.io/api/v1"
 	leetCodeGraphQLURL   = "https://leetcode.com/graphql"
-	invalidUsageSymbol   = "invalid usage, use !s SYMBOL or !s SYMBOL 7d|30d|60d|90d"
+	invalidUsageSymbol   = "invalid usage, use !s SYMBOL, !s SYMBOL RANGE (1d|5d|7d|30d|60d|90d|6m|1y|5y|ytd|YYYY-MM-DD..YYYY-MM-DD) or !s SYMBOL SYMBOL..."
 	dateFormatPattern    = "2006-01-02"
 	unexpectedCodeErrMsg = "unexpected status code: %d"
 )
@@ -181,6 +182,20 @@ func Run() error {
 	b.RegisterHandler(bot.HandlerTypeMessageText, "!s ", bot.MatchTypePrefix, stockHandler, obs("bot.stock", "!s "))
 	b.RegisterHandler(bot.HandlerTypeMessageText, "!sa", bot.MatchTypeExact, stockAnalysisHandler, obs("bot.stock_analysis", "!sa"))
 	b.RegisterHandler(bot.HandlerTypeMessageText, "!sa ", bot.MatchTypePrefix, stockAnalysisHandler, obs("bot.stock_analysis", "!sa "))
+	b.RegisterHandler(bot.HandlerTypeMessageText, cryptoCommand, bot.MatchTypeExact, cryptoHandler, obs("bot.crypto", cryptoCommand))
+	b.RegisterHandler(bot.HandlerTypeMessageText, cryptoCommand+" ", bot.MatchTypePrefix, cryptoHandler, obs("bot.crypto", cryptoCommand+" "))
+	b.RegisterHandler(bot.HandlerTypeMessageText, fxCommand, bot.MatchTypeExact, fxHandler, obs("bot.fx", fxCommand))
+	b.RegisterHandler(bot.HandlerTypeMessageText, fxCommand+" ", bot.MatchTypePrefix, fxHandler, obs("bot.fx", fxCommand+" "))
+	b.RegisterHandler(bot.HandlerTypeMessageText, compareCommand, bot.MatchTypeExact, compareHandler, obs("bot.compare", compareCommand))
+	b.RegisterHandler(bot.HandlerTypeMessageText, compareCommand+" ", bot.MatchTypePrefix, compareHandler, obs("bot.compare", compareCommand+" "))
+	b.RegisterHandlerMatchFunc(shouldHandleWatchlist, watchlistHandler, obs("bot.watchlist", watchlistCommand))
+	b.RegisterHandlerMatchFunc(shouldHandleAlert, alertHandler, obs("bot.alert", alertCommand))
+	b.RegisterHandlerMatchFunc(shouldHandleTranslate, translateHandler, obs("bot.translate", "!tr"))
+	b.RegisterHandlerMatchFunc(shouldHandleImage, imageHandler, obs("bot.image", "!img"))
+	b.RegisterHandlerMatchFunc(shouldHandlePersona, personaHandler, obs("bot.persona", "!persona"))
+	b.RegisterHandlerMatchFunc(shouldHandleDomains, domainsHandler, obs("bot.domains", domainsCommand))
+	b.RegisterHandlerMatchFunc(shouldHandleAutoSummaryCommand, autoSummaryCommandHandler, obs("bot.autosummary", autoSummaryCommand))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, sourcesCallbackPrefix, bot.MatchTypePrefix, sourcesCallbackHandler, obs("bot.ask_sources", sourcesCallbackPrefix))
 
 	me, err := b.GetMe(ctx)
 	if err != nil {
@@ -195,6 +210,8 @@ func Run() error {
 	// Registered after the ask handlers so a message that both mentions the bot
 	// and contains an x.com link is answered, not just link-rewritten.
 	b.RegisterHandlerMatchFunc(shouldHandleXLink, xLinkHandler, obs("bot.xlink", ""))
+	b.RegisterHandlerMatchFunc(shouldHandleAutoSummary, autoSummaryHandler, obs("bot.autosummary_link", ""))
+	b.RegisterHandlerMatchFunc(shouldHandleInlineQuery, inlineQueryHandler, obs("bot.inline", ""))
 
 	allowedGroups, err = parseAllowedGroupIDs(os.Getenv("ALLOWED_GROUP_IDS"))
 	if err != nil {
@@ -208,6 +225,11 @@ func Run() error {
 	}
 	logAllowedUsernames("Loaded allowed username configuration")
 
+	initChatSettings()
+	initDailyBarCache()
+	initParallelCaches()
+	initPriceAlerts()
+
 	var initErr error
 	textExplainer, initErr = initGeminiExplainer()
 	if initErr != nil {
@@ -223,6 +245,7 @@ func Run() error {
 		}
 		log.Info().
 			Str("model", model).
+			Strs("fallback_models", textExplainer.fallbackModels).
 			Dur("timeout", timeout).
 			Msg("Gemini explainer initialized")
 	}
@@ -231,8 +254,13 @@ func Run() error {
 	initStockAnalyzer()
 	analysisLimiter = loadAnalysisRateLimiter()
 
+	initImageGenerator()
+	imageLimiter = loadImageRateLimiter()
+	autoSummaryLimiter = loadAutoSummaryLimiter()
+
 	go startHealthServer()
 	go startAllowedGroupsReporter(ctx)
+	go startPriceAlertPoller(ctx, b)
 
 	log.Info().Msg("Bot started")
 	b.Start(ctx)
@@ -346,6 +374,9 @@ func applyUpdateAttributes(span trace.Span, name string, literal string, update
 				attrs = append(attrs, attribute.Int64("bot.user_id", update.Message.From.ID))
 			}
 		}
+		if update.InlineQuery != nil && update.InlineQuery.From != nil {
+			attrs = append(attrs, attribute.Int64("bot.user_id", update.InlineQuery.From.ID))
+		}
 	}
 	span.SetAttributes(attrs...)
 }
@@ -418,6 +449,9 @@ func isAllowedUsername(username string) bool {
 }
 
 func enforceChatAccess(ctx context.Context, b *bot.Bot, update *models.Update) bool {
+	if update != nil && update.InlineQuery != nil {
+		return enforceInlineQueryAccess(update.InlineQuery)
+	}
 	chat := extractChatFromUpdate(update)
 	if chat == nil {
 		return true
@@ -537,6 +571,8 @@ func extractUserFromUpdate(update *models.Update) *models.User {
 		return &update.ChatJoinRequest.From
 	case update.CallbackQuery != nil:
 		return &update.CallbackQuery.From
+	case update.InlineQuery != nil:
+		return update.InlineQuery.From
 	}
 	return nil
 }
@@ -605,6 +641,8 @@ func logIncomingUpdate(update *models.Update, matched bool) {
 			Bool("is_reply", update.Message.ReplyToMessage != nil)
 	case update.CallbackQuery != nil:
 		event = event.Str("update_type", "callback_query")
+	case update.InlineQuery != nil:
+		event = event.Str("update_type", "inline_query")
 	default:
 		event = event.Str("update_type", "other")
 	}
@@ -661,9 +699,27 @@ func helpHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 /help - Show this help message
 /lc - Get today's LeetCode daily challenge
 !s SYMBOL - Get stock price (e.g., !s AAPL)
-!s SYMBOL 7d|30d|60d|90d - Get historical chart image (e.g., !s AAPL 7d)
+!s SYMBOL 7d|30d|60d|90d|6m|1y|5y|ytd - Get historical chart image (e.g., !s AAPL 7d)
+!s SYMBOL 1d|5d - Intraday chart of the latest session(s), US Eastern time
+!s SYMBOL YYYY-MM-DD..YYYY-MM-DD - Historical chart for a date span
+!s SYMBOL RANGE candle - Candlestick chart with volume (e.g., !s AAPL 30d candle)
+!s SYMBOL RANGE sma20 ema rsi macd bb - Indicator overlays and panels (e.g., !s AAPL 90d candle sma50 rsi)
+!s SYMBOL SYMBOL... - Compare up to 8 quotes in one table (e.g., !s AAPL MSFT NVDA)
+!cmp SYMBOL SYMBOL... [RANGE] - Performance chart rebased to 100 (e.g., !cmp AAPL MSFT QQQ 90d)
+!c SYMBOL [RANGE] - Crypto quote or chart (e.g., !c BTC, !c ETH 30d)
+!fx BASE QUOTE [RANGE] - Exchange rate or chart (e.g., !fx USD SGD, !fx USD THB 90d)
+!wl [chart] - This chat's watchlist as one quote table, optionally with a 30-day performance chart
+!wl add|rm SYMBOL... - Edit this chat's shared watchlist (e.g., !wl add AAPL NVDA)
+!alert SYMBOL > PRICE|< PRICE|-5%% - Mention me here when a price is hit (e.g., !alert AAPL > 250, !alert TSLA -5%%)
+!alerts, !alert rm ID - List this chat's price alerts or remove one
 !sa SYMBOL - AI-generated stock analysis, not financial advice (e.g., !sa AAPL)
-Mention + question - Ask anything (e.g., @%s what is a mutex?)`, strings.TrimPrefix(botMention, "@"))
+!tr [>my|>en|LANGUAGE] [TEXT] - Translate text or the replied message (Burmese <-> English by default)
+!img PROMPT - Generate an image; reply to a photo with !img INSTRUCTIONS to edit it
+!persona [NAME] - Show or (admins) set this chat's answer persona
+!domains [allow|deny|rm DOMAIN] - Show or (admins) edit which link domains answers may read
+!autosummary [on|off] - Show or (admins) switch TL;DR replies to posted article links
+Mention + question - Ask anything (e.g., @%[1]s what is a mutex?)
+Inline: @%[1]s $AAPL | lc | ask QUESTION - Use from any chat (allowlisted users only)`, strings.TrimPrefix(botMention, "@"))
 
 	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          update.Message.Chat.ID,
@@ -723,8 +779,9 @@ func initStockAnalyzer() {
 		log.Error().Err(err).Msg("Failed to initialize stock analyzer")
 		return
 	}
+	analyzer.fallbackModels = loadGeminiFallbackModels()
 	stockAnalyzerInstance = analyzer
-	log.Info().Str("model", model).Dur("timeout", timeout).Int32("max_output_tokens", maxOutputTokens).Msg("Stock analyzer initialized")
+	log.Info().Str("model", model).Strs("fallback_models", analyzer.fallbackModels).Dur("timeout", timeout).Int32("max_output_tokens", maxOutputTokens).Msg("Stock analyzer initialized")
 }
 
 const (
@@ -11,7 +11,7 @@ import (
 
 // TestParseStockCommand_Roundtrip verifies that for any symbol matching
 // the regex and any valid range, the parser returns the uppercased
-// symbol and correct day count.
+// symbol and range.
 func TestParseStockCommand_Roundtrip(t *testing.T) {
 	hegel.Test(t, func(ht *hegel.T) {
 		raw := hegel.Draw(ht, hegel.FromRegex(`[A-Z0-9.\-]{1,10}`, true))
@@ -19,28 +19,27 @@ func TestParseStockCommand_Roundtrip(t *testing.T) {
 
 		// Property: spot quote roundtrip.
 		cmd := "!s " + sym
-		gotSym, gotDays, err := parseStockCommand(cmd)
+		gotSym, gotRange, _, err := parseStockCommand(cmd)
 		if err != nil {
 			ht.Fatalf("parseStockCommand(%q) error: %v", cmd, err)
 		}
-		if gotSym != sym || gotDays != 0 {
-			ht.Fatalf("parseStockCommand(%q) = (%q, %d), want (%q, 0)",
-				cmd, gotSym, gotDays, sym)
+		if gotSym != sym || !gotRange.isZero() {
+			ht.Fatalf("parseStockCommand(%q) = (%q, %q), want (%q, no range)",
+				cmd, gotSym, gotRange.label(), sym)
 		}
 
 		// Property: historical range roundtrip.
 		rangeToken := hegel.Draw(ht, hegel.SampledFrom([]string{
-			"7d", "30d", "60d", "90d",
+			"7d", "30d", "60d", "90d", "6m", "1y", "5y",
 		}))
 		cmd = "!s " + sym + " " + rangeToken
-		gotSym, gotDays, err = parseStockCommand(cmd)
+		gotSym, gotRange, _, err = parseStockCommand(cmd)
 		if err != nil {
 			ht.Fatalf("parseStockCommand(%q) error: %v", cmd, err)
 		}
-		wantDays := stockRangeDays[strings.ToLower(rangeToken)]
-		if gotSym != sym || gotDays != wantDays {
-			ht.Fatalf("parseStockCommand(%q) = (%q, %d), want (%q, %d)",
-				cmd, gotSym, gotDays, sym, wantDays)
+		if gotSym != sym || gotRange.label() != rangeToken {
+			ht.Fatalf("parseStockCommand(%q) = (%q, %q), want (%q, %q)",
+				cmd, gotSym, gotRange.label(), sym, rangeToken)
 		}
 	}, hegel.WithTestCases(200))
 }
@@ -69,7 +68,7 @@ func TestParseStockAnalysisCommand_Roundtrip(t *testing.T) {
 func TestStockParsers_NeverPanics(t *testing.T) {
 	hegel.Test(t, func(ht *hegel.T) {
 		text := hegel.Draw(ht, hegel.Text().MaxSize(50))
-		_, _, _ = parseStockCommand(text)
+		_, _, _, _ = parseStockCommand(text)
 		_, _ = parseStockAnalysisCommand(text)
 	}, hegel.WithTestCases(100))
 }
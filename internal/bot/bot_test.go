@@ -764,22 +764,35 @@ func TestBlockedStockResponse_EmptyMap(t *testing.T) {
 
 func TestParseStockCommand(t *testing.T) {
 	tests := []struct {
-		name      string
-		input     string
-		wantSym   string
-		wantDays  int
-		wantError bool
-		errSubstr string
+		name       string
+		input      string
+		wantSym    string
+		wantRange  string
+		wantCandle bool
+		wantError  bool
+		errSubstr  string
 	}{
-		{name: "spot quote", input: "!s AAPL", wantSym: testSymbolAAPL, wantDays: 0},
-		{name: "historical 7d", input: "!s AAPL 7d", wantSym: testSymbolAAPL, wantDays: 7},
-		{name: "historical 30d", input: "!s msft 30d", wantSym: "MSFT", wantDays: 30},
+		{name: "spot quote", input: "!s AAPL", wantSym: testSymbolAAPL},
+		{name: "historical 7d", input: "!s AAPL 7d", wantSym: testSymbolAAPL, wantRange: "7d"},
+		{name: "historical 30d", input: "!s msft 30d", wantSym: "MSFT", wantRange: "30d"},
+		{name: "historical 1y", input: "!s AAPL 1Y", wantSym: testSymbolAAPL, wantRange: "1y"},
+		{name: "historical ytd", input: "!s AAPL ytd", wantSym: testSymbolAAPL, wantRange: "YTD"},
+		{name: "historical span", input: "!s AAPL 2024-01-01..2024-06-30", wantSym: testSymbolAAPL, wantRange: "2024-01-01..2024-06-30"},
 		{name: "tab after command", input: "!s\tAAPL", wantError: true, errSubstr: testErrInvalidUsage},
 		{name: "newline after command with range", input: "!s\nAAPL 7d", wantError: true, errSubstr: testErrInvalidUsage},
 		{name: "missing separator after command", input: "!sAAPL", wantError: true, errSubstr: testErrInvalidUsage},
 		{name: "invalid range", input: "!s AAPL 10d", wantError: true, errSubstr: "invalid range"},
-		{name: "invalid range 1d", input: "!s AAPL 1d", wantError: true, errSubstr: "invalid range"},
+		{name: "intraday 1d", input: "!s AAPL 1d", wantSym: testSymbolAAPL, wantRange: "1d"},
+		{name: "intraday 5d candle", input: "!s AAPL 5d candle", wantSym: testSymbolAAPL, wantRange: "5d", wantCandle: true},
+		{name: "invalid range 2d", input: "!s AAPL 2d", wantError: true, errSubstr: "invalid range"},
 		{name: "invalid range 365d", input: "!s AAPL 365d", wantError: true, errSubstr: "invalid range"},
+		{name: "candle chart", input: "!s AAPL 30d CANDLE", wantSym: testSymbolAAPL, wantRange: "30d", wantCandle: true},
+		{name: "candle without range", input: "!s AAPL candle", wantError: true, errSubstr: "needs a range first"},
+		{name: "unknown chart option", input: "!s AAPL 30d candel", wantError: true, errSubstr: "unknown chart option"},
+		{name: "indicator on daily bars", input: "!s AAPL 90d sma200", wantSym: testSymbolAAPL, wantRange: "90d"},
+		{name: "indicator too long for weekly bars", input: "!s AAPL 1y sma200", wantError: true, errSubstr: "sma200 needs more history than a chart of weekly bars"},
+		{name: "invalid range 3m", input: "!s AAPL 3m", wantError: true, errSubstr: "invalid range"},
+		{name: "reversed span", input: "!s AAPL 2024-06-30..2024-01-01", wantError: true, errSubstr: "invalid date span"},
 		{name: "invalid symbol chars", input: "!s $$$", wantError: true, errSubstr: "invalid stock symbol"},
 		{name: "invalid symbol punctuation", input: "!s AAPL!", wantError: true, errSubstr: "invalid stock symbol"},
 		{name: "invalid symbol with extra token", input: "!s AA PL", wantError: true, errSubstr: testErrInvalidUsage},
@@ -790,10 +803,10 @@ func TestParseStockCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotSym, gotDays, err := parseStockCommand(tt.input)
+			gotSym, gotRange, gotOpts, err := parseStockCommand(tt.input)
 			if tt.wantError {
 				if err == nil {
-					t.Fatalf("expected error, got symbol=%q days=%d", gotSym, gotDays)
+					t.Fatalf("expected error, got symbol=%q range=%q", gotSym, gotRange.label())
 				}
 				if tt.errSubstr != "" && !strings.Contains(err.Error(), tt.errSubstr) {
 					t.Fatalf("expected error containing %q, got %q", tt.errSubstr, err.Error())
@@ -803,8 +816,11 @@ func TestParseStockCommand(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
-			if gotSym != tt.wantSym || gotDays != tt.wantDays {
-				t.Fatalf("got (%q,%d), want (%q,%d)", gotSym, gotDays, tt.wantSym, tt.wantDays)
+			if gotOpts.candle != tt.wantCandle {
+				t.Fatalf("candle = %v, want %v", gotOpts.candle, tt.wantCandle)
+			}
+			if gotSym != tt.wantSym || gotRange.label() != tt.wantRange {
+				t.Fatalf("got (%q,%q), want (%q,%q)", gotSym, gotRange.label(), tt.wantSym, tt.wantRange)
 			}
 		})
 	}
@@ -816,7 +832,7 @@ func TestRenderHistoricalChartPNG(t *testing.T) {
 		{Date: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Close: 101.25},
 		{Date: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), Close: 99.75},
 	}
-	buf, err := renderHistoricalChartPNG(testSymbolAAPL, 7, bars)
+	buf, err := renderHistoricalChartPNG(testSymbolAAPL, "7d", bars)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -837,7 +853,7 @@ func TestFormatHistoricalSummary(t *testing.T) {
 		{Date: time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), Close: 100, High: 102, Low: 99},
 		{Date: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Close: 110, High: 111, Low: 98},
 	}
-	got := formatHistoricalSummary(testSymbolAAPL, 7, bars, nil)
+	got := formatHistoricalSummary(testSymbolAAPL, "7d", bars, nil, "")
 	if !strings.Contains(got, "AAPL 7d") {
 		t.Fatalf("expected symbol and range in summary, got %q", got)
 	}
@@ -847,7 +863,7 @@ func TestFormatHistoricalSummary(t *testing.T) {
 }
 
 func TestFormatHistoricalSummary_EmptyBars(t *testing.T) {
-	got := formatHistoricalSummary(testSymbolAAPL, 7, nil, nil)
+	got := formatHistoricalSummary(testSymbolAAPL, "7d", nil, nil, "")
 	if !strings.Contains(got, "No historical data returned") {
 		t.Fatalf("expected empty-data message, got %q", got)
 	}
@@ -864,7 +880,7 @@ func TestFormatHistoricalSummary_WithProfile(t *testing.T) {
 		Industry:             testIndustryTechnology,
 	}
 
-	got := formatHistoricalSummary("MSFT", 7, bars, profile)
+	got := formatHistoricalSummary("MSFT", "7d", bars, profile, "")
 	if !strings.Contains(got, "Microsoft Corporation (MSFT)") {
 		t.Fatalf("expected company title in summary, got %q", got)
 	}
@@ -887,7 +903,7 @@ func TestFormatHistoricalSummary_ExchangeCurrency(t *testing.T) {
 		Currency: "JPY",
 	}
 
-	got := formatHistoricalSummary("SHECY", 7, bars, profile)
+	got := formatHistoricalSummary("SHECY", "7d", bars, profile, "")
 
 	if !strings.Contains(got, "🏛 Exchange: OTC Markets") {
 		t.Fatalf("expected exchange line in historical summary, got %q", got)
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+const chatSettingsFileName = "chat_settings.json"
+
+// chatSettings holds the per-chat options admins can change at runtime. The
+// zero value means "deployment defaults".
+type chatSettings struct {
+	Persona string `json:"persona,omitempty"`
+
+	// AllowedDomains and DeniedDomains narrow which links ask answers are
+	// grounded in (see domainPolicy).
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	DeniedDomains  []string `json:"denied_domains,omitempty"`
+
+	// AutoSummary replies to posted article links with a short TL;DR (see
+	// autoSummaryHandler).
+	AutoSummary bool `json:"auto_summary,omitempty"`
+
+	// Watchlist is the chat's shared `!wl` symbols. Unlike the options
+	// above, any member may edit it.
+	Watchlist []string `json:"watchlist,omitempty"`
+}
+
+func (c chatSettings) isZero() bool {
+	return c.Persona == "" && len(c.AllowedDomains) == 0 && len(c.DeniedDomains) == 0 && !c.AutoSummary &&
+		len(c.Watchlist) == 0
+}
+
+// chatSettingsStore keeps chatSettings in memory and persists every change to
+// a JSON file. An empty path keeps the store memory-only.
+type chatSettingsStore struct {
+	mu    sync.RWMutex
+	path  string
+	chats map[int64]chatSettings
+}
+
+var chatSettingsInstance = newChatSettingsStore("")
+
+func newChatSettingsStore(path string) *chatSettingsStore {
+	return &chatSettingsStore{path: path, chats: make(map[int64]chatSettings)}
+}
+
+// loadChatSettingsStore reads path when it exists. A corrupt file is an error
+// rather than silently discarded settings.
+func loadChatSettingsStore(path string) (*chatSettingsStore, error) {
+	store := newChatSettingsStore(path)
+	if _, err := readJSONFile(path, &store.chats); err != nil {
+		return nil, err
+	}
+	if store.chats == nil {
+		store.chats = make(map[int64]chatSettings)
+	}
+	return store, nil
+}
+
+// initChatSettings loads the persisted settings from BOT_DATA_DIR, falling
+// back to a memory-only store so a bad file never keeps the bot down.
+func initChatSettings() {
+	path := botDataPath(chatSettingsFileName)
+	store, err := loadChatSettingsStore(path)
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to load chat settings; changes will not persist")
+		chatSettingsInstance = newChatSettingsStore("")
+		return
+	}
+	chatSettingsInstance = store
+	log.Info().Str("path", path).Int("chats", len(store.chats)).Msg("Loaded chat settings")
+}
+
+func (s *chatSettingsStore) get(chatID int64) chatSettings {
+	if s == nil {
+		return chatSettings{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chats[chatID]
+}
+
+// update applies fn to the chat's settings and persists the result. The
+// in-memory change is kept even when persisting fails.
+func (s *chatSettingsStore) update(chatID int64, fn func(*chatSettings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.chats[chatID]
+	fn(&settings)
+	if settings.isZero() {
+		delete(s.chats, chatID)
+	} else {
+		s.chats[chatID] = settings
+	}
+
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFileAtomic(s.path, s.chats)
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChatSettingsStorePersistsAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", chatSettingsFileName)
+	store, err := loadChatSettingsStore(path)
+	if err != nil {
+		t.Fatalf("load empty store: %v", err)
+	}
+
+	if err := store.update(-100, func(s *chatSettings) { s.Persona = "terse" }); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := store.update(-200, func(s *chatSettings) { s.Persona = "beginner" }); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := store.update(-200, func(s *chatSettings) { s.Persona = "" }); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	reloaded, err := loadChatSettingsStore(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.get(-100).Persona; got != "terse" {
+		t.Fatalf("expected persisted persona, got %q", got)
+	}
+	if _, ok := reloaded.chats[-200]; ok {
+		t.Fatal("expected reset chat to be dropped from the file")
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the settings file (no temp files), got %d entries", len(entries))
+	}
+}
+
+func TestLoadChatSettingsStoreRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), chatSettingsFileName)
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := loadChatSettingsStore(path); err == nil {
+		t.Fatal("expected corrupt settings file to fail loading")
+	}
+}
+
+func TestChatSettingsStoreNilGet(t *testing.T) {
+	var store *chatSettingsStore
+	if got := store.get(1); !got.isZero() {
+		t.Fatalf("expected zero settings from nil store, got %+v", got)
+	}
+}
+
+func TestBotDataPath(t *testing.T) {
+	t.Setenv("BOT_DATA_DIR", "")
+	if got := botDataPath("x.json"); got != filepath.Join(defaultBotDataDir, "x.json") {
+		t.Fatalf("unexpected default path %q", got)
+	}
+	t.Setenv("BOT_DATA_DIR", "/var/lib/bot")
+	if got := botDataPath("x.json"); got != "/var/lib/bot/x.json" {
+		t.Fatalf("unexpected configured path %q", got)
+	}
+}
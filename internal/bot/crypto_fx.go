@@ -0,0 +1,473 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	cryptoCommand = "!c"
+	fxCommand     = "!fx"
+
+	defaultCryptoExchange = "BINANCE"
+	defaultCryptoQuote    = "USDT"
+	defaultForexExchange  = "OANDA"
+
+	// quoteCandleLookbackDays covers weekends and holidays so forex quotes
+	// still have a previous close to compare against.
+	quoteCandleLookbackDays = 7
+
+	// maxCryptoPriceDecimals bounds formatCryptoPrice for coins priced in
+	// tiny fractions of a cent.
+	maxCryptoPriceDecimals = 10
+
+	invalidUsageCrypto = "invalid usage, use !c SYMBOL or !c SYMBOL RANGE (e.g., !c BTC, !c ETH 30d)"
+	invalidUsageFX     = "invalid usage, use !fx BASE QUOTE or !fx BASE QUOTE RANGE (e.g., !fx USD SGD, !fx USD THB 90d)"
+)
+
+var (
+	cryptoAssetRE   = regexp.MustCompile(`^[A-Z0-9]{2,10}$`)
+	currencyCodeRE  = regexp.MustCompile(`^[A-Z]{3}$`)
+	errNoCandleData = errors.New("no candle data available")
+)
+
+type marketAssetKind int
+
+const (
+	marketAssetCrypto marketAssetKind = iota
+	marketAssetForex
+)
+
+// marketAsset is a crypto pair or currency pair as Finnhub names it.
+// label is what users see (BTC/USDT, USD/SGD); symbol is the exchange-
+// qualified Finnhub symbol (BINANCE:BTCUSDT, OANDA:USD_SGD).
+type marketAsset struct {
+	kind   marketAssetKind
+	label  string
+	symbol string
+}
+
+// candleEndpoint is the Finnhub candle path for the asset kind.
+func (a marketAsset) candleEndpoint() string {
+	if a.kind == marketAssetForex {
+		return "forex/candle"
+	}
+	return "crypto/candle"
+}
+
+// fileSymbol is the label without the pair separator, for upload names.
+func (a marketAsset) fileSymbol() string {
+	return strings.ReplaceAll(a.label, "/", "")
+}
+
+// finnhubCandles is the Finnhub candle response: parallel arrays, with s set
+// to "no_data" when the window is empty.
+type finnhubCandles struct {
+	Status string    `json:"s"`
+	Times  []int64   `json:"t"`
+	Open   []float64 `json:"o"`
+	High   []float64 `json:"h"`
+	Low    []float64 `json:"l"`
+	Close  []float64 `json:"c"`
+	Volume []float64 `json:"v"`
+}
+
+// parseCryptoCommand parses `!c BTC [RANGE] [chart options]`. The asset is
+// priced against FINNHUB_CRYPTO_QUOTE (USDT) on FINNHUB_CRYPTO_EXCHANGE
+// (Binance).
+func parseCryptoCommand(text string) (marketAsset, stockRange, stockChartOptions, error) {
+	parts, err := commandArgs(text, cryptoCommand, invalidUsageCrypto)
+	if err != nil {
+		return marketAsset{}, stockRange{}, stockChartOptions{}, err
+	}
+	base := strings.ToUpper(parts[0])
+	if !cryptoAssetRE.MatchString(base) {
+		return marketAsset{}, stockRange{}, stockChartOptions{}, fmt.Errorf("invalid crypto symbol %q, use 2-10 letters or numbers (e.g., BTC, ETH)", parts[0])
+	}
+	quote := cmp.Or(strings.ToUpper(getenvTrim("FINNHUB_CRYPTO_QUOTE")), defaultCryptoQuote)
+	exchange := cmp.Or(strings.ToUpper(getenvTrim("FINNHUB_CRYPTO_EXCHANGE")), defaultCryptoExchange)
+	asset := marketAsset{
+		kind:   marketAssetCrypto,
+		label:  base + "/" + quote,
+		symbol: exchange + ":" + base + quote,
+	}
+	rng, opts, err := parseMarketAssetRange(parts[1:], invalidUsageCrypto)
+	return asset, rng, opts, err
+}
+
+// parseFXCommand parses `!fx USD SGD [RANGE] [chart options]`; the pair may
+// also be written USD/SGD.
+func parseFXCommand(text string) (marketAsset, stockRange, stockChartOptions, error) {
+	parts, err := commandArgs(text, fxCommand, invalidUsageFX)
+	if err != nil {
+		return marketAsset{}, stockRange{}, stockChartOptions{}, err
+	}
+	if base, quote, ok := strings.Cut(parts[0], "/"); ok {
+		parts = append([]string{base, quote}, parts[1:]...)
+	}
+	if len(parts) < 2 {
+		return marketAsset{}, stockRange{}, stockChartOptions{}, errors.New(invalidUsageFX)
+	}
+	base, quote := strings.ToUpper(parts[0]), strings.ToUpper(parts[1])
+	if !currencyCodeRE.MatchString(base) || !currencyCodeRE.MatchString(quote) || base == quote {
+		return marketAsset{}, stockRange{}, stockChartOptions{}, errors.New("invalid currency pair, use two different 3-letter codes (e.g., !fx USD SGD)")
+	}
+	exchange := cmp.Or(strings.ToUpper(getenvTrim("FINNHUB_FOREX_EXCHANGE")), defaultForexExchange)
+	asset := marketAsset{
+		kind:   marketAssetForex,
+		label:  base + "/" + quote,
+		symbol: exchange + ":" + base + "_" + quote,
+	}
+	rng, opts, err := parseMarketAssetRange(parts[2:], invalidUsageFX)
+	return asset, rng, opts, err
+}
+
+// commandArgs splits the arguments after command, requiring at least one.
+func commandArgs(text, command, usage string) ([]string, error) {
+	rest, ok := strings.CutPrefix(text, command)
+	if !ok || (rest != "" && rest[0] != ' ') {
+		return nil, errors.New(usage)
+	}
+	parts := strings.Fields(rest)
+	if len(parts) == 0 {
+		return nil, errors.New(usage)
+	}
+	return parts, nil
+}
+
+// parseMarketAssetRange parses the optional range and chart options after
+// the asset. Candles are daily, so the intraday 1d/5d ranges are rejected.
+func parseMarketAssetRange(tokens []string, usage string) (stockRange, stockChartOptions, error) {
+	if len(tokens) == 0 {
+		return stockRange{}, stockChartOptions{}, nil
+	}
+	if !isStockRangeToken(tokens[0]) {
+		return stockRange{}, stockChartOptions{}, errors.New(usage)
+	}
+	rng, err := parseStockRange(tokens[0], nowFunc())
+	if err != nil {
+		return stockRange{}, stockChartOptions{}, err
+	}
+	if rng.isIntraday() {
+		return stockRange{}, stockChartOptions{}, errors.New("intraday ranges are only available for stocks, use 7d or longer")
+	}
+	opts, err := parseStockChartOptions(tokens[1:])
+	if err != nil {
+		return stockRange{}, stockChartOptions{}, err
+	}
+	if err := opts.checkWarmup(barIntervalFor(rng.dateRange(nowFunc()))); err != nil {
+		return stockRange{}, stockChartOptions{}, err
+	}
+	return rng, opts, nil
+}
+
+func cryptoHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	asset, rng, opts, err := parseCryptoCommand(update.Message.Text)
+	handleMarketAsset(ctx, b, update, asset, rng, opts, err)
+}
+
+func fxHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	asset, rng, opts, err := parseFXCommand(update.Message.Text)
+	handleMarketAsset(ctx, b, update, asset, rng, opts, err)
+}
+
+// handleMarketAsset answers `!c` and `!fx`: a quote built from the latest
+// daily candles, or a chart of the requested range.
+func handleMarketAsset(
+	ctx context.Context,
+	b *bot.Bot,
+	update *models.Update,
+	asset marketAsset,
+	rng stockRange,
+	chartOpts stockChartOptions,
+	parseErr error,
+) {
+	if parseErr != nil {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          update.Message.Chat.ID,
+			MessageThreadID: update.Message.MessageThreadID,
+			Text:            parseErr.Error(),
+		})
+		return
+	}
+
+	loadingText := fmt.Sprintf("Fetching data for %s...", asset.label)
+	if !rng.isZero() {
+		loadingText = fmt.Sprintf("Fetching %s historical data for %s...", rng.label(), asset.label)
+	}
+	loadingMsg, loadingErr := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: update.Message.MessageThreadID,
+		Text:            loadingText,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                update.Message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+	if loadingErr != nil {
+		log.Warn().Err(loadingErr).Str("symbol", asset.symbol).Msg("Failed to send market asset loading state")
+	}
+
+	now := nowFunc()
+	if rng.isZero() {
+		bars, err := fetchFinnhubCandles(ctx, asset, now.AddDate(0, 0, -quoteCandleLookbackDays), now)
+		if err != nil {
+			appotel.RecordOutcome(ctx, "error")
+			log.Error().Err(err).Str("symbol", asset.symbol).Msg("Failed to fetch market asset quote")
+			sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Failed to fetch a quote for %s. Please try again later.", asset.label))
+			return
+		}
+		appotel.RecordOutcome(ctx, "success")
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, formatMarketAssetMessage(asset, quoteFromCandles(bars)))
+		return
+	}
+
+	label := rng.label()
+	window := rng.dateRange(now)
+	interval := barIntervalFor(window)
+	// Crypto trades every day, so relative ranges run up to now; an explicit
+	// span stops at its own (exclusive) end.
+	to := now
+	if rng.isSpan() {
+		to = window.End.Add(-time.Second)
+	}
+	from := window.Start.AddDate(0, 0, -chartOpts.warmupDays(interval))
+	candles, err := fetchFinnhubCandles(ctx, asset, from, to)
+	if err != nil && !errors.Is(err, errNoCandleData) {
+		appotel.RecordOutcome(ctx, "error")
+		log.Error().Err(err).Str("symbol", asset.symbol).Str("range", label).Msg("Failed to fetch market asset candles")
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Failed to fetch %s historical data for %s. Please try again later.", label, asset.label))
+		return
+	}
+	appotel.RecordOutcome(ctx, "success")
+	warmup, history := splitWarmupBars(candles, window.Start)
+	if len(history) == 0 {
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("No historical data returned for %s (%s).", asset.label, label))
+		return
+	}
+
+	// As in handleHistoricalStock, the price summary uses the daily candles
+	// and the indicator readings the charted bars.
+	chartWarmup, chartBars := downsampleBars(warmup, interval), downsampleBars(history, interval)
+	readings := formatIndicatorReadings(chartOpts.indicators, closePrices(append(slices.Clip(chartWarmup), chartBars...)))
+	caption := formatMarketAssetSummary(asset, label, history, readings)
+	if interval != barIntervalDaily {
+		if readings != "" {
+			caption += fmt.Sprintf("\nChart and indicators: %s bars", interval)
+		} else {
+			caption += fmt.Sprintf("\nChart: %s bars", interval)
+		}
+	}
+	chartPNG, err := renderStockChartPNG(asset.label, label, chartWarmup, chartBars, chartOpts)
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", asset.symbol).Str("range", label).Msg("Failed to render market asset chart; sending text only")
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, caption)
+		return
+	}
+	sendHistoricalChart(ctx, b, update, loadingMsg, loadingErr, asset.fileSymbol(), rng, chartPNG, caption)
+}
+
+// fetchFinnhubCandles fetches daily candles for asset between from and to
+// from Finnhub GET /crypto/candle or /forex/candle.
+func fetchFinnhubCandles(ctx context.Context, asset marketAsset, from, to time.Time) (bars []HistoricalBar, err error) {
+	endpoint := asset.candleEndpoint()
+	ctx, span := tracer().Start(
+		ctx, "finnhub."+strings.ReplaceAll(endpoint, "/", "_"),
+		trace.WithAttributes(
+			attribute.String(finnhubEndpointAttr, endpoint),
+			attribute.String("symbol", asset.symbol),
+		),
+	)
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	apiKey := os.Getenv("FINNHUB_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("FINNHUB_API_KEY not configured")
+	}
+	u, err := url.Parse(finnhubBaseURL + "/" + endpoint)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("symbol", asset.symbol)
+	q.Set("resolution", "D")
+	q.Set("from", strconv.FormatInt(from.Unix(), 10))
+	q.Set("to", strconv.FormatInt(to.Unix(), 10))
+	q.Set("token", apiKey)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// URL is built from the trusted finnhubBaseURL constant.
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, sanitizeHTTPClientError(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(unexpectedCodeErrMsg, resp.StatusCode)
+	}
+
+	var decoded finnhubCandles
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded.bars()
+}
+
+// bars converts the parallel arrays into day-truncated bars, oldest first.
+func (c finnhubCandles) bars() ([]HistoricalBar, error) {
+	if c.Status == "no_data" || len(c.Times) == 0 {
+		return nil, errNoCandleData
+	}
+	n := len(c.Times)
+	if len(c.Open) != n || len(c.High) != n || len(c.Low) != n || len(c.Close) != n {
+		return nil, errors.New("malformed candle response")
+	}
+	bars := make([]HistoricalBar, n)
+	for i, ts := range c.Times {
+		bars[i] = HistoricalBar{
+			Date:  time.Unix(ts, 0).UTC().Truncate(24 * time.Hour),
+			Open:  c.Open[i],
+			High:  c.High[i],
+			Low:   c.Low[i],
+			Close: c.Close[i],
+		}
+		if i < len(c.Volume) && c.Volume[i] > 0 {
+			bars[i].Volume = uint64(c.Volume[i])
+		}
+	}
+	return bars, nil
+}
+
+// quoteFromCandles builds a StockQuote from the latest daily candle. The
+// previous close is the prior candle's close, or the latest candle's open
+// when there is no prior candle.
+func quoteFromCandles(bars []HistoricalBar) *StockQuote {
+	last := bars[len(bars)-1]
+	quote := &StockQuote{
+		CurrentPrice:  last.Close,
+		High:          last.High,
+		Low:           last.Low,
+		Open:          last.Open,
+		PreviousClose: last.Open,
+	}
+	if len(bars) > 1 {
+		quote.PreviousClose = bars[len(bars)-2].Close
+	}
+	quote.Change = quote.CurrentPrice - quote.PreviousClose
+	if quote.PreviousClose != 0 {
+		quote.PercentChange = quote.Change / quote.PreviousClose * 100
+	}
+	return quote
+}
+
+// formatMarketAssetMessage renders a quote like formatStockMessage, titled
+// with the pair. Crypto prices are in a USD stablecoin and keep the $ sign
+// (see formatCryptoPrice); exchange rates are plain numbers with four
+// decimals.
+func formatMarketAssetMessage(asset marketAsset, quote *StockQuote) string {
+	changeEmoji := "🔴"
+	if quote.Change >= 0 {
+		changeEmoji = "🟢"
+	}
+	if asset.kind == marketAssetCrypto {
+		return fmt.Sprintf(`%s %s
+💵 Current: $%s
+📈 Change: %s (%.2f%%)
+📊 Open: $%s | High: $%s | Low: $%s
+📉 Previous Close: $%s`,
+			asset.label, changeEmoji,
+			formatCryptoPrice(quote.CurrentPrice),
+			formatCryptoPrice(quote.Change), quote.PercentChange,
+			formatCryptoPrice(quote.Open), formatCryptoPrice(quote.High), formatCryptoPrice(quote.Low),
+			formatCryptoPrice(quote.PreviousClose))
+	}
+
+	return fmt.Sprintf(`%s (%s) %s
+💱 Rate: %.4f
+📈 Change: %.4f (%.2f%%)
+📊 Open: %.4f | High: %.4f | Low: %.4f
+📉 Previous Close: %.4f`,
+		asset.label, asset.symbol, changeEmoji,
+		quote.CurrentPrice,
+		quote.Change, quote.PercentChange,
+		quote.Open, quote.High, quote.Low,
+		quote.PreviousClose)
+}
+
+// formatMarketAssetSummary is formatHistoricalSummary for crypto and forex
+// pairs, with prices formatted as in formatMarketAssetMessage.
+func formatMarketAssetSummary(asset marketAsset, label string, bars []HistoricalBar, indicators string) string {
+	priceName := "Rate"
+	price := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+	if asset.kind == marketAssetCrypto {
+		priceName = "Close"
+		price = func(v float64) string { return "$" + formatCryptoPrice(v) }
+	}
+
+	first, last := bars[0], bars[len(bars)-1]
+	high, low := first.High, first.Low
+	for _, bar := range bars[1:] {
+		high = max(high, bar.High)
+		low = min(low, bar.Low)
+	}
+	change := 0.0
+	if first.Close != 0 {
+		change = (last.Close - first.Close) / first.Close * 100
+	}
+	summary := fmt.Sprintf(
+		"%s %s (%s to %s)\n%s: %s\nReturn: %.2f%%\nRange: %s - %s",
+		asset.label,
+		label,
+		first.Date.Format(dateFormatPattern),
+		last.Date.Format(dateFormatPattern),
+		priceName,
+		price(last.Close),
+		change,
+		price(low),
+		price(high),
+	)
+	if indicators != "" {
+		summary += "\nIndicators: " + indicators
+	}
+	return summary
+}
+
+// formatCryptoPrice keeps four significant digits below $1, so coins like
+// DOGE and SHIB do not collapse to 0.00, and two decimals from $1 up.
+func formatCryptoPrice(v float64) string {
+	abs := math.Abs(v)
+	if abs >= 1 || abs == 0 {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	decimals := min(3-int(math.Floor(math.Log10(abs))), maxCryptoPriceDecimals)
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
@@ -0,0 +1,296 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCryptoCommand(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantSymbol string
+		wantRange  string
+		wantCandle bool
+		errSubstr  string
+	}{
+		{input: "!c BTC", wantSymbol: "BINANCE:BTCUSDT"},
+		{input: "!c eth 30d", wantSymbol: "BINANCE:ETHUSDT", wantRange: "30d"},
+		{input: "!c SOL 1y candle", wantSymbol: "BINANCE:SOLUSDT", wantRange: "1y", wantCandle: true},
+		{input: "!c", errSubstr: "invalid usage"},
+		{input: "!cBTC", errSubstr: "invalid usage"},
+		{input: "!c BTC ETH", errSubstr: "invalid usage"},
+		{input: "!c B$C", errSubstr: "invalid crypto symbol"},
+		{input: "!c BTC 10d", errSubstr: "invalid range"},
+		{input: "!c BTC 1d", errSubstr: "only available for stocks"},
+		{input: "!c BTC 30d candel", errSubstr: "unknown chart option"},
+	}
+	for _, tt := range tests {
+		asset, rng, opts, err := parseCryptoCommand(tt.input)
+		if tt.errSubstr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("parseCryptoCommand(%q) error = %v, want %q", tt.input, err, tt.errSubstr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCryptoCommand(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if asset.symbol != tt.wantSymbol || rng.label() != tt.wantRange || opts.candle != tt.wantCandle {
+			t.Errorf("parseCryptoCommand(%q) = %q, %q, candle=%v", tt.input, asset.symbol, rng.label(), opts.candle)
+		}
+	}
+}
+
+func TestParseCryptoCommandExchangeOverride(t *testing.T) {
+	t.Setenv("FINNHUB_CRYPTO_EXCHANGE", "coinbase")
+	t.Setenv("FINNHUB_CRYPTO_QUOTE", "usd")
+
+	asset, _, _, err := parseCryptoCommand("!c BTC")
+	if err != nil || asset.symbol != "COINBASE:BTCUSD" || asset.label != "BTC/USD" {
+		t.Fatalf("parseCryptoCommand() = %+v, %v", asset, err)
+	}
+}
+
+func TestParseFXCommand(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantSymbol string
+		wantLabel  string
+		wantRange  string
+		errSubstr  string
+	}{
+		{input: "!fx USD SGD", wantSymbol: "OANDA:USD_SGD", wantLabel: "USD/SGD"},
+		{input: "!fx usd/thb 90d", wantSymbol: "OANDA:USD_THB", wantLabel: "USD/THB", wantRange: "90d"},
+		{input: "!fx EUR USD ytd", wantSymbol: "OANDA:EUR_USD", wantLabel: "EUR/USD", wantRange: "YTD"},
+		{input: "!fx", errSubstr: "invalid usage"},
+		{input: "!fx USD", errSubstr: "invalid usage"},
+		{input: "!fx USD USD", errSubstr: "invalid currency pair"},
+		{input: "!fx US SGD", errSubstr: "invalid currency pair"},
+		{input: "!fx USD SGD 5d", errSubstr: "only available for stocks"},
+	}
+	for _, tt := range tests {
+		asset, rng, _, err := parseFXCommand(tt.input)
+		if tt.errSubstr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("parseFXCommand(%q) error = %v, want %q", tt.input, err, tt.errSubstr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseFXCommand(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if asset.symbol != tt.wantSymbol || asset.label != tt.wantLabel || rng.label() != tt.wantRange {
+			t.Errorf("parseFXCommand(%q) = %q, %q, %q", tt.input, asset.symbol, asset.label, rng.label())
+		}
+	}
+}
+
+func TestFinnhubCandlesBars(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	candles := finnhubCandles{
+		Status: "ok",
+		Times:  []int64{day.Unix(), day.AddDate(0, 0, 1).Unix()},
+		Open:   []float64{1.35, 1.36},
+		High:   []float64{1.37, 1.38},
+		Low:    []float64{1.34, 1.35},
+		Close:  []float64{1.36, 1.3668},
+	}
+	bars, err := candles.bars()
+	if err != nil || len(bars) != 2 || !bars[1].Date.Equal(day.AddDate(0, 0, 1)) {
+		t.Fatalf("bars() = %v, %v", bars, err)
+	}
+
+	quote := quoteFromCandles(bars)
+	if quote.CurrentPrice != 1.3668 || quote.PreviousClose != 1.36 || quote.Open != 1.36 {
+		t.Fatalf("quoteFromCandles() = %+v", quote)
+	}
+	msg := formatMarketAssetMessage(marketAsset{kind: marketAssetForex, label: "USD/SGD", symbol: "OANDA:USD_SGD"}, quote)
+	for _, want := range []string{"USD/SGD (OANDA:USD_SGD) 🟢", "💱 Rate: 1.3668", "📈 Change: 0.0068 (0.50%)", "📉 Previous Close: 1.3600"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("forex message missing %q:\n%s", want, msg)
+		}
+	}
+
+	if _, err := (finnhubCandles{Status: "no_data"}).bars(); !errors.Is(err, errNoCandleData) {
+		t.Errorf("no_data bars() error = %v, want errNoCandleData", err)
+	}
+	if _, err := (finnhubCandles{Status: "ok", Times: []int64{1}, Close: []float64{1}}).bars(); err == nil {
+		t.Error("bars() accepted arrays of different lengths")
+	}
+}
+
+func TestFormatMarketAssetSummaryForex(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	bars := []HistoricalBar{
+		{Date: day, Open: 34.1, High: 34.5, Low: 34.0, Close: 34.2},
+		{Date: day.AddDate(0, 0, 1), Open: 34.2, High: 34.9, Low: 34.1, Close: 34.884},
+	}
+	got := formatMarketAssetSummary(marketAsset{kind: marketAssetForex, label: "USD/THB"}, "30d", bars, "")
+	want := "USD/THB 30d (2026-09-01 to 2026-09-02)\nRate: 34.8840\nReturn: 2.00%\nRange: 34.0000 - 34.9000"
+	if got != want {
+		t.Fatalf("formatMarketAssetSummary() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatCryptoPrice(t *testing.T) {
+	t.Parallel()
+
+	for v, want := range map[float64]string{
+		69020:      "69020.00",
+		1.5:        "1.50",
+		0.1234567:  "0.1235",
+		0.00001234: "0.00001234",
+		-0.00042:   "-0.0004200",
+		0:          "0.00",
+		1.2e-12:    "0.0000000000",
+	} {
+		if got := formatCryptoPrice(v); got != want {
+			t.Errorf("formatCryptoPrice(%v) = %q, want %q", v, got, want)
+		}
+	}
+
+	msg := formatMarketAssetMessage(marketAsset{kind: marketAssetCrypto, label: "SHIB/USDT", symbol: "BINANCE:SHIBUSDT"},
+		&StockQuote{CurrentPrice: 0.00001234, PreviousClose: 0.0000121, Change: 0.00000024})
+	if strings.Contains(msg, "BINANCE") || !strings.Contains(msg, "SHIB/USDT 🟢") || !strings.Contains(msg, "$0.00001234") {
+		t.Fatalf("formatMarketAssetMessage() =\n%s", msg)
+	}
+}
+
+func TestCryptoHandlerQuote(t *testing.T) {
+	var gotPath, gotSymbol string
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotSymbol = r.URL.Path, r.URL.Query().Get("symbol")
+		_ = json.NewEncoder(w).Encode(finnhubCandles{
+			Status: "ok",
+			Times:  []int64{1760572800, 1760659200},
+			Open:   []float64{67000, 68000},
+			High:   []float64{68500, 69500},
+			Low:    []float64{66500, 67500},
+			Close:  []float64{68000, 69020},
+			Volume: []float64{1200, 1300},
+		})
+	}))
+	server.Start()
+	useRedirectedHTTPClient(t, server.URL)
+	t.Setenv("FINNHUB_API_KEY", "test-key")
+	b, srv := newTestBot(t)
+
+	cryptoHandler(context.Background(), b, groupTextUpdate("!c btc"))
+
+	if gotPath != "/api/v1/crypto/candle" || gotSymbol != "BINANCE:BTCUSDT" {
+		t.Fatalf("requested %s?symbol=%s, want the crypto candle endpoint for BINANCE:BTCUSDT", gotPath, gotSymbol)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, want := range []string{"BTC/USDT 🟢", "💵 Current: $69020.00", "(1.50%)"} {
+		if !strings.Contains(srv.lastMessage, want) {
+			t.Errorf("reply missing %q:\n%s", want, srv.lastMessage)
+		}
+	}
+}
+
+func TestFXHandlerNoData(t *testing.T) {
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/forex/candle" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"s":"no_data"}`))
+	}))
+	server.Start()
+	useRedirectedHTTPClient(t, server.URL)
+	t.Setenv("FINNHUB_API_KEY", "test-key")
+	b, srv := newTestBot(t)
+
+	fxHandler(context.Background(), b, groupTextUpdate("!fx USD SGD 30d"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.lastMessage, "No historical data returned for USD/SGD (30d)") {
+		t.Fatalf("reply = %q, want the no-data notice", srv.lastMessage)
+	}
+}
+
+func TestFXHandlerDateSpanStopsAtSpanEnd(t *testing.T) {
+	var gotFrom, gotTo string
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotFrom, gotTo = r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		_, _ = w.Write([]byte(`{"s":"no_data"}`))
+	}))
+	server.Start()
+	useRedirectedHTTPClient(t, server.URL)
+	t.Setenv("FINNHUB_API_KEY", "test-key")
+	b, _ := newTestBot(t)
+
+	fxHandler(context.Background(), b, groupTextUpdate("!fx USD SGD 2025-01-01..2025-01-31"))
+
+	wantFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	wantTo := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC).Unix() - 1
+	if gotFrom != strconv.FormatInt(wantFrom, 10) || gotTo != strconv.FormatInt(wantTo, 10) {
+		t.Fatalf("requested from=%s to=%s, want %d to %d", gotFrom, gotTo, wantFrom, wantTo)
+	}
+}
+
+func TestCryptoHandlerLongRangeIndicatorsUseChartedBars(t *testing.T) {
+	var gotFrom time.Time
+	var candles []HistoricalBar
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		gotFrom = time.Unix(from, 0).UTC()
+		// One candle a day, each closing a dollar above the last.
+		var resp finnhubCandles
+		resp.Status = "ok"
+		candles = nil
+		for day := gotFrom; day.Unix() <= to; day = day.AddDate(0, 0, 1) {
+			price := float64(len(candles) + 100)
+			candles = append(candles, HistoricalBar{Date: day, Close: price})
+			resp.Times = append(resp.Times, day.Unix())
+			resp.Open = append(resp.Open, price)
+			resp.High = append(resp.High, price)
+			resp.Low = append(resp.Low, price)
+			resp.Close = append(resp.Close, price)
+			resp.Volume = append(resp.Volume, 1)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	server.Start()
+	useRedirectedHTTPClient(t, server.URL)
+	t.Setenv("FINNHUB_API_KEY", "test-key")
+	b, srv := newTestBot(t)
+
+	cryptoHandler(context.Background(), b, groupTextUpdate("!c BTC 1y sma20"))
+
+	rng, _ := parseStockRange("1y", nowFunc())
+	window := rng.dateRange(nowFunc())
+	if want := window.Start.AddDate(0, 0, -21*7); !gotFrom.Equal(want) {
+		t.Fatalf("candles requested from %s, want %s to warm up a 20-week SMA", gotFrom, want)
+	}
+	weekly := formatIndicatorReadings([]indicatorSpec{{kind: indicatorSMA, period: 20}}, closePrices(downsampleBars(candles, barIntervalWeekly)))
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.lastCaption, "Indicators: "+weekly) || !strings.Contains(srv.lastCaption, "Chart and indicators: weekly bars") {
+		t.Fatalf("caption = %q, want the weekly reading %q", srv.lastCaption, weekly)
+	}
+}
+
+func TestParseCryptoCommandRejectsUnsettledIndicator(t *testing.T) {
+	t.Parallel()
+
+	if _, _, _, err := parseCryptoCommand("!c BTC 5y rsi"); err == nil || !strings.Contains(err.Error(), "rsi14 needs more history") {
+		t.Fatalf("parseCryptoCommand() error = %v, want the warm-up rejection", err)
+	}
+}
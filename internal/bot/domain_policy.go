@@ -0,0 +1,307 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	domainsCommand       = "!domains"
+	domainsAdminOnlyMsg  = "Only chat admins can change the link domain lists."
+	domainsSaveFailedMsg = "Domain lists changed, but they could not be saved and will reset when the bot restarts."
+	domainsUsageMsg      = "Usage: !domains [allow|deny|rm DOMAIN...] or !domains clear"
+
+	// maxChatDomains bounds each per-chat list so the settings file stays
+	// small no matter what admins paste.
+	maxChatDomains = 50
+)
+
+// Reasons a URL was kept out of grounding, used in metrics.
+const (
+	domainDeniedReasonDenylist       = "denylist"
+	domainDeniedReasonNotAllowlisted = "not_allowlisted"
+)
+
+// domainPattern accepts bare hostnames with at least one dot (example.com,
+// news.example.co.uk). IP literals never reach extraction anyway.
+var domainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+// deniedURL is a question link the domain policy kept away from extraction.
+type deniedURL struct {
+	URL    string
+	Host   string
+	Reason string
+}
+
+// domainPolicy combines the deployment lists (EXTRACT_ALLOWED_DOMAINS,
+// EXTRACT_DENIED_DOMAINS) with the chat's own lists. A deny entry at either
+// level always wins; each non-empty allowlist must also match, so a chat can
+// narrow the deployment allowlist but never widen it. Entries match the
+// domain itself and every subdomain.
+type domainPolicy struct {
+	allowed     []string
+	denied      []string
+	chatAllowed []string
+	chatDenied  []string
+}
+
+// domainPolicyFor loads the policy for the chat message was sent in; a nil
+// message gets the deployment lists only.
+func domainPolicyFor(message *models.Message) domainPolicy {
+	policy := domainPolicy{
+		allowed: loadDomainList("EXTRACT_ALLOWED_DOMAINS"),
+		denied:  loadDomainList("EXTRACT_DENIED_DOMAINS"),
+	}
+	if message != nil {
+		settings := chatSettingsInstance.get(message.Chat.ID)
+		policy.chatAllowed = settings.AllowedDomains
+		policy.chatDenied = settings.DeniedDomains
+	}
+	return policy
+}
+
+// check reports why host is not allowed, or "" when it may be extracted.
+func (p domainPolicy) check(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	switch {
+	case matchesAnyDomain(host, p.denied), matchesAnyDomain(host, p.chatDenied):
+		return domainDeniedReasonDenylist
+	case len(p.allowed) > 0 && !matchesAnyDomain(host, p.allowed),
+		len(p.chatAllowed) > 0 && !matchesAnyDomain(host, p.chatAllowed):
+		return domainDeniedReasonNotAllowlisted
+	default:
+		return ""
+	}
+}
+
+// checkURL applies check to the host of a normalized extraction URL.
+func (p domainPolicy) checkURL(rawURL string) (host, reason string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", domainDeniedReasonDenylist
+	}
+	host = strings.ToLower(u.Hostname())
+	return host, p.check(host)
+}
+
+func matchesAnyDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDomain turns a user- or env-supplied entry into a bare lowercase
+// hostname. It accepts full URLs, "*.example.com" and a leading "www.", so
+// admins can paste whatever they have at hand.
+func normalizeDomain(raw string) (string, bool) {
+	domain := strings.ToLower(strings.TrimSpace(raw))
+	if strings.Contains(domain, "://") {
+		u, err := url.Parse(domain)
+		if err != nil {
+			return "", false
+		}
+		domain = u.Hostname()
+	}
+	domain, _, _ = strings.Cut(domain, "/")
+	domain = strings.TrimPrefix(domain, "*.")
+	domain = strings.TrimPrefix(domain, "www.")
+	domain = strings.Trim(domain, ".")
+	if !domainPattern.MatchString(domain) {
+		return "", false
+	}
+	return domain, true
+}
+
+// loadDomainList reads a comma-separated domain list from name, skipping
+// (and logging) entries that are not domains.
+func loadDomainList(name string) []string {
+	raw := getenvTrim(name)
+	if raw == "" {
+		return nil
+	}
+	var domains []string
+	for token := range strings.SplitSeq(raw, ",") {
+		if strings.TrimSpace(token) == "" {
+			continue
+		}
+		domain, ok := normalizeDomain(token)
+		if !ok {
+			log.Warn().Str("env", name).Str("value", token).Msg("Invalid domain entry; skipping")
+			continue
+		}
+		if !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// deniedDomainsNote is the one-line notice prepended to an answer when some
+// of the question's links were skipped.
+func deniedDomainsNote(denied []deniedURL) string {
+	var hosts []string
+	for _, d := range denied {
+		host := strings.TrimPrefix(d.Host, "www.")
+		if host != "" && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return ""
+	}
+	return "Skipped links from blocked sites: " + strings.Join(hosts, ", ")
+}
+
+func recordDeniedURLs(ctx context.Context, denied []deniedURL) {
+	for _, d := range denied {
+		appotel.Instruments().ExtractURLsDeniedTotal.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("reason", d.Reason),
+		))
+	}
+}
+
+func shouldHandleDomains(update *models.Update) bool {
+	if update == nil || update.Message == nil {
+		return false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(update.Message.Text), domainsCommand)
+	return ok && (rest == "" || startsWithSpace(rest))
+}
+
+// domainsHandler shows the link domain lists with "!domains" and lets admins
+// edit the chat's own lists: "!domains allow|deny DOMAIN...", "!domains rm
+// DOMAIN..." (removes from both lists) and "!domains clear".
+func domainsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	message := update.Message
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(message.Text), domainsCommand))
+
+	if len(fields) == 0 {
+		appotel.RecordOutcome(ctx, "success")
+		sendSettingsReply(ctx, b, message, formatDomainLists(domainPolicyFor(message)))
+		return
+	}
+
+	action := strings.ToLower(fields[0])
+	var domains []string
+	switch action {
+	case "allow", "deny", "rm":
+		for _, raw := range fields[1:] {
+			domain, ok := normalizeDomain(raw)
+			if !ok {
+				sendSettingsReply(ctx, b, message, fmt.Sprintf("%q is not a domain.", raw))
+				return
+			}
+			domains = append(domains, domain)
+		}
+		if len(domains) == 0 {
+			sendSettingsReply(ctx, b, message, domainsUsageMsg)
+			return
+		}
+	case "clear":
+	default:
+		sendSettingsReply(ctx, b, message, domainsUsageMsg)
+		return
+	}
+
+	if !isChatAdmin(ctx, b, message) {
+		appotel.RecordOutcome(ctx, "blocked")
+		sendSettingsReply(ctx, b, message, domainsAdminOnlyMsg)
+		return
+	}
+
+	var tooMany bool
+	err := chatSettingsInstance.update(message.Chat.ID, func(settings *chatSettings) {
+		switch action {
+		case "allow":
+			settings.DeniedDomains = removeDomains(settings.DeniedDomains, domains)
+			settings.AllowedDomains, tooMany = addDomains(settings.AllowedDomains, domains)
+		case "deny":
+			settings.AllowedDomains = removeDomains(settings.AllowedDomains, domains)
+			settings.DeniedDomains, tooMany = addDomains(settings.DeniedDomains, domains)
+		case "rm":
+			settings.AllowedDomains = removeDomains(settings.AllowedDomains, domains)
+			settings.DeniedDomains = removeDomains(settings.DeniedDomains, domains)
+		case "clear":
+			settings.AllowedDomains = nil
+			settings.DeniedDomains = nil
+		}
+	})
+	if err != nil {
+		appotel.RecordOutcome(ctx, "error")
+		log.Error().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to persist chat domain lists")
+		sendSettingsReply(ctx, b, message, domainsSaveFailedMsg)
+		return
+	}
+
+	log.Info().Int64("chat_id", message.Chat.ID).Str("action", action).Strs("domains", domains).Msg("Chat domain lists changed")
+	appotel.RecordOutcome(ctx, "success")
+	reply := formatDomainLists(domainPolicyFor(message))
+	if tooMany {
+		reply = fmt.Sprintf("Each list holds at most %d domains; the rest were not added.\n\n%s", maxChatDomains, reply)
+	}
+	sendSettingsReply(ctx, b, message, reply)
+}
+
+// addDomains returns list plus the domains not yet in it, up to
+// maxChatDomains, and reports whether some were dropped for the cap. Both
+// helpers copy rather than edit in place, since readers may hold the stored
+// slices.
+func addDomains(list, domains []string) ([]string, bool) {
+	list = slices.Clone(list)
+	for _, domain := range domains {
+		if slices.Contains(list, domain) {
+			continue
+		}
+		if len(list) >= maxChatDomains {
+			return list, true
+		}
+		list = append(list, domain)
+	}
+	return list, false
+}
+
+func removeDomains(list, domains []string) []string {
+	var kept []string
+	for _, domain := range list {
+		if !slices.Contains(domains, domain) {
+			kept = append(kept, domain)
+		}
+	}
+	return kept
+}
+
+func formatDomainLists(policy domainPolicy) string {
+	list := func(domains []string) string {
+		if len(domains) == 0 {
+			return "(none)"
+		}
+		return strings.Join(domains, ", ")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Link domains for this chat\n")
+	sb.WriteString("Allowed: " + list(policy.chatAllowed) + "\n")
+	sb.WriteString("Blocked: " + list(policy.chatDenied))
+	if len(policy.allowed) > 0 || len(policy.denied) > 0 {
+		sb.WriteString("\n\nBot-wide\n")
+		sb.WriteString("Allowed: " + list(policy.allowed) + "\n")
+		sb.WriteString("Blocked: " + list(policy.denied))
+	}
+	sb.WriteString("\n\nAdmins can change this chat's lists with !domains allow|deny|rm DOMAIN or !domains clear.")
+	return sb.String()
+}
@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"Example.com":                  "example.com",
+		" www.ft.com ":                 "ft.com",
+		"*.wsj.com":                    "wsj.com",
+		"https://News.Example.com/a?b": "news.example.com",
+		"example.com/path":             "example.com",
+		"localhost":                    "",
+		"not a domain":                 "",
+		"-bad-.com":                    "",
+		"":                             "",
+	}
+	for raw, want := range tests {
+		got, ok := normalizeDomain(raw)
+		if got != want || ok != (want != "") {
+			t.Errorf("normalizeDomain(%q) = %q, %v; want %q", raw, got, ok, want)
+		}
+	}
+}
+
+func TestDomainPolicyCheck(t *testing.T) {
+	t.Parallel()
+
+	policy := domainPolicy{denied: []string{"ft.com"}, chatDenied: []string{"example.org"}}
+	for host, want := range map[string]string{
+		"ft.com":          domainDeniedReasonDenylist,
+		"markets.ft.com":  domainDeniedReasonDenylist,
+		"notft.com":       "",
+		"www.example.org": domainDeniedReasonDenylist,
+		"go.dev":          "",
+	} {
+		if got := policy.check(host); got != want {
+			t.Errorf("check(%q) = %q, want %q", host, got, want)
+		}
+	}
+
+	narrowed := domainPolicy{allowed: []string{"go.dev", "python.org"}, chatAllowed: []string{"python.org", "rust-lang.org"}}
+	for host, want := range map[string]string{
+		"docs.python.org": "",
+		"go.dev":          domainDeniedReasonNotAllowlisted,
+		"rust-lang.org":   domainDeniedReasonNotAllowlisted,
+	} {
+		if got := narrowed.check(host); got != want {
+			t.Errorf("narrowed check(%q) = %q, want %q", host, got, want)
+		}
+	}
+
+	if got := (domainPolicy{allowed: []string{"ft.com"}, chatDenied: []string{"ft.com"}}).check("ft.com"); got != domainDeniedReasonDenylist {
+		t.Errorf("expected a deny entry to win over an allow entry, got %q", got)
+	}
+}
+
+func TestLoadDomainList(t *testing.T) {
+	t.Setenv("EXTRACT_DENIED_DOMAINS", "ft.com, WWW.FT.COM,,bad domain,https://wsj.com/")
+	got := loadDomainList("EXTRACT_DENIED_DOMAINS")
+	if strings.Join(got, ",") != "ft.com,wsj.com" {
+		t.Fatalf("loadDomainList() = %q", got)
+	}
+}
+
+func TestExtractQuestionURLsAppliesDomainPolicy(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	t.Setenv("EXTRACT_ALLOWED_DOMAINS", "")
+	t.Setenv("EXTRACT_DENIED_DOMAINS", "ft.com")
+	if err := store.update(-100, func(s *chatSettings) { s.DeniedDomains = []string{"example.org"} }); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	message := groupTextUpdate("").Message
+	question := "compare https://www.ft.com/a https://example.org/b https://go.dev/c https://go.dev/d"
+	urls, denied, _ := extractQuestionURLs(message, question, "", 2)
+
+	if strings.Join(urls, " ") != "https://go.dev/c https://go.dev/d" {
+		t.Fatalf("expected denied links not to use up the cap, got %q", urls)
+	}
+	if len(denied) != 2 || denied[0].Host != "www.ft.com" || denied[1].URL != "https://example.org/b" {
+		t.Fatalf("unexpected denied links %+v", denied)
+	}
+	if note := deniedDomainsNote(denied); note != "Skipped links from blocked sites: ft.com, example.org" {
+		t.Fatalf("deniedDomainsNote() = %q", note)
+	}
+
+	// Other chats only get the deployment list.
+	other := &models.Message{Chat: models.Chat{ID: -200}}
+	if urls, denied, _ := extractQuestionURLs(other, question, "", 5); len(urls) != 3 || len(denied) != 1 {
+		t.Fatalf("expected only the deployment denylist elsewhere, got %q / %+v", urls, denied)
+	}
+}
+
+func TestAnswerTextQuestionNotesDeniedLinks(t *testing.T) {
+	withChatSettingsStore(t, newChatSettingsStore(""))
+	prev := textExplainer
+	t.Cleanup(func() { textExplainer = prev })
+	textExplainer = &geminiExplainer{generator: &capturingGenerator{}}
+	t.Setenv("EXTRACT_ENABLED", "true")
+	t.Setenv("EXTRACT_ALLOWED_DOMAINS", "")
+	t.Setenv("EXTRACT_DENIED_DOMAINS", "paywall.example.com")
+	t.Setenv("WEB_SEARCH_PROVIDERS", "none")
+
+	answer, sources, err := answerTextQuestion(context.Background(), nil, "", "summarize https://paywall.example.com/story", false)
+	if err != nil {
+		t.Fatalf("answerTextQuestion() error = %v", err)
+	}
+	if !strings.HasPrefix(answer, "Skipped links from blocked sites: paywall.example.com\n\nexplanation") || sources != nil {
+		t.Fatalf("expected the skip note ahead of an ungrounded answer, got %q (%d sources)", answer, len(sources))
+	}
+}
+
+func TestDomainsHandler_AdminEditsLists(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	t.Setenv("EXTRACT_ALLOWED_DOMAINS", "")
+	t.Setenv("EXTRACT_DENIED_DOMAINS", "")
+	b, srv := newTestBot(t)
+
+	send := func(text string) string {
+		update := privateTextUpdate(text)
+		update.Message.From = &models.User{ID: 42}
+		domainsHandler(context.Background(), b, update)
+		return srv.lastMessage
+	}
+
+	if reply := send("!domains deny https://www.FT.com/ wsj.com"); !strings.Contains(reply, "Blocked: ft.com, wsj.com") {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+	send("!domains allow wsj.com")
+	if got := store.get(42); strings.Join(got.DeniedDomains, ",") != "ft.com" || strings.Join(got.AllowedDomains, ",") != "wsj.com" {
+		t.Fatalf("expected allow to move the domain across lists, got %+v", got)
+	}
+	if reply := send("!domains rm nope"); reply != `"nope" is not a domain.` {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+	if reply := send("!domains block ft.com"); reply != domainsUsageMsg {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+	send("!domains clear")
+	if !store.get(42).isZero() {
+		t.Fatalf("expected clear to reset the chat, got %+v", store.get(42))
+	}
+}
+
+func TestDomainsHandler_GroupNonAdminDenied(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	b, srv := newTestBot(t)
+
+	update := groupTextUpdate("!domains deny ft.com")
+	update.Message.From = &models.User{ID: 7}
+	domainsHandler(context.Background(), b, update)
+
+	if srv.lastMessage != domainsAdminOnlyMsg {
+		t.Fatalf("expected admin-only reply, got %q", srv.lastMessage)
+	}
+	if !store.get(-100).isZero() {
+		t.Fatal("expected settings to stay unchanged")
+	}
+}
+
+func TestShouldHandleDomains(t *testing.T) {
+	for text, want := range map[string]bool{
+		"!domains":           true,
+		"!domains deny a.io": true,
+		"!domainsx":          false,
+	} {
+		if got := shouldHandleDomains(groupTextUpdate(text)); got != want {
+			t.Errorf("shouldHandleDomains(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package bot
+
+import (
+	"context"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+// freshnessVerdict is the rule pre-classifier's answer. Unknown defers the
+// decision to Gemini.
+type freshnessVerdict int
+
+const (
+	freshnessUnknown freshnessVerdict = iota
+	freshnessFresh
+	freshnessTimeless
+)
+
+func (v freshnessVerdict) String() string {
+	switch v {
+	case freshnessFresh:
+		return "fresh"
+	case freshnessTimeless:
+		return "timeless"
+	case freshnessUnknown:
+		return "unknown"
+	}
+	return "unknown"
+}
+
+const (
+	searchDecisionRules  = "rules"
+	searchDecisionGemini = "gemini"
+
+	// modelKnowledgeCutoffYear is the first year the Gemini models are assumed
+	// to know little about; a question naming it or a later year needs the
+	// web.
+	modelKnowledgeCutoffYear = 2025
+)
+
+// freshKeywords are phrases that only make sense about the present. Matched
+// on word boundaries against the lowercased question.
+// They are specific on purpose: a bare "price", "score", "news" or "polls"
+// would also catch timeless questions like "what is price elasticity", "what
+// is a z-score" or "how do news aggregators rank stories".
+var freshKeywords = []string{
+	"today", "tonight", "yesterday", "tomorrow", "right now", "currently",
+	"this week", "this month", "this year", "last week", "next week",
+	"latest", "newest", "recent", "recently", "breaking", "as of",
+	"news about", "news on", "in the news", "any news", "headlines",
+	"price of", "prices of", "price today", "stock price", "share price",
+	"how much is", "exchange rate", "market cap",
+	"score of", "final score", "who won", "standings", "fixtures",
+	"weather", "forecast", "temperature in",
+	"release date", "election results", "poll results", "polls show", "polling average",
+}
+
+// freshKeywordsBurmese are Burmese counterparts. Burmese does not put spaces
+// between words, so these are matched as substrings of the question with its
+// spaces removed. Like freshKeywords they are phrases: a bare "သတင်း" (news),
+// "ရလဒ်" (result), "အခု" (now) or "နောက်ဆုံး" (last) also sits inside
+// "သတင်းစာပညာ" (journalism), "code ရဲ့ ရလဒ်", "အခုလို" (like this) and
+// "နောက်ဆုံးအခန်း" (the last chapter).
+var freshKeywordsBurmese = []string{
+	"ဒီနေ့", "ယနေ့", "မနေ့က", "မနက်ဖြန်", "အခုချိန်", "လောလောဆယ်", "လက်ရှိ",
+	"နောက်ဆုံးရ", "သတင်းထူး", "ဘာသတင်း", "ဈေးနှုန်း", "ငွေလဲနှုန်း", "ရာသီဥတု",
+	"ပွဲရလဒ်",
+}
+
+var (
+	freshKeywordRegexp = regexp.MustCompile(`\b(?:` + joinQuoted(freshKeywords) + `)\b`)
+	yearRegexp         = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+	tickerRegexp       = regexp.MustCompile(`\$[A-Za-z]{1,5}\b`)
+
+	// definitionRegexp matches questions about what something is or means,
+	// whose answer does not change over time. "explain ..." and "difference
+	// between ..." are left to Gemini since they often ask about current
+	// products or events.
+	definitionRegexp = regexp.MustCompile(`^(?:what\s+(?:is|are)\s+(?:a|an)\s|what\s+does\s+.+\s+mean|what's\s+(?:a|an)\s|define\s|eli5\s)`)
+
+	// codeSignalRegexp spots source code in a question or quoted message.
+	codeSignalRegexp = regexp.MustCompile("(?m)```|^\\s*(?:func|def|class|import|package|public|private|const|let|var|return|#include)\\b|[;{}]\\s*$|=>|:=")
+)
+
+func joinQuoted(words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		quoted = append(quoted, regexp.QuoteMeta(w))
+	}
+	return strings.Join(quoted, "|")
+}
+
+// classifyFreshnessByRules decides from the text alone whether a question
+// needs fresh web data. Fresh signals (date words, prices, scores, weather,
+// $TICKERs, recent years) win over timeless ones (definitions, code-only
+// questions). It returns freshnessUnknown, deferring to Gemini, whenever no
+// rule fires. reason names the rule that decided, for the span.
+func classifyFreshnessByRules(message, question string) (verdict freshnessVerdict, reason string) {
+	text := strings.TrimSpace(question)
+	if text == "" {
+		text = strings.TrimSpace(message)
+	}
+	if text == "" {
+		return freshnessUnknown, ""
+	}
+	lower := strings.ToLower(text)
+
+	if freshKeywordRegexp.MatchString(lower) {
+		return freshnessFresh, "keyword"
+	}
+	unspaced := strings.ReplaceAll(text, " ", "")
+	for _, kw := range freshKeywordsBurmese {
+		if strings.Contains(unspaced, kw) {
+			return freshnessFresh, "keyword"
+		}
+	}
+	if tickerRegexp.MatchString(text) {
+		return freshnessFresh, "ticker"
+	}
+	for _, match := range yearRegexp.FindAllString(lower, -1) {
+		if year, err := strconv.Atoi(match); err == nil && year >= modelKnowledgeCutoffYear {
+			return freshnessFresh, "recent_year"
+		}
+	}
+
+	if isCodeQuestion(message, question) {
+		return freshnessTimeless, "code"
+	}
+	if definitionRegexp.MatchString(lower) {
+		return freshnessTimeless, "definition"
+	}
+	return freshnessUnknown, ""
+}
+
+// isCodeQuestion reports whether the material being asked about is source
+// code: either the quoted message is code, or the question carries code
+// with at most a short lead-in.
+func isCodeQuestion(message, question string) bool {
+	if codeSignalRegexp.MatchString(message) {
+		return true
+	}
+	if !codeSignalRegexp.MatchString(question) {
+		return false
+	}
+	prose := 0
+	for line := range strings.Lines(question) {
+		if !codeSignalRegexp.MatchString(line) && strings.TrimSpace(line) != "" {
+			prose++
+		}
+	}
+	return prose <= 1
+}
+
+// planSearch decides whether a question needs web search, trying the rules
+// first and spending a Gemini call only when they are unsure. A rule verdict
+// of "fresh" uses the question itself as the search objective and query.
+func planSearch(ctx context.Context, message, question string) (*searchPlan, error) {
+	ctx, span := tracer().Start(ctx, "search.plan")
+	defer span.End()
+
+	verdict, reason := classifyFreshnessByRules(message, question)
+	if verdict != freshnessUnknown {
+		plan := &searchPlan{NeedsSearch: verdict == freshnessFresh}
+		normalizeSearchPlan(plan, sanitizeForPrompt(message, maxExplainInputLength), sanitizeForPrompt(question, maxQuestionInputLength))
+		span.SetAttributes(
+			attribute.String("search.decision_source", searchDecisionRules),
+			attribute.String("search.rule_reason", reason),
+			attribute.Bool("search.needs_search", plan.NeedsSearch),
+		)
+		recordSearchDecision(ctx, searchDecisionRules, plan.NeedsSearch)
+		maybeShadowClassify(ctx, message, question, verdict)
+		return plan, nil
+	}
+
+	span.SetAttributes(attribute.String("search.decision_source", searchDecisionGemini))
+	plan, err := textExplainer.classifySearchNeed(ctx, message, question)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("search.needs_search", plan.NeedsSearch))
+	recordSearchDecision(ctx, searchDecisionGemini, plan.NeedsSearch)
+	return plan, nil
+}
+
+func recordSearchDecision(ctx context.Context, source string, needsSearch bool) {
+	appotel.Instruments().SearchDecisionsTotal.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("source", source),
+		attribute.Bool("needs_search", needsSearch),
+	))
+}
+
+// maybeShadowClassify re-checks a sampled share of rule decisions with Gemini
+// in the background (FRESHNESS_RULES_SHADOW_RATE, 0 to 1, default 0) and
+// records whether the two agree, so the rules can be tuned against the
+// model's judgment without slowing down the answer.
+func maybeShadowClassify(ctx context.Context, message, question string, verdict freshnessVerdict) {
+	rate := loadFreshnessShadowRate()
+	if rate <= 0 || textExplainer == nil || rand.Float64() >= rate { //nolint:gosec // sampling, not security
+		return
+	}
+
+	shadowCtx := context.WithoutCancel(ctx)
+	go func() {
+		plan, err := textExplainer.classifySearchNeed(shadowCtx, message, question)
+		if err != nil {
+			log.Debug().Err(err).Msg("Shadow freshness classification failed")
+			return
+		}
+		recordClassifierAgreement(shadowCtx, verdict, plan.NeedsSearch)
+	}()
+}
+
+func recordClassifierAgreement(ctx context.Context, verdict freshnessVerdict, geminiNeedsSearch bool) {
+	agree := (verdict == freshnessFresh) == geminiNeedsSearch
+	appotel.Instruments().SearchClassifierAgreementTotal.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("rule_verdict", verdict.String()),
+		attribute.Bool("agree", agree),
+	))
+	if !agree {
+		log.Info().
+			Str("rule_verdict", verdict.String()).
+			Bool("gemini_needs_search", geminiNeedsSearch).
+			Msg("Freshness rules disagreed with Gemini")
+	}
+}
+
+// loadFreshnessShadowRate reads FRESHNESS_RULES_SHADOW_RATE, clamped to
+// [0, 1]; missing or invalid values disable shadowing.
+func loadFreshnessShadowRate() float64 {
+	raw := getenvTrim("FRESHNESS_RULES_SHADOW_RATE")
+	if raw == "" {
+		return 0
+	}
+	rate, err := strconv.ParseFloat(raw, 64)
+	if err != nil || rate <= 0 {
+		return 0
+	}
+	return min(rate, 1)
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClassifyFreshnessByRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		message    string
+		question   string
+		want       freshnessVerdict
+		wantReason string
+	}{
+		{name: "date word", question: "What's the weather in Yangon today?", want: freshnessFresh, wantReason: "keyword"},
+		{name: "price", question: "stock price of nvidia", want: freshnessFresh, wantReason: "keyword"},
+		{name: "score", question: "who won the match last night", want: freshnessFresh, wantReason: "keyword"},
+		{name: "burmese", question: "ဒီနေ့ ရန်ကုန် ရာသီဥတု ဘယ်လိုလဲ", want: freshnessFresh, wantReason: "keyword"},
+		{name: "burmese latest news", question: "ရွှေဈေး နောက်ဆုံးရ သတင်း", want: freshnessFresh, wantReason: "keyword"},
+		{name: "burmese election result", question: "ရွေးကောက်ပွဲ ရလဒ် ထွက်ပြီလား", want: freshnessFresh, wantReason: "keyword"},
+		{name: "burmese journalism", question: "သတင်းစာပညာ ဆိုတာ ဘာလဲ", want: freshnessUnknown},
+		{name: "burmese code result", question: "ဒီ loop ရဲ့ ရလဒ် ဘာကြောင့် မှားနေလဲ", want: freshnessUnknown},
+		{name: "burmese like this", question: "အခုလို ရေးရင် ဘာကွာလဲ", want: freshnessUnknown},
+		{name: "burmese last chapter", question: "စာအုပ်ရဲ့ နောက်ဆုံးအခန်း ကို ရှင်းပြပါ", want: freshnessUnknown},
+		{name: "ticker", question: "thoughts on $TSLA?", want: freshnessFresh, wantReason: "ticker"},
+		{name: "recent year", question: "2026 world cup host cities", want: freshnessFresh, wantReason: "recent_year"},
+		{name: "fresh beats definition", question: "what is a good stock price today", want: freshnessFresh, wantReason: "keyword"},
+		{name: "definition", question: "What is a mutex?", want: freshnessTimeless, wantReason: "definition"},
+		{name: "eli5", question: "eli5 recursion", want: freshnessTimeless, wantReason: "definition"},
+		{name: "explain defers", question: "explain the new iPhone lineup", want: freshnessUnknown},
+		{name: "difference defers", question: "difference between the pixel 10 and pixel 11", want: freshnessUnknown},
+		{name: "news phrase", question: "any news on the fed decision?", want: freshnessFresh, wantReason: "keyword"},
+		{name: "bare news", question: "how do news aggregators rank stories", want: freshnessUnknown},
+		{name: "bare polls", question: "how do exit polls work", want: freshnessUnknown},
+		{name: "quoted code", message: "func main() {\n\tfmt.Println(\"hi\")\n}", question: "why does this not compile?", want: freshnessTimeless, wantReason: "code"},
+		{name: "inline code", question: "what does this do?\nx := make([]int, 0, 10)", want: freshnessTimeless, wantReason: "code"},
+		{name: "old year", question: "1998 world cup host cities", want: freshnessUnknown},
+		{name: "price elasticity", question: "how does price elasticity work", want: freshnessUnknown},
+		{name: "z-score", question: "how do I compute a z-score", want: freshnessUnknown},
+		{name: "open question", question: "should I learn Rust or Go", want: freshnessUnknown},
+		{name: "empty", want: freshnessUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, reason := classifyFreshnessByRules(tt.message, tt.question)
+			if got != tt.want || reason != tt.wantReason {
+				t.Fatalf("classifyFreshnessByRules() = %s/%q, want %s/%q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPlanSearchSkipsGeminiWhenRulesDecide(t *testing.T) {
+	prev := textExplainer
+	t.Cleanup(func() { textExplainer = prev })
+	generator := &capturingJSONGenerator{jsonBody: `{"needs_search": false}`}
+	textExplainer = &geminiExplainer{generator: generator}
+	t.Setenv("FRESHNESS_RULES_SHADOW_RATE", "")
+
+	plan, err := planSearch(context.Background(), "", "AAPL stock price today")
+	if err != nil {
+		t.Fatalf("planSearch() error = %v", err)
+	}
+	if !plan.NeedsSearch || plan.Objective != "AAPL stock price today" || len(plan.SearchQueries) != 1 {
+		t.Fatalf("unexpected rule plan %+v", plan)
+	}
+
+	plan, err = planSearch(context.Background(), "", "what is a goroutine?")
+	if err != nil || plan.NeedsSearch {
+		t.Fatalf("planSearch() = %+v, %v; want no search", plan, err)
+	}
+	if generator.capturedContents != nil {
+		t.Fatal("expected rule verdicts to skip the Gemini classifier")
+	}
+
+	if _, err := planSearch(context.Background(), "", "should I learn Rust or Go"); err != nil {
+		t.Fatalf("planSearch() error = %v", err)
+	}
+	if generator.capturedContents == nil {
+		t.Fatal("expected an undecided question to reach the Gemini classifier")
+	}
+}
+
+func TestLoadFreshnessShadowRate(t *testing.T) {
+	tests := map[string]float64{
+		"":     0,
+		"junk": 0,
+		"-0.5": 0,
+		"0.25": 0.25,
+		"3":    1,
+	}
+	for raw, want := range tests {
+		t.Setenv("FRESHNESS_RULES_SHADOW_RATE", raw)
+		if got := loadFreshnessShadowRate(); got != want {
+			t.Errorf("loadFreshnessShadowRate(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -854,7 +854,7 @@ func FuzzExtractQuestionURLs(f *testing.F) {
 			},
 		}
 
-		urls, _ := extractQuestionURLs(message, question, quoted, maxURLs)
+		urls, _, _ := extractQuestionURLs(message, question, quoted, maxURLs)
 
 		effectiveMax := maxURLs
 		if effectiveMax <= 0 {
@@ -1018,7 +1018,7 @@ func FuzzStripAskPrefix(f *testing.F) {
 
 // FuzzParseStockCommand verifies that a successfully parsed !s command:
 //   - returns an uppercase symbol matching symbolRegex
-//   - returns days of 0 or one of the supported range values
+//   - returns no range or one that resolves to 1 day to 5 years
 //   - round-trips through its canonical "!s SYMBOL" form
 func FuzzParseStockCommand(f *testing.F) {
 	f.Add("!s AAPL")
@@ -1027,12 +1027,14 @@ func FuzzParseStockCommand(f *testing.F) {
 	f.Add("!s aapl 30D")
 	f.Add("!s")
 	f.Add("!s AAPL 1d")
+	f.Add("!s AAPL ytd")
+	f.Add("!s AAPL 2024-01-01..2024-06-30")
 	f.Add("!s AAPL extra token")
 	f.Add("aapl")
 	f.Add("")
 
 	f.Fuzz(func(t *testing.T, text string) {
-		symbol, days, err := parseStockCommand(text)
+		symbol, rng, _, err := parseStockCommand(text)
 		if err != nil {
 			return
 		}
@@ -1047,27 +1049,21 @@ func FuzzParseStockCommand(f *testing.F) {
 			t.Fatalf("parseStockCommand(%q) symbol %q not uppercase", text, symbol)
 		}
 
-		// Property: days is 0 or a supported range value.
-		if days != 0 {
-			supported := false
-			for _, d := range stockRangeDays {
-				if days == d {
-					supported = true
-					break
-				}
-			}
-			if !supported {
-				t.Fatalf("parseStockCommand(%q) days %d not in stockRangeDays", text, days)
+		// Property: a range, when present, resolves to a window of 1 day to
+		// 5 years.
+		if !rng.isZero() {
+			if days := rangeDays(rng.dateRange(nowFunc())); days < 1 || days > maxStockRangeDays {
+				t.Fatalf("parseStockCommand(%q) range %q spans %d days", text, rng.label(), days)
 			}
 		}
 
 		// Property: the canonical form reparses to the same symbol, no range.
-		canonical, canonicalDays, canonicalErr := parseStockCommand("!s " + symbol)
+		canonical, canonicalRange, _, canonicalErr := parseStockCommand("!s " + symbol)
 		if canonicalErr != nil {
 			t.Fatalf("canonical !s %q failed to parse: %v", symbol, canonicalErr)
 		}
-		if canonical != symbol || canonicalDays != 0 {
-			t.Fatalf("canonical parse = (%q, %d), want (%q, 0)", canonical, canonicalDays, symbol)
+		if canonical != symbol || !canonicalRange.isZero() {
+			t.Fatalf("canonical parse = (%q, %q), want (%q, no range)", canonical, canonicalRange.label(), symbol)
 		}
 	})
 }
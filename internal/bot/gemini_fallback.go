@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genai"
+)
+
+const (
+	// maxGeminiFallbackModels caps GEMINI_FALLBACK_MODELS so a long list
+	// cannot turn one request into many billable calls.
+	maxGeminiFallbackModels = 2
+
+	genAIResponseModelAttr = "gen_ai.response.model"
+	geminiAttemptCountAttr = "gemini.attempt_count"
+)
+
+// errEmptyGeminiResponse marks a response that was neither blocked nor
+// carried any text. It is retryable: another model usually answers.
+var errEmptyGeminiResponse = errors.New("empty response from Gemini")
+
+// loadGeminiFallbackModels parses GEMINI_FALLBACK_MODELS, a comma-separated,
+// ordered list of models tried after the primary model fails with a retryable
+// error. Blank and duplicate entries are dropped.
+func loadGeminiFallbackModels() []string {
+	raw := getenvTrim("GEMINI_FALLBACK_MODELS")
+	if raw == "" {
+		return nil
+	}
+
+	var models []string
+	for token := range strings.SplitSeq(raw, ",") {
+		model := strings.TrimSpace(token)
+		if model == "" || slices.Contains(models, model) {
+			continue
+		}
+		models = append(models, model)
+		if len(models) == maxGeminiFallbackModels {
+			break
+		}
+	}
+	return models
+}
+
+// geminiModelChain returns the primary model followed by its fallbacks,
+// skipping any fallback equal to the primary.
+func geminiModelChain(primary string, fallbacks []string) []string {
+	chain := []string{primary}
+	for _, model := range fallbacks {
+		if !slices.Contains(chain, model) {
+			chain = append(chain, model)
+		}
+	}
+	return chain
+}
+
+// isRetryableGeminiError reports whether a failed attempt may be retried on
+// the next model: per-attempt timeouts, rate limiting (429), overload (503),
+// and empty responses. Safety blocks are never retried.
+func isRetryableGeminiError(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, ErrExplainBlocked):
+		return false
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, errEmptyGeminiResponse):
+		return true
+	}
+	if apiErr, ok := errors.AsType[genai.APIError](err); ok {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// attemptTimeout splits what is left of the total deadline across the
+// remaining attempts. Every attempt but the last gets two thirds of the
+// remaining budget, so a slow primary still leaves room for one fallback
+// while getting most of the time when it is healthy.
+func attemptTimeout(remaining time.Duration, attemptsLeft int) time.Duration {
+	if attemptsLeft <= 1 {
+		return remaining
+	}
+	return remaining * 2 / 3
+}
+
+// geminiGeneration is the outcome of generateWithFallback.
+type geminiGeneration struct {
+	resp     *genai.GenerateContentResponse
+	text     string
+	model    string
+	attempts int
+}
+
+// generateWithFallback calls GenerateContent on each model in chain until one
+// returns non-empty text, moving on only after a retryable failure. All
+// attempts share the total timeout; a safety block stops the chain at once and
+// returns ErrExplainBlocked. The attempt count and the final model are set on
+// span. logLabel names the feature in log lines (e.g. "explain").
+func generateWithFallback(
+	ctx context.Context,
+	span trace.Span,
+	generator geminiContentGenerator,
+	chain []string,
+	timeout time.Duration,
+	contents []*genai.Content,
+	config *genai.GenerateContentConfig,
+	logLabel string,
+) (gen geminiGeneration, err error) {
+	deadline := time.Now().Add(timeout)
+	totalCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	defer func() {
+		span.SetAttributes(
+			attribute.Int(geminiAttemptCountAttr, gen.attempts),
+			attribute.String(genAIResponseModelAttr, gen.model),
+		)
+	}()
+
+	for i, model := range chain {
+		gen.attempts = i + 1
+		gen.model = model
+
+		attemptCtx, attemptCancel := context.WithTimeout(totalCtx, attemptTimeout(time.Until(deadline), len(chain)-i))
+		gen.resp, gen.text, err = generateOnce(attemptCtx, ctx, generator, model, contents, config, logLabel)
+		attemptCancel()
+
+		if err == nil || !isRetryableGeminiError(err) || i == len(chain)-1 || totalCtx.Err() != nil {
+			break
+		}
+		log.Warn().
+			Err(err).
+			Str("model", model).
+			Str("next_model", chain[i+1]).
+			Str("feature", logLabel).
+			Msg("Gemini attempt failed; trying fallback model")
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return gen, ErrExplainTimeout
+	}
+	return gen, err
+}
+
+// generateOnce runs a single attempt. Token usage is recorded on usageCtx,
+// the caller's span context, so it is not lost when attemptCtx is cancelled.
+func generateOnce(
+	attemptCtx context.Context,
+	usageCtx context.Context,
+	generator geminiContentGenerator,
+	model string,
+	contents []*genai.Content,
+	config *genai.GenerateContentConfig,
+	logLabel string,
+) (*genai.GenerateContentResponse, string, error) {
+	resp, err := generator.GenerateContent(attemptCtx, model, contents, config)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			return nil, "", context.DeadlineExceeded
+		}
+		return nil, "", fmt.Errorf("gemini generate content failed: %w", err)
+	}
+	if resp == nil {
+		return nil, "", errEmptyGeminiResponse
+	}
+	recordGeminiTokenUsage(usageCtx, model, resp)
+	if blocked, reason := isGeminiResponseBlocked(resp); blocked {
+		log.Warn().Str("reason", reason).Str("model", model).Msgf("Gemini blocked %s response", logLabel)
+		return resp, "", ErrExplainBlocked
+	}
+
+	out := strings.TrimSpace(resp.Text())
+	if out == "" {
+		logEmptyGeminiResponse(resp, firstCandidateFinishReason(resp))
+		return resp, "", errEmptyGeminiResponse
+	}
+	return resp, out, nil
+}
@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genai"
+)
+
+// scriptedGeneratorStep is one canned GenerateContent outcome. A step with
+// wait set blocks until the attempt context is done.
+type scriptedGeneratorStep struct {
+	resp *genai.GenerateContentResponse
+	err  error
+	wait bool
+}
+
+type scriptedGenerator struct {
+	mu     sync.Mutex
+	steps  []scriptedGeneratorStep
+	models []string
+}
+
+func (s *scriptedGenerator) GenerateContent(
+	ctx context.Context,
+	model string,
+	_ []*genai.Content,
+	_ *genai.GenerateContentConfig,
+) (*genai.GenerateContentResponse, error) {
+	s.mu.Lock()
+	s.models = append(s.models, model)
+	step := s.steps[0]
+	if len(s.steps) > 1 {
+		s.steps = s.steps[1:]
+	}
+	s.mu.Unlock()
+
+	if step.wait {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return step.resp, step.err
+}
+
+func (s *scriptedGenerator) calledModels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.models)
+}
+
+func textResponse(text string) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{Content: &genai.Content{Parts: []*genai.Part{{Text: text}}}},
+		},
+	}
+}
+
+func blockedResponse() *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonSafety},
+	}
+}
+
+func TestLoadGeminiFallbackModels(t *testing.T) {
+	t.Setenv("GEMINI_FALLBACK_MODELS", "")
+	if got := loadGeminiFallbackModels(); got != nil {
+		t.Fatalf("expected no fallbacks when unset, got %v", got)
+	}
+
+	t.Setenv("GEMINI_FALLBACK_MODELS", " gemini-lite , ,gemini-lite,gemini-old,gemini-extra")
+	got := loadGeminiFallbackModels()
+	want := []string{"gemini-lite", "gemini-old"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("loadGeminiFallbackModels() = %v, want %v", got, want)
+	}
+}
+
+func TestGeminiModelChainSkipsPrimaryDuplicate(t *testing.T) {
+	got := geminiModelChain("primary", []string{"primary", "lite"})
+	if !slices.Equal(got, []string{"primary", "lite"}) {
+		t.Fatalf("unexpected chain %v", got)
+	}
+}
+
+func TestIsRetryableGeminiError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "blocked", err: ErrExplainBlocked, want: false},
+		{name: "deadline", err: context.DeadlineExceeded, want: true},
+		{name: "empty", err: errEmptyGeminiResponse, want: true},
+		{name: "429", err: genai.APIError{Code: http.StatusTooManyRequests}, want: true},
+		{name: "wrapped 503", err: errors.Join(errors.New("call"), genai.APIError{Code: http.StatusServiceUnavailable}), want: true},
+		{name: "400", err: genai.APIError{Code: http.StatusBadRequest}, want: false},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+	for _, tt := range tests {
+		if got := isRetryableGeminiError(tt.err); got != tt.want {
+			t.Errorf("%s: isRetryableGeminiError() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExplainFallsBackOnOverload(t *testing.T) {
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{
+		{err: genai.APIError{Code: http.StatusServiceUnavailable}},
+		{resp: textResponse("from lite")},
+	}}
+	explainer := &geminiExplainer{generator: gen, model: "primary", fallbackModels: []string{"lite"}}
+
+	out, err := explainer.explainWithLanguage(context.Background(), "text", "", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(gen.calledModels(), []string{"primary", "lite"}) {
+		t.Fatalf("unexpected model attempts %v", gen.calledModels())
+	}
+	if !strings.HasPrefix(out, "from lite") {
+		t.Fatalf("expected fallback answer, got %q", out)
+	}
+}
+
+func TestExplainFallsBackOnEmptyResponse(t *testing.T) {
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{
+		{resp: textResponse("   ")},
+		{resp: textResponse("answer")},
+	}}
+	explainer := &geminiExplainer{generator: gen, model: "primary", fallbackModels: []string{"lite"}}
+
+	if _, err := explainer.explainWithLanguage(context.Background(), "text", "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gen.calledModels()) != 2 {
+		t.Fatalf("expected 2 attempts, got %v", gen.calledModels())
+	}
+}
+
+func TestExplainNeverRetriesSafetyBlock(t *testing.T) {
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{
+		{resp: blockedResponse()},
+		{resp: textResponse("should not be used")},
+	}}
+	explainer := &geminiExplainer{generator: gen, model: "primary", fallbackModels: []string{"lite"}}
+
+	_, err := explainer.explainWithLanguage(context.Background(), "text", "", false)
+	if !errors.Is(err, ErrExplainBlocked) {
+		t.Fatalf("expected ErrExplainBlocked, got %v", err)
+	}
+	if !slices.Equal(gen.calledModels(), []string{"primary"}) {
+		t.Fatalf("expected a single attempt, got %v", gen.calledModels())
+	}
+}
+
+func TestExplainDoesNotRetryClientErrors(t *testing.T) {
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{
+		{err: genai.APIError{Code: http.StatusBadRequest}},
+	}}
+	explainer := &geminiExplainer{generator: gen, model: "primary", fallbackModels: []string{"lite"}}
+
+	if _, err := explainer.explainWithLanguage(context.Background(), "text", "", false); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(gen.calledModels()) != 1 {
+		t.Fatalf("expected a single attempt, got %v", gen.calledModels())
+	}
+}
+
+func TestExplainFallbackSharesTotalTimeout(t *testing.T) {
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{
+		{wait: true},
+		{resp: textResponse("answer")},
+	}}
+	explainer := &geminiExplainer{
+		generator:      gen,
+		model:          "primary",
+		fallbackModels: []string{"lite"},
+		explainTimeout: 300 * time.Millisecond,
+	}
+
+	start := time.Now()
+	if _, err := explainer.explainWithLanguage(context.Background(), "text", "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 300*time.Millisecond {
+		t.Fatalf("expected fallback within the total deadline, took %v", elapsed)
+	}
+	if !slices.Equal(gen.calledModels(), []string{"primary", "lite"}) {
+		t.Fatalf("unexpected model attempts %v", gen.calledModels())
+	}
+}
+
+func TestExplainTimeoutAfterAllAttempts(t *testing.T) {
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{{wait: true}}}
+	explainer := &geminiExplainer{
+		generator:      gen,
+		model:          "primary",
+		fallbackModels: []string{"lite"},
+		explainTimeout: 100 * time.Millisecond,
+	}
+
+	_, err := explainer.explainWithLanguage(context.Background(), "text", "", false)
+	if !errors.Is(err, ErrExplainTimeout) {
+		t.Fatalf("expected ErrExplainTimeout, got %v", err)
+	}
+	if len(gen.calledModels()) != 2 {
+		t.Fatalf("expected both models to be tried, got %v", gen.calledModels())
+	}
+}
+
+func TestGenerateWithFallbackRecordsSpanAttributes(t *testing.T) {
+	mem := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(mem))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{
+		{err: genai.APIError{Code: http.StatusTooManyRequests}},
+		{resp: textResponse("answer")},
+	}}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "gemini.test")
+	result, err := generateWithFallback(ctx, span, gen, []string{"primary", "lite"}, time.Second, nil, nil, "test")
+	span.End()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.model != "lite" || result.attempts != 2 || result.text != "answer" {
+		t.Fatalf("unexpected generation %+v", result)
+	}
+
+	spans := mem.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attrs := map[string]string{}
+	for _, kv := range spans[0].Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs[geminiAttemptCountAttr] != "2" || attrs[genAIResponseModelAttr] != "lite" {
+		t.Fatalf("unexpected span attributes %v", attrs)
+	}
+}
+
+func TestAnalyzeFallsBackOnRateLimit(t *testing.T) {
+	gen := &scriptedGenerator{steps: []scriptedGeneratorStep{
+		{err: genai.APIError{Code: http.StatusTooManyRequests}},
+		{resp: textResponse("analysis")},
+	}}
+	analyzer := &stockAnalyzer{generator: gen, model: "primary", fallbackModels: []string{"lite"}, timeout: time.Second}
+
+	out, err := analyzer.analyze(context.Background(), &stockAnalysisInput{Symbol: "AAPL"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "analysis" {
+		t.Fatalf("expected fallback analysis, got %q", out)
+	}
+	if !slices.Equal(gen.calledModels(), []string{"primary", "lite"}) {
+		t.Fatalf("unexpected model attempts %v", gen.calledModels())
+	}
+}
@@ -0,0 +1,493 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultGitHubAPIBaseURL = "https://api.github.com"
+	defaultGitHubAPITimeout = 10 * time.Second
+	githubAPIVersion        = "2022-11-28"
+
+	// maxGitHubExcerpts bounds the excerpts per link; issue threads and
+	// large files are cut to fit, header first.
+	maxGitHubExcerpts = 6
+	// maxGitHubComments is how many issue comments are read, oldest first.
+	maxGitHubComments = 10
+	// maxGitHubCommentRunes keeps one long comment from crowding out the rest.
+	maxGitHubCommentRunes = 600
+	// maxGitHubPRFiles is how many changed files are listed for a PR.
+	maxGitHubPRFiles = 30
+	// maxGitHubRawBytes bounds README and file reads.
+	maxGitHubRawBytes = 1 << 20
+)
+
+var errGitHubNotFound = errors.New("github resource not found or private")
+
+// githubLinkResolver answers github.com links from the GitHub REST API
+// rather than scraping the page, which is mostly navigation chrome. Each
+// link becomes one parallelExtractResult, the same shape the page
+// extractors return, so the grounded explainer treats them alike.
+// GITHUB_TOKEN is optional; without it the API allows 60 requests an hour.
+type githubLinkResolver struct {
+	baseURL string
+	token   string
+	timeout time.Duration
+}
+
+// newGitHubLinkResolver returns nil when EXTRACT_ENABLED or
+// GITHUB_LINKS_ENABLED is explicitly false.
+func newGitHubLinkResolver() *githubLinkResolver {
+	if !loadExtractEnabled() || !loadGitHubLinksEnabled() {
+		return nil
+	}
+	return &githubLinkResolver{
+		baseURL: defaultGitHubAPIBaseURL,
+		token:   getenvTrim("GITHUB_TOKEN"),
+		timeout: defaultGitHubAPITimeout,
+	}
+}
+
+// resolve fetches every link concurrently. Links that could not be resolved
+// are returned in failed, in order, so the caller can try generic page
+// extraction on them instead.
+func (g *githubLinkResolver) resolve(ctx context.Context, urls []string) (results []parallelExtractResult, failed []string) {
+	if g == nil {
+		return nil, urls
+	}
+
+	resolved := make([]parallelExtractResult, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Go(func() {
+			resolved[i], errs[i] = g.resolveLink(ctx, u)
+		})
+	}
+	wg.Wait()
+
+	for i, result := range resolved {
+		if errs[i] != nil {
+			// Only the host is logged: a full link can name a private
+			// repository or carry query tokens.
+			log.Warn().Err(errs[i]).Str("host", urlHost(urls[i])).Msg("GitHub link lookup failed; falling back to page extraction")
+			failed = append(failed, urls[i])
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, failed
+}
+
+func (g *githubLinkResolver) resolveLink(ctx context.Context, rawURL string) (result parallelExtractResult, err error) {
+	link, ok := parseGitHubLink(rawURL)
+	if !ok {
+		return result, errors.New("not a supported GitHub link")
+	}
+
+	ctx, span := tracer().Start(
+		ctx, "github.resolve",
+		trace.WithAttributes(
+			attribute.String("github.kind", string(link.kind)),
+			attribute.String("github.repo", link.owner+"/"+link.repo),
+		),
+	)
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(g.timeout, defaultGitHubAPITimeout))
+	defer cancel()
+
+	switch link.kind {
+	case githubLinkRepo:
+		result, err = g.resolveRepo(ctx, link)
+	case githubLinkIssue:
+		result, err = g.resolveIssue(ctx, link)
+	case githubLinkPull:
+		result, err = g.resolvePull(ctx, link)
+	case githubLinkBlob:
+		result, err = g.resolveBlob(ctx, link)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.URL = rawURL
+	result.Title = sanitizeForPrompt(result.Title, maxTitleRuneLen)
+	if len(result.Excerpts) > maxGitHubExcerpts {
+		result.Excerpts = result.Excerpts[:maxGitHubExcerpts]
+	}
+	for i, e := range result.Excerpts {
+		result.Excerpts[i] = sanitizeForPrompt(e, maxParallelExtractExcerptRuneLen)
+	}
+	span.SetAttributes(attribute.Int("github.excerpts_count", len(result.Excerpts)))
+	return result, nil
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubRepo struct {
+	FullName        string `json:"full_name"`
+	Description     string `json:"description"`
+	Language        string `json:"language"`
+	StargazersCount int    `json:"stargazers_count"`
+	DefaultBranch   string `json:"default_branch"`
+	Archived        bool   `json:"archived"`
+	PushedAt        string `json:"pushed_at"`
+}
+
+type githubIssue struct {
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	State     string     `json:"state"`
+	User      githubUser `json:"user"`
+	CreatedAt string     `json:"created_at"`
+	Comments  int        `json:"comments"`
+}
+
+type githubComment struct {
+	Body string     `json:"body"`
+	User githubUser `json:"user"`
+}
+
+type githubPull struct {
+	githubIssue
+
+	Merged       bool `json:"merged"`
+	Draft        bool `json:"draft"`
+	Commits      int  `json:"commits"`
+	Additions    int  `json:"additions"`
+	Deletions    int  `json:"deletions"`
+	ChangedFiles int  `json:"changed_files"`
+	Base         struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+}
+
+type githubPullFile struct {
+	Filename  string `json:"filename"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// resolveRepo describes the repository and excerpts its README.
+func (g *githubLinkResolver) resolveRepo(ctx context.Context, link githubLink) (parallelExtractResult, error) {
+	repoPath := githubRepoPath(link)
+	var repo githubRepo
+	if err := g.getJSON(ctx, repoPath, nil, &repo); err != nil {
+		return parallelExtractResult{}, err
+	}
+
+	header := fmt.Sprintf("Repository %s", cmp.Or(repo.FullName, link.owner+"/"+link.repo))
+	if repo.Description != "" {
+		header += ": " + repo.Description
+	}
+	var facts []string
+	if repo.Language != "" {
+		facts = append(facts, "written mainly in "+repo.Language)
+	}
+	facts = append(facts, fmt.Sprintf("%d stars", repo.StargazersCount))
+	if repo.DefaultBranch != "" {
+		facts = append(facts, "default branch "+repo.DefaultBranch)
+	}
+	if repo.Archived {
+		facts = append(facts, "archived")
+	}
+	header += " (" + strings.Join(facts, ", ") + ")."
+	excerpts := []string{header}
+
+	readme, err := g.getRaw(ctx, repoPath+"/readme", nil)
+	switch {
+	case errors.Is(err, errGitHubNotFound):
+		// A repository without a README is still worth describing.
+	case err != nil:
+		return parallelExtractResult{}, fmt.Errorf("fetch readme: %w", err)
+	default:
+		excerpts = append(excerpts, chunkLines(readme, maxParallelExtractExcerptRuneLen)...)
+	}
+
+	return parallelExtractResult{
+		Title:       cmp.Or(repo.FullName, link.owner+"/"+link.repo),
+		PublishDate: dateOnly(repo.PushedAt),
+		Excerpts:    excerpts,
+	}, nil
+}
+
+// resolveIssue excerpts the issue's title, state, body and first comments.
+func (g *githubLinkResolver) resolveIssue(ctx context.Context, link githubLink) (parallelExtractResult, error) {
+	issuePath := fmt.Sprintf("%s/issues/%d", githubRepoPath(link), link.number)
+	var issue githubIssue
+	if err := g.getJSON(ctx, issuePath, nil, &issue); err != nil {
+		return parallelExtractResult{}, err
+	}
+
+	excerpts := []string{fmt.Sprintf("Issue #%d (%s) opened by %s on %s: %s",
+		link.number, issue.State, issue.User.Login, dateOnly(issue.CreatedAt), issue.Title)}
+	excerpts = append(excerpts, chunkLines(issue.Body, maxParallelExtractExcerptRuneLen)...)
+
+	if issue.Comments > 0 {
+		comments, err := g.comments(ctx, issuePath)
+		if err != nil {
+			return parallelExtractResult{}, err
+		}
+		excerpts = append(excerpts, comments...)
+	}
+
+	return parallelExtractResult{
+		Title:       fmt.Sprintf("%s/%s#%d: %s", link.owner, link.repo, link.number, issue.Title),
+		PublishDate: dateOnly(issue.CreatedAt),
+		Excerpts:    excerpts,
+	}, nil
+}
+
+// resolvePull excerpts the pull request's description and diff stats: the
+// totals and the per-file additions and deletions.
+func (g *githubLinkResolver) resolvePull(ctx context.Context, link githubLink) (parallelExtractResult, error) {
+	pullPath := fmt.Sprintf("%s/pulls/%d", githubRepoPath(link), link.number)
+	var pull githubPull
+	if err := g.getJSON(ctx, pullPath, nil, &pull); err != nil {
+		return parallelExtractResult{}, err
+	}
+
+	state := pull.State
+	switch {
+	case pull.Merged:
+		state = "merged"
+	case pull.Draft && state == "open":
+		state = "draft"
+	}
+	excerpts := []string{fmt.Sprintf(
+		"Pull request #%d (%s) by %s, opened %s: %s. Merges %s into %s. %d commits, %d files changed, +%d -%d lines.",
+		link.number, state, pull.User.Login, dateOnly(pull.CreatedAt), pull.Title,
+		pull.Head.Ref, pull.Base.Ref, pull.Commits, pull.ChangedFiles, pull.Additions, pull.Deletions,
+	)}
+
+	var files []githubPullFile
+	query := url.Values{"per_page": {strconv.Itoa(maxGitHubPRFiles)}}
+	if err := g.getJSON(ctx, pullPath+"/files", query, &files); err != nil {
+		return parallelExtractResult{}, fmt.Errorf("fetch pull request files: %w", err)
+	}
+	if len(files) > 0 {
+		lines := make([]string, 0, len(files)+1)
+		lines = append(lines, "Files changed:")
+		for _, f := range files {
+			lines = append(lines, fmt.Sprintf("%s (%s, +%d -%d)", f.Filename, f.Status, f.Additions, f.Deletions))
+		}
+		if pull.ChangedFiles > len(files) {
+			lines = append(lines, fmt.Sprintf("... and %d more files", pull.ChangedFiles-len(files)))
+		}
+		excerpts = append(excerpts, strings.Join(lines, "\n"))
+	}
+
+	excerpts = append(excerpts, chunkLines(pull.Body, maxParallelExtractExcerptRuneLen)...)
+
+	return parallelExtractResult{
+		Title:       fmt.Sprintf("%s/%s#%d: %s", link.owner, link.repo, link.number, pull.Title),
+		PublishDate: dateOnly(pull.CreatedAt),
+		Excerpts:    excerpts,
+	}, nil
+}
+
+// resolveBlob excerpts a file, or just the linked line range, with line
+// numbers so answers can refer to them.
+func (g *githubLinkResolver) resolveBlob(ctx context.Context, link githubLink) (parallelExtractResult, error) {
+	contentPath := githubRepoPath(link) + "/contents/" + escapeGitHubPath(link.path)
+	content, err := g.getRaw(ctx, contentPath, url.Values{"ref": {link.ref}})
+	if err != nil {
+		return parallelExtractResult{}, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), "\n")
+	first, last := 1, len(lines)
+	if link.startLine > 0 {
+		first = min(link.startLine, len(lines))
+		last = min(link.endLine, len(lines))
+	}
+
+	numbered := make([]string, 0, last-first+1)
+	for n := first; n <= last; n++ {
+		numbered = append(numbered, fmt.Sprintf("%d: %s", n, lines[n-1]))
+	}
+
+	header := fmt.Sprintf("File %s at %s in %s/%s", link.path, link.ref, link.owner, link.repo)
+	if link.startLine > 0 {
+		header += fmt.Sprintf(", lines %d-%d", first, last)
+	}
+	excerpts := []string{header + ":"}
+	excerpts = append(excerpts, chunkLines(strings.Join(numbered, "\n"), maxParallelExtractExcerptRuneLen)...)
+
+	return parallelExtractResult{
+		Title:    fmt.Sprintf("%s/%s: %s", link.owner, link.repo, link.path),
+		Excerpts: excerpts,
+	}, nil
+}
+
+// comments returns the issue's first comments, one excerpt each.
+func (g *githubLinkResolver) comments(ctx context.Context, issuePath string) ([]string, error) {
+	var comments []githubComment
+	query := url.Values{"per_page": {strconv.Itoa(maxGitHubComments)}}
+	if err := g.getJSON(ctx, issuePath+"/comments", query, &comments); err != nil {
+		return nil, fmt.Errorf("fetch comments: %w", err)
+	}
+	excerpts := make([]string, 0, len(comments))
+	for _, c := range comments {
+		body := strings.TrimSpace(c.Body)
+		if body == "" {
+			continue
+		}
+		if runeLen(body) > maxGitHubCommentRunes {
+			body = truncateRunes(body, maxGitHubCommentRunes) + "…"
+		}
+		excerpts = append(excerpts, fmt.Sprintf("Comment by %s: %s", c.User.Login, body))
+	}
+	return excerpts, nil
+}
+
+func (g *githubLinkResolver) getJSON(ctx context.Context, path string, query url.Values, out any) error {
+	body, err := g.get(ctx, path, query, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode github response: %w", err)
+	}
+	return nil
+}
+
+// getRaw fetches file content (README, blobs) undecoded via the raw media
+// type instead of base64 JSON.
+func (g *githubLinkResolver) getRaw(ctx context.Context, path string, query url.Values) (string, error) {
+	body, err := g.get(ctx, path, query, "application/vnd.github.raw")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (g *githubLinkResolver) get(ctx context.Context, path string, query url.Values, accept string) ([]byte, error) {
+	endpoint := strings.TrimRight(cmp.Or(g.baseURL, defaultGitHubAPIBaseURL), "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create github request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+	req.Header.Set("User-Agent", localExtractUserAgent)
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github request failed: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errGitHubNotFound
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("github rate limit or access denied (status %d)", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("github returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGitHubRawBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read github response: %w", err)
+	}
+	return body, nil
+}
+
+func githubRepoPath(link githubLink) string {
+	return "/repos/" + url.PathEscape(link.owner) + "/" + url.PathEscape(link.repo)
+}
+
+// escapeGitHubPath escapes each segment of a repository file path.
+func escapeGitHubPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// chunkLines splits text into excerpts of at most maxRunes, breaking
+// between lines so code and Markdown keep their layout. A single line
+// longer than maxRunes becomes its own (later truncated) excerpt.
+func chunkLines(text string, maxRunes int) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	var sb strings.Builder
+	size := 0
+	for line := range strings.SplitSeq(text, "\n") {
+		n := runeLen(line) + 1
+		if size > 0 && size+n > maxRunes {
+			chunks = append(chunks, strings.TrimRight(sb.String(), "\n"))
+			sb.Reset()
+			size = 0
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		size += n
+	}
+	if sb.Len() > 0 {
+		chunks = append(chunks, strings.TrimRight(sb.String(), "\n"))
+	}
+	return chunks
+}
+
+// dateOnly trims an ISO 8601 timestamp to its date.
+func dateOnly(timestamp string) string {
+	if len(timestamp) > len(dateFormatPattern) {
+		return timestamp[:len(dateFormatPattern)]
+	}
+	return timestamp
+}
+
+// loadGitHubLinksEnabled reads GITHUB_LINKS_ENABLED, defaulting to enabled.
+func loadGitHubLinksEnabled() bool {
+	raw := getenvTrim("GITHUB_LINKS_ENABLED")
+	if raw == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Warn().Str("value", raw).Msg("Invalid GITHUB_LINKS_ENABLED value; defaulting to enabled")
+		return true
+	}
+	return enabled
+}
@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestGitHubResolver serves canned GitHub API responses keyed by path.
+func newTestGitHubResolver(t *testing.T, responses map[string]string) *githubLinkResolver {
+	t.Helper()
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer gh-token" {
+			t.Errorf("expected bearer token, got %q", got)
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	server.Start()
+	return &githubLinkResolver{baseURL: server.URL, token: "gh-token", timeout: 5 * time.Second}
+}
+
+func TestGitHubLinkResolverPullRequest(t *testing.T) {
+	t.Parallel()
+
+	resolver := newTestGitHubResolver(t, map[string]string{
+		"/repos/acme/widget/pulls/7": `{"title": "Add retries", "body": "Retries failed uploads.\n\nCloses #3.",
+			"state": "closed", "merged": true, "user": {"login": "alice"}, "created_at": "2026-03-01T10:00:00Z",
+			"commits": 2, "additions": 40, "deletions": 5, "changed_files": 2,
+			"base": {"ref": "main"}, "head": {"ref": "retries"}}`,
+		"/repos/acme/widget/pulls/7/files": `[{"filename": "upload.go", "status": "modified", "additions": 35, "deletions": 5},
+			{"filename": "upload_test.go", "status": "added", "additions": 5, "deletions": 0}]`,
+	})
+
+	results, failed := resolver.resolve(context.Background(), []string{"https://github.com/acme/widget/pull/7"})
+	if len(results) != 1 || len(failed) != 0 {
+		t.Fatalf("resolve() = %+v, failed %v", results, failed)
+	}
+	got := results[0]
+	if got.Title != "acme/widget#7: Add retries" || got.PublishDate != "2026-03-01" || got.URL != "https://github.com/acme/widget/pull/7" {
+		t.Errorf("unexpected metadata %+v", got)
+	}
+	if len(got.Excerpts) != 3 {
+		t.Fatalf("expected header, files and body excerpts, got %q", got.Excerpts)
+	}
+	if !strings.Contains(got.Excerpts[0], "(merged) by alice") || !strings.Contains(got.Excerpts[0], "+40 -5") {
+		t.Errorf("unexpected header %q", got.Excerpts[0])
+	}
+	if !strings.Contains(got.Excerpts[1], "upload_test.go (added, +5 -0)") {
+		t.Errorf("unexpected files excerpt %q", got.Excerpts[1])
+	}
+	if got.Excerpts[2] != "Retries failed uploads.\n\nCloses #3." {
+		t.Errorf("unexpected body excerpt %q", got.Excerpts[2])
+	}
+}
+
+func TestGitHubLinkResolverIssueWithComments(t *testing.T) {
+	t.Parallel()
+
+	resolver := newTestGitHubResolver(t, map[string]string{
+		"/repos/acme/widget/issues/3": `{"title": "Uploads fail", "body": "Large uploads time out.", "state": "open",
+			"user": {"login": "bob"}, "created_at": "2026-02-01T00:00:00Z", "comments": 2}`,
+		"/repos/acme/widget/issues/3/comments": `[{"body": "Same here", "user": {"login": "carol"}}, {"body": "  ", "user": {"login": "dan"}}]`,
+	})
+
+	results, _ := resolver.resolve(context.Background(), []string{"https://github.com/acme/widget/issues/3"})
+	if len(results) != 1 {
+		t.Fatal("expected the issue to resolve")
+	}
+	excerpts := results[0].Excerpts
+	if len(excerpts) != 3 || excerpts[0] != "Issue #3 (open) opened by bob on 2026-02-01: Uploads fail" || excerpts[2] != "Comment by carol: Same here" {
+		t.Fatalf("unexpected excerpts %q", excerpts)
+	}
+}
+
+func TestGitHubLinkResolverRepoAndBlob(t *testing.T) {
+	t.Parallel()
+
+	resolver := newTestGitHubResolver(t, map[string]string{
+		"/repos/acme/widget":                      `{"full_name": "acme/widget", "description": "Widgets", "language": "Go", "stargazers_count": 12, "default_branch": "main"}`,
+		"/repos/acme/widget/readme":               "# Widget\n\nMakes widgets.",
+		"/repos/acme/widget/contents/cmd/main.go": "package main\n\nfunc main() {\n\trun()\n}\n",
+	})
+
+	results, failed := resolver.resolve(context.Background(), []string{
+		"https://github.com/acme/widget",
+		"https://github.com/acme/widget/blob/main/cmd/main.go#L3-L4",
+		"https://github.com/acme/missing",
+	})
+	if len(results) != 2 || len(failed) != 1 || failed[0] != "https://github.com/acme/missing" {
+		t.Fatalf("resolve() = %+v, failed %v", results, failed)
+	}
+
+	repo := results[0]
+	if repo.Excerpts[0] != "Repository acme/widget: Widgets (written mainly in Go, 12 stars, default branch main)." || repo.Excerpts[1] != "# Widget\n\nMakes widgets." {
+		t.Errorf("unexpected repo excerpts %q", repo.Excerpts)
+	}
+
+	blob := results[1]
+	if blob.Title != "acme/widget: cmd/main.go" || len(blob.Excerpts) != 2 {
+		t.Fatalf("unexpected blob result %+v", blob)
+	}
+	if blob.Excerpts[0] != "File cmd/main.go at main in acme/widget, lines 3-4:" || blob.Excerpts[1] != "3: func main() {\n4: \trun()" {
+		t.Errorf("unexpected blob excerpts %q", blob.Excerpts)
+	}
+}
+
+func TestGitHubLinkResolverDisabled(t *testing.T) {
+	var resolver *githubLinkResolver
+	urls := []string{"https://github.com/acme/widget"}
+	if results, failed := resolver.resolve(context.Background(), urls); results != nil || len(failed) != 1 {
+		t.Fatalf("expected a nil resolver to hand every link back, got %+v %v", results, failed)
+	}
+
+	t.Setenv("EXTRACT_ENABLED", "")
+	t.Setenv("GITHUB_LINKS_ENABLED", "false")
+	if newGitHubLinkResolver() != nil {
+		t.Fatal("expected GITHUB_LINKS_ENABLED=false to disable GitHub lookups")
+	}
+}
+
+func TestChunkLines(t *testing.T) {
+	t.Parallel()
+
+	if chunkLines("  \n ", 10) != nil {
+		t.Fatal("expected no chunks for blank text")
+	}
+	got := chunkLines("aaaa\nbbbb\ncccc", 10)
+	if len(got) != 2 || got[0] != "aaaa\nbbbb" || got[1] != "cccc" {
+		t.Fatalf("chunkLines() = %q", got)
+	}
+}
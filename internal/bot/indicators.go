@@ -0,0 +1,345 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Technical indicators for `!s SYMBOL RANGE sma20 rsi ...`. Every series
+// has the same length as its input and holds NaN until the indicator has
+// enough bars to be defined, so it lines up index-for-index with the bars it
+// was computed from.
+
+const (
+	defaultMovingAveragePeriod = 20
+	defaultRSIPeriod           = 14
+	minIndicatorPeriod         = 2
+	maxIndicatorPeriod         = 200
+
+	macdFastPeriod   = 12
+	macdSlowPeriod   = 26
+	macdSignalPeriod = 9
+
+	bollingerPeriod     = 20
+	bollingerDeviations = 2.0
+
+	// maxChartIndicators keeps one chart readable.
+	maxChartIndicators = 5
+)
+
+type indicatorKind int
+
+const (
+	indicatorSMA indicatorKind = iota
+	indicatorEMA
+	indicatorRSI
+	indicatorMACD
+	indicatorBollinger
+)
+
+var indicatorTokenRE = regexp.MustCompile(`^(sma|ema|rsi)([0-9]{1,3})?$|^(macd|bb|bollinger)$`)
+
+// indicatorSpec is one requested indicator. period is unused for MACD and
+// Bollinger Bands, which always use their textbook parameters.
+type indicatorSpec struct {
+	kind   indicatorKind
+	period int
+}
+
+// isIndicatorToken reports whether token is written like an indicator,
+// valid period or not.
+func isIndicatorToken(token string) bool {
+	return indicatorTokenRE.MatchString(strings.ToLower(token))
+}
+
+// parseIndicatorToken parses sma20, ema, rsi, macd, bb and friends.
+func parseIndicatorToken(token string) (indicatorSpec, error) {
+	m := indicatorTokenRE.FindStringSubmatch(strings.ToLower(token))
+	if m == nil {
+		return indicatorSpec{}, fmt.Errorf("unknown indicator %q", token)
+	}
+	switch m[3] {
+	case "macd":
+		return indicatorSpec{kind: indicatorMACD}, nil
+	case "bb", "bollinger":
+		return indicatorSpec{kind: indicatorBollinger, period: bollingerPeriod}, nil
+	}
+
+	spec := indicatorSpec{period: defaultMovingAveragePeriod}
+	switch m[1] {
+	case "sma":
+		spec.kind = indicatorSMA
+	case "ema":
+		spec.kind = indicatorEMA
+	case "rsi":
+		spec.kind, spec.period = indicatorRSI, defaultRSIPeriod
+	}
+	if m[2] != "" {
+		period, _ := strconv.Atoi(m[2])
+		if period < minIndicatorPeriod || period > maxIndicatorPeriod {
+			return indicatorSpec{}, fmt.Errorf("invalid indicator period in %q, use %d to %d", token, minIndicatorPeriod, maxIndicatorPeriod)
+		}
+		spec.period = period
+	}
+	return spec, nil
+}
+
+// name is the label used in legends and captions, e.g. SMA20 or MACD(12,26,9).
+func (s indicatorSpec) name() string {
+	switch s.kind {
+	case indicatorEMA:
+		return "EMA" + strconv.Itoa(s.period)
+	case indicatorRSI:
+		return "RSI" + strconv.Itoa(s.period)
+	case indicatorMACD:
+		return fmt.Sprintf("MACD(%d,%d,%d)", macdFastPeriod, macdSlowPeriod, macdSignalPeriod)
+	case indicatorBollinger:
+		return fmt.Sprintf("BB(%d,%g)", bollingerPeriod, bollingerDeviations)
+	default:
+		return "SMA" + strconv.Itoa(s.period)
+	}
+}
+
+// lookback is how many bars the indicator needs before its values settle.
+// EMA-based indicators are defined earlier but keep converging, so they ask
+// for a few multiples of their period.
+func (s indicatorSpec) lookback() int {
+	switch s.kind {
+	case indicatorEMA, indicatorRSI:
+		return 3 * s.period
+	case indicatorMACD:
+		return 3*macdSlowPeriod + macdSignalPeriod
+	default:
+		return s.period
+	}
+}
+
+// indicatorPanel says where a series is drawn.
+type indicatorPanel int
+
+const (
+	panelPrice indicatorPanel = iota
+	panelRSI
+	panelMACD
+)
+
+// indicatorSeries is one drawable line. histogram marks the MACD histogram,
+// drawn as bars instead of a line; band marks the Bollinger lines, drawn in
+// one shared color.
+type indicatorSeries struct {
+	name      string
+	panel     indicatorPanel
+	histogram bool
+	band      bool
+	values    []float64
+}
+
+// computeIndicatorSeries computes every requested indicator over closes.
+func computeIndicatorSeries(specs []indicatorSpec, closes []float64) []indicatorSeries {
+	var out []indicatorSeries
+	for _, spec := range specs {
+		switch spec.kind {
+		case indicatorSMA:
+			out = append(out, indicatorSeries{name: spec.name(), values: simpleMovingAverage(closes, spec.period)})
+		case indicatorEMA:
+			out = append(out, indicatorSeries{name: spec.name(), values: exponentialMovingAverage(closes, spec.period)})
+		case indicatorRSI:
+			out = append(out, indicatorSeries{name: spec.name(), panel: panelRSI, values: relativeStrengthIndex(closes, spec.period)})
+		case indicatorMACD:
+			line, signal, hist := movingAverageConvergenceDivergence(closes, macdFastPeriod, macdSlowPeriod, macdSignalPeriod)
+			out = append(out,
+				indicatorSeries{name: "MACD", panel: panelMACD, values: line},
+				indicatorSeries{name: "Signal", panel: panelMACD, values: signal},
+				indicatorSeries{name: "Histogram", panel: panelMACD, histogram: true, values: hist},
+			)
+		case indicatorBollinger:
+			mid, upper, lower := bollingerBands(closes, bollingerPeriod, bollingerDeviations)
+			out = append(out,
+				indicatorSeries{name: "BB upper", band: true, values: upper},
+				indicatorSeries{name: "BB mid", band: true, values: mid},
+				indicatorSeries{name: "BB lower", band: true, values: lower},
+			)
+		}
+	}
+	return out
+}
+
+// formatIndicatorReadings renders the latest value of each indicator for the
+// caption, e.g. "SMA20 231.45 | RSI14 62.3". An indicator without enough
+// bars reads "n/a".
+func formatIndicatorReadings(specs []indicatorSpec, closes []float64) string {
+	if len(specs) == 0 {
+		return ""
+	}
+	readings := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		var value string
+		switch spec.kind {
+		case indicatorSMA:
+			value = formatIndicatorValue("%.2f", lastValue(simpleMovingAverage(closes, spec.period)))
+		case indicatorEMA:
+			value = formatIndicatorValue("%.2f", lastValue(exponentialMovingAverage(closes, spec.period)))
+		case indicatorRSI:
+			value = formatIndicatorValue("%.1f", lastValue(relativeStrengthIndex(closes, spec.period)))
+		case indicatorMACD:
+			line, signal, _ := movingAverageConvergenceDivergence(closes, macdFastPeriod, macdSlowPeriod, macdSignalPeriod)
+			value = formatIndicatorValue("%.2f", lastValue(line)) + " / signal " + formatIndicatorValue("%.2f", lastValue(signal))
+		case indicatorBollinger:
+			mid, upper, lower := bollingerBands(closes, bollingerPeriod, bollingerDeviations)
+			value = fmt.Sprintf("%s / %s / %s",
+				formatIndicatorValue("%.2f", lastValue(upper)),
+				formatIndicatorValue("%.2f", lastValue(mid)),
+				formatIndicatorValue("%.2f", lastValue(lower)),
+			)
+		}
+		readings = append(readings, spec.name()+" "+value)
+	}
+	return strings.Join(readings, " | ")
+}
+
+func formatIndicatorValue(format string, v float64) string {
+	if math.IsNaN(v) {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, v)
+}
+
+func lastValue(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	return values[len(values)-1]
+}
+
+func nanSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+// simpleMovingAverage is the mean of the last period values.
+func simpleMovingAverage(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period < 1 {
+		return out
+	}
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// exponentialMovingAverage uses the 2/(period+1) smoothing factor, seeded
+// with the simple average of the first period values. NaN inputs (such as
+// the undefined head of another indicator) are skipped until the first
+// period defined values have been seen.
+func exponentialMovingAverage(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period < 1 {
+		return out
+	}
+	alpha := 2 / float64(period+1)
+	seen, sum, prev := 0, 0.0, math.NaN()
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		seen++
+		switch {
+		case seen < period:
+			sum += v
+			continue
+		case seen == period:
+			prev = (sum + v) / float64(period)
+		default:
+			prev = alpha*v + (1-alpha)*prev
+		}
+		out[i] = prev
+	}
+	return out
+}
+
+// relativeStrengthIndex is Wilder's RSI: average gains and losses start as
+// simple means over the first period changes and are then smoothed with
+// factor 1/period. A window without losses reads 100, one without any
+// movement 50.
+func relativeStrengthIndex(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period < 1 || len(values) <= period {
+		return out
+	}
+	avgGain, avgLoss := 0.0, 0.0
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		avgGain += max(change, 0)
+		avgLoss += max(-change, 0)
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	out[period] = rsiFromAverages(avgGain, avgLoss)
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		avgGain = (avgGain*float64(period-1) + max(change, 0)) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + max(-change, 0)) / float64(period)
+		out[i] = rsiFromAverages(avgGain, avgLoss)
+	}
+	return out
+}
+
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	switch {
+	case avgLoss == 0 && avgGain == 0:
+		return 50
+	case avgLoss == 0:
+		return 100
+	default:
+		return 100 - 100/(1+avgGain/avgLoss)
+	}
+}
+
+// movingAverageConvergenceDivergence returns the MACD line (fast EMA minus
+// slow EMA), its signal EMA and the histogram (line minus signal).
+func movingAverageConvergenceDivergence(values []float64, fast, slow, signal int) (line, signalLine, histogram []float64) {
+	fastEMA := exponentialMovingAverage(values, fast)
+	slowEMA := exponentialMovingAverage(values, slow)
+	line = make([]float64, len(values))
+	for i := range values {
+		line[i] = fastEMA[i] - slowEMA[i]
+	}
+	signalLine = exponentialMovingAverage(line, signal)
+	histogram = make([]float64, len(values))
+	for i := range values {
+		histogram[i] = line[i] - signalLine[i]
+	}
+	return line, signalLine, histogram
+}
+
+// bollingerBands returns the period SMA and the bands deviations population
+// standard deviations above and below it.
+func bollingerBands(values []float64, period int, deviations float64) (mid, upper, lower []float64) {
+	mid = simpleMovingAverage(values, period)
+	upper = nanSeries(len(values))
+	lower = nanSeries(len(values))
+	for i := period - 1; i < len(values) && period > 0; i++ {
+		variance := 0.0
+		for _, v := range values[i-period+1 : i+1] {
+			variance += (v - mid[i]) * (v - mid[i])
+		}
+		stddev := math.Sqrt(variance / float64(period))
+		upper[i] = mid[i] + deviations*stddev
+		lower[i] = mid[i] - deviations*stddev
+	}
+	return mid, upper, lower
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"math"
+	"testing"
+
+	"hegel.dev/go/hegel"
+)
+
+// drawIndicatorPrices draws a price series in cents so every value is a
+// realistic, finite, positive price.
+func drawIndicatorPrices(ht *hegel.T) []float64 {
+	cents := hegel.Draw(ht, hegel.Lists(hegel.Integers[int](1, 10_000_000)).MaxSize(120))
+	prices := make([]float64, len(cents))
+	for i, c := range cents {
+		prices[i] = float64(c) / 100
+	}
+	return prices
+}
+
+// indicatorsAgree compares two indicator values: both undefined, or equal
+// within a relative tolerance that absorbs the running-sum rounding.
+func indicatorsAgree(got, want float64) bool {
+	if math.IsNaN(got) || math.IsNaN(want) {
+		return math.IsNaN(got) && math.IsNaN(want)
+	}
+	return math.Abs(got-want) <= 1e-9*max(1, math.Abs(want))
+}
+
+// referenceSMA recomputes every window from scratch.
+func referenceSMA(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	for i := period - 1; i < len(values); i++ {
+		sum := 0.0
+		for _, v := range values[i-period+1 : i+1] {
+			sum += v
+		}
+		out[i] = sum / float64(period)
+	}
+	return out
+}
+
+// referenceEMA is the textbook definition on a series without gaps.
+func referenceEMA(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if len(values) < period {
+		return out
+	}
+	out[period-1] = referenceSMA(values, period)[period-1]
+	k := 2 / float64(period+1)
+	for i := period; i < len(values); i++ {
+		out[i] = values[i]*k + out[i-1]*(1-k)
+	}
+	return out
+}
+
+// referenceRSI builds Wilder's averages from explicit gain/loss slices.
+func referenceRSI(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if len(values) <= period {
+		return out
+	}
+	gains := make([]float64, len(values))
+	losses := make([]float64, len(values))
+	for i := 1; i < len(values); i++ {
+		if d := values[i] - values[i-1]; d > 0 {
+			gains[i] = d
+		} else {
+			losses[i] = -d
+		}
+	}
+	avgGain := referenceSMA(gains[1:period+1], period)[period-1]
+	avgLoss := referenceSMA(losses[1:period+1], period)[period-1]
+	for i := period; i < len(values); i++ {
+		if i > period {
+			avgGain = (avgGain*float64(period-1) + gains[i]) / float64(period)
+			avgLoss = (avgLoss*float64(period-1) + losses[i]) / float64(period)
+		}
+		switch {
+		case avgGain == 0 && avgLoss == 0:
+			out[i] = 50
+		case avgLoss == 0:
+			out[i] = 100
+		default:
+			out[i] = 100 * avgGain / (avgGain + avgLoss)
+		}
+	}
+	return out
+}
+
+// TestMovingAverages_MatchReference checks the running-sum SMA and the
+// seeded EMA against from-scratch reference implementations.
+func TestMovingAverages_MatchReference(t *testing.T) {
+	hegel.Test(t, func(ht *hegel.T) {
+		prices := drawIndicatorPrices(ht)
+		period := hegel.Draw(ht, hegel.Integers[int](minIndicatorPeriod, 40))
+
+		sma, wantSMA := simpleMovingAverage(prices, period), referenceSMA(prices, period)
+		ema, wantEMA := exponentialMovingAverage(prices, period), referenceEMA(prices, period)
+		for i := range prices {
+			if !indicatorsAgree(sma[i], wantSMA[i]) {
+				ht.Fatalf("sma(period=%d)[%d] = %v, want %v", period, i, sma[i], wantSMA[i])
+			}
+			if !indicatorsAgree(ema[i], wantEMA[i]) {
+				ht.Fatalf("ema(period=%d)[%d] = %v, want %v", period, i, ema[i], wantEMA[i])
+			}
+			// Property: both averages stay inside the range of their inputs.
+			lo, hi := minMax(prices[:i+1])
+			for _, v := range []float64{sma[i], ema[i]} {
+				if !math.IsNaN(v) && (v < lo-1e-9 || v > hi+1e-9) {
+					ht.Fatalf("average %v outside input range [%v, %v]", v, lo, hi)
+				}
+			}
+		}
+	}, hegel.WithTestCases(200))
+}
+
+// TestRelativeStrengthIndex_MatchesReference checks Wilder's RSI against a
+// reference built from explicit gain/loss series, and that it stays within
+// [0, 100].
+func TestRelativeStrengthIndex_MatchesReference(t *testing.T) {
+	hegel.Test(t, func(ht *hegel.T) {
+		prices := drawIndicatorPrices(ht)
+		period := hegel.Draw(ht, hegel.Integers[int](minIndicatorPeriod, 30))
+
+		got, want := relativeStrengthIndex(prices, period), referenceRSI(prices, period)
+		for i := range prices {
+			if math.Abs(got[i]-want[i]) > 1e-6 && !(math.IsNaN(got[i]) && math.IsNaN(want[i])) {
+				ht.Fatalf("rsi(period=%d)[%d] = %v, want %v", period, i, got[i], want[i])
+			}
+			if !math.IsNaN(got[i]) && (got[i] < 0 || got[i] > 100) {
+				ht.Fatalf("rsi[%d] = %v outside [0, 100]", i, got[i])
+			}
+		}
+	}, hegel.WithTestCases(200))
+}
+
+// TestBollingerBands_SymmetricAroundSMA checks the bands are the SMA plus
+// and minus k population standard deviations.
+func TestBollingerBands_SymmetricAroundSMA(t *testing.T) {
+	hegel.Test(t, func(ht *hegel.T) {
+		prices := drawIndicatorPrices(ht)
+		period := hegel.Draw(ht, hegel.Integers[int](minIndicatorPeriod, 40))
+
+		mid, upper, lower := bollingerBands(prices, period, bollingerDeviations)
+		wantMid := referenceSMA(prices, period)
+		for i := range prices {
+			if !indicatorsAgree(mid[i], wantMid[i]) {
+				ht.Fatalf("mid[%d] = %v, want %v", i, mid[i], wantMid[i])
+			}
+			if math.IsNaN(mid[i]) {
+				if !math.IsNaN(upper[i]) || !math.IsNaN(lower[i]) {
+					ht.Fatalf("bands defined at %d before the SMA", i)
+				}
+				continue
+			}
+			variance := 0.0
+			for _, v := range prices[i-period+1 : i+1] {
+				variance += (v - wantMid[i]) * (v - wantMid[i])
+			}
+			width := bollingerDeviations * math.Sqrt(variance/float64(period))
+			if !indicatorsAgree(upper[i], wantMid[i]+width) || !indicatorsAgree(lower[i], wantMid[i]-width) {
+				ht.Fatalf("bands[%d] = (%v, %v), want %v ± %v", i, lower[i], upper[i], wantMid[i], width)
+			}
+		}
+	}, hegel.WithTestCases(200))
+}
+
+// TestMACD_ComposedOfEMAs checks the MACD line is the fast EMA minus the
+// slow EMA, the signal is the EMA of the defined part of the line, and the
+// histogram is their difference.
+func TestMACD_ComposedOfEMAs(t *testing.T) {
+	hegel.Test(t, func(ht *hegel.T) {
+		prices := drawIndicatorPrices(ht)
+
+		line, signal, hist := movingAverageConvergenceDivergence(prices, macdFastPeriod, macdSlowPeriod, macdSignalPeriod)
+		fast, slow := referenceEMA(prices, macdFastPeriod), referenceEMA(prices, macdSlowPeriod)
+		var wantSignal []float64
+		if len(prices) >= macdSlowPeriod {
+			wantSignal = append(nanSeries(macdSlowPeriod-1), referenceEMA(line[macdSlowPeriod-1:], macdSignalPeriod)...)
+		} else {
+			wantSignal = nanSeries(len(prices))
+		}
+		for i := range prices {
+			if !indicatorsAgree(line[i], fast[i]-slow[i]) {
+				ht.Fatalf("line[%d] = %v, want %v", i, line[i], fast[i]-slow[i])
+			}
+			if !indicatorsAgree(signal[i], wantSignal[i]) {
+				ht.Fatalf("signal[%d] = %v, want %v", i, signal[i], wantSignal[i])
+			}
+			if !indicatorsAgree(hist[i], line[i]-signal[i]) {
+				ht.Fatalf("hist[%d] = %v, want %v", i, hist[i], line[i]-signal[i])
+			}
+		}
+	}, hegel.WithTestCases(200))
+}
+
+func minMax(values []float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return lo, hi
+}
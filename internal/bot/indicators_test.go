@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"math"
+	"testing"
+)
+
+// wilderCloses is the closing-price series from Wilder's RSI worked example
+// as reproduced by StockCharts. The published RSI values were computed from
+// unrounded prices, so results agree to within 0.1.
+var wilderCloses = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+	46.21, 46.25, 45.71, 46.46, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+	43.42, 42.66, 43.13,
+}
+
+func assertClose(t *testing.T, what string, got, want, tolerance float64) {
+	t.Helper()
+	if math.IsNaN(got) || math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %v, want %v (±%v)", what, got, want, tolerance)
+	}
+}
+
+func TestRelativeStrengthIndexWilderExample(t *testing.T) {
+	t.Parallel()
+
+	got := relativeStrengthIndex(wilderCloses, 14)
+	for i := range 14 {
+		if !math.IsNaN(got[i]) {
+			t.Fatalf("rsi[%d] = %v, want NaN before the first full window", i, got[i])
+		}
+	}
+	want := []float64{70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38, 54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77}
+	for i, w := range want {
+		assertClose(t, "rsi", got[14+i], w, 0.1)
+	}
+}
+
+func TestRelativeStrengthIndexFlatAndRising(t *testing.T) {
+	t.Parallel()
+
+	assertClose(t, "flat rsi", lastValue(relativeStrengthIndex([]float64{5, 5, 5, 5}, 3)), 50, 0)
+	assertClose(t, "rising rsi", lastValue(relativeStrengthIndex([]float64{1, 2, 3, 4}, 3)), 100, 0)
+	if got := relativeStrengthIndex([]float64{1, 2, 3}, 3); !math.IsNaN(got[2]) {
+		t.Fatalf("expected NaN with only period bars, got %v", got)
+	}
+}
+
+func TestSimpleAndExponentialMovingAverage(t *testing.T) {
+	t.Parallel()
+
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	sma := simpleMovingAverage(values, 3)
+	if !math.IsNaN(sma[1]) {
+		t.Fatalf("sma[1] = %v, want NaN", sma[1])
+	}
+	assertClose(t, "sma[2]", sma[2], 2, 1e-12)
+	assertClose(t, "sma[9]", sma[9], 9, 1e-12)
+
+	// Seeded with SMA(1,2,3)=2 and alpha 0.5, a linear series lags by one.
+	ema := exponentialMovingAverage(values, 3)
+	assertClose(t, "ema[2]", ema[2], 2, 1e-12)
+	assertClose(t, "ema[3]", ema[3], 3, 1e-12)
+	assertClose(t, "ema[9]", ema[9], 9, 1e-12)
+
+	// NaN heads are skipped, so an EMA of an indicator starts once it has
+	// period defined values.
+	ema = exponentialMovingAverage([]float64{math.NaN(), math.NaN(), 4, 6}, 2)
+	if !math.IsNaN(ema[2]) {
+		t.Fatalf("ema[2] = %v, want NaN", ema[2])
+	}
+	assertClose(t, "ema over NaN head", ema[3], 5, 1e-12)
+}
+
+func TestBollingerBands(t *testing.T) {
+	t.Parallel()
+
+	mid, upper, lower := bollingerBands([]float64{1, 2, 3, 4, 5}, 5, 2)
+	assertClose(t, "mid", mid[4], 3, 1e-12)
+	assertClose(t, "upper", upper[4], 3+2*math.Sqrt2, 1e-12)
+	assertClose(t, "lower", lower[4], 3-2*math.Sqrt2, 1e-12)
+	if !math.IsNaN(upper[3]) {
+		t.Fatalf("upper[3] = %v, want NaN", upper[3])
+	}
+}
+
+func TestMovingAverageConvergenceDivergence(t *testing.T) {
+	t.Parallel()
+
+	values := make([]float64, 60)
+	for i := range values {
+		values[i] = 100 + 10*math.Sin(float64(i)/5)
+	}
+	line, signal, hist := movingAverageConvergenceDivergence(values, 12, 26, 9)
+	if !math.IsNaN(line[24]) || math.IsNaN(line[25]) {
+		t.Fatalf("MACD line should start at index 25, got %v %v", line[24], line[25])
+	}
+	if !math.IsNaN(signal[32]) || math.IsNaN(signal[33]) {
+		t.Fatalf("signal should start at index 33, got %v %v", signal[32], signal[33])
+	}
+	fast, slow := exponentialMovingAverage(values, 12), exponentialMovingAverage(values, 26)
+	for i := 33; i < len(values); i++ {
+		assertClose(t, "line", line[i], fast[i]-slow[i], 1e-12)
+		assertClose(t, "histogram", hist[i], line[i]-signal[i], 1e-12)
+	}
+}
+
+func TestParseIndicatorToken(t *testing.T) {
+	t.Parallel()
+
+	for token, want := range map[string]string{
+		"sma":       "SMA20",
+		"SMA50":     "SMA50",
+		"ema9":      "EMA9",
+		"rsi":       "RSI14",
+		"rsi7":      "RSI7",
+		"macd":      "MACD(12,26,9)",
+		"bb":        "BB(20,2)",
+		"Bollinger": "BB(20,2)",
+	} {
+		spec, err := parseIndicatorToken(token)
+		if err != nil || spec.name() != want {
+			t.Errorf("parseIndicatorToken(%q) = %q, %v; want %q", token, spec.name(), err, want)
+		}
+	}
+	for _, token := range []string{"sma1", "rsi201", "macd9", "vwap"} {
+		if _, err := parseIndicatorToken(token); err == nil {
+			t.Errorf("parseIndicatorToken(%q) should fail", token)
+		}
+	}
+}
+
+func TestFormatIndicatorReadings(t *testing.T) {
+	t.Parallel()
+
+	specs := []indicatorSpec{{kind: indicatorSMA, period: 3}, {kind: indicatorSMA, period: 50}, {kind: indicatorRSI, period: 3}}
+	got := formatIndicatorReadings(specs, []float64{1, 2, 3, 4, 5})
+	if want := "SMA3 4.00 | SMA50 n/a | RSI3 100.0"; got != want {
+		t.Fatalf("formatIndicatorReadings = %q, want %q", got, want)
+	}
+	if got := formatIndicatorReadings(nil, []float64{1}); got != "" {
+		t.Fatalf("expected no readings without indicators, got %q", got)
+	}
+}
@@ -0,0 +1,321 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	// inlineQueryCacheTTL bounds how long an inline answer is reused for the
+	// same query, both in-process and in Telegram's own result cache
+	// (cache_time). Quotes go stale quickly, so keep it short.
+	inlineQueryCacheTTL        = 60 * time.Second
+	inlineQueryCacheMaxEntries = 200
+
+	// inlineAskTimeout caps the Gemini call behind "@bot ask ...". Telegram
+	// discards answers to inline queries that arrive too late, so waiting the
+	// full GEMINI_TIMEOUT_SECONDS would only burn tokens for nothing.
+	inlineAskTimeout = 10 * time.Second
+
+	maxInlineTitleRunes       = 64
+	maxInlineDescriptionRunes = 120
+
+	inlineUsageText = "Inline usage: $AAPL for a stock quote, lc for the daily LeetCode question, or ask <question>."
+)
+
+type inlineQueryKind int
+
+const (
+	inlineQueryUnknown inlineQueryKind = iota
+	inlineQueryStock
+	inlineQueryLeetCode
+	inlineQueryAsk
+)
+
+// inlineQueryRequest is a parsed inline query. arg is the upper-cased symbol
+// for stock queries and the trimmed question for ask queries.
+type inlineQueryRequest struct {
+	kind inlineQueryKind
+	arg  string
+}
+
+// cacheKey identifies equivalent queries so "$aapl" and "$AAPL" share one
+// cached answer.
+func (r inlineQueryRequest) cacheKey() string {
+	switch r.kind {
+	case inlineQueryStock:
+		return "stock:" + r.arg
+	case inlineQueryLeetCode:
+		return "lc"
+	case inlineQueryAsk:
+		return "ask:" + r.arg
+	case inlineQueryUnknown:
+		return ""
+	default:
+		return ""
+	}
+}
+
+// inlineAnswer is the single article returned for an inline query.
+type inlineAnswer struct {
+	id          string
+	title       string
+	description string
+	text        string
+}
+
+// inlineCache holds inline answers keyed by inlineQueryRequest.cacheKey.
+// Telegram fires an inline query on every keystroke pause, so the same query
+// commonly arrives several times in a row from one or more users.
+var inlineCache = newTTLCache[inlineAnswer]("inline_query", inlineQueryCacheTTL, inlineQueryCacheMaxEntries)
+
+// parseInlineQuery recognizes "$SYMBOL", "lc", and "ask <question>". Anything
+// else is inlineQueryUnknown and gets the usage hint.
+func parseInlineQuery(query string) inlineQueryRequest {
+	trimmed := strings.TrimSpace(query)
+	if trimmed == "" {
+		return inlineQueryRequest{}
+	}
+	lower := strings.ToLower(trimmed)
+
+	if lower == "lc" {
+		return inlineQueryRequest{kind: inlineQueryLeetCode}
+	}
+
+	if strings.HasPrefix(trimmed, "$") {
+		symbol := strings.ToUpper(trimmed[1:])
+		if symbolRegex.MatchString(symbol) {
+			return inlineQueryRequest{kind: inlineQueryStock, arg: symbol}
+		}
+		return inlineQueryRequest{}
+	}
+
+	if strings.HasPrefix(lower, "ask ") {
+		question := strings.TrimSpace(trimmed[len("ask "):])
+		if question != "" {
+			return inlineQueryRequest{kind: inlineQueryAsk, arg: question}
+		}
+	}
+
+	return inlineQueryRequest{}
+}
+
+func shouldHandleInlineQuery(update *models.Update) bool {
+	return update != nil && update.InlineQuery != nil
+}
+
+// enforceInlineQueryAccess gates inline queries on ALLOWED_USERNAMES. Inline
+// mode works in any chat, including ones the bot is not a member of, so the
+// group allowlist cannot apply; the user allowlist is the only gate.
+// Unauthorized queries are left unanswered.
+func enforceInlineQueryAccess(query *models.InlineQuery) bool {
+	username := ""
+	if query.From != nil {
+		username = query.From.Username
+	}
+	if isAllowedUsername(username) {
+		log.Info().
+			Str("username", strings.ToLower(username)).
+			Bool("allowed", true).
+			Msg("Inline query activity")
+		return true
+	}
+
+	log.Info().
+		Str("username", strings.ToLower(username)).
+		Bool("allowed", false).
+		Msg("Ignoring inline query from unauthorized user")
+	return false
+}
+
+func inlineQueryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	query := update.InlineQuery
+	req := parseInlineQuery(query.Query)
+	if req.kind == inlineQueryUnknown {
+		answerInlineQuery(ctx, b, query.ID, inlineTextAnswer("usage", "Usage", inlineUsageText))
+		return
+	}
+
+	key := req.cacheKey()
+	if cached, ok := inlineCache.get(ctx, key); ok {
+		appotel.RecordOutcome(ctx, "success")
+		answerInlineQuery(ctx, b, query.ID, cached)
+		return
+	}
+
+	answer, outcome := resolveInlineAnswer(ctx, query, req)
+	if outcome == "success" {
+		inlineCache.set(key, answer)
+	}
+	appotel.RecordOutcome(ctx, outcome)
+	answerInlineQuery(ctx, b, query.ID, answer)
+}
+
+// resolveInlineAnswer produces the article for a parsed query and the
+// outcome to record. Failures are still answered with an explanatory article
+// so the user sees why nothing useful came back.
+func resolveInlineAnswer(ctx context.Context, query *models.InlineQuery, req inlineQueryRequest) (inlineAnswer, string) {
+	switch req.kind {
+	case inlineQueryStock:
+		return resolveInlineStock(ctx, req.arg)
+	case inlineQueryLeetCode:
+		return resolveInlineLeetCode(ctx)
+	case inlineQueryAsk:
+		return resolveInlineAsk(ctx, query, req.arg)
+	case inlineQueryUnknown:
+		return inlineTextAnswer("usage", "Usage", inlineUsageText), appotel.OutcomeUnknown
+	default:
+		return inlineTextAnswer("usage", "Usage", inlineUsageText), appotel.OutcomeUnknown
+	}
+}
+
+func resolveInlineStock(ctx context.Context, symbol string) (inlineAnswer, string) {
+	id := "stock-" + symbol
+	if msg, blocked := blockedStockResponse(symbol); blocked {
+		return inlineTextAnswer(id, symbol, msg), "blocked"
+	}
+
+	quote, err := fetchStockQuote(ctx, symbol)
+	if err != nil {
+		log.Error().Err(err).Str("symbol", symbol).Msg("Failed to fetch stock quote for inline query")
+		return inlineTextAnswer(id, symbol,
+			fmt.Sprintf("Failed to fetch stock quote for %s. Please try again later.", symbol)), "error"
+	}
+
+	profile, err := fetchCompanyProfile(ctx, symbol)
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to fetch company profile for inline query")
+	}
+
+	description := symbol
+	if profile != nil && profile.Name != "" {
+		description = profile.Name
+	}
+	return inlineAnswer{
+		id:          id,
+		title:       fmt.Sprintf("%s $%.2f (%+.2f%%)", symbol, quote.CurrentPrice, quote.PercentChange),
+		description: truncateRunes(description, maxInlineDescriptionRunes),
+		text:        formatStockMessage(symbol, quote, profile),
+	}, "success"
+}
+
+func resolveInlineLeetCode(ctx context.Context) (inlineAnswer, string) {
+	question, err := fetchDailyLeetCode(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch LeetCode daily question for inline query")
+		return inlineTextAnswer("lc", "LeetCode daily",
+			"Failed to fetch LeetCode daily question. Please try again later."), "error"
+	}
+	return inlineAnswer{
+		id:          "lc",
+		title:       truncateRunes("LeetCode daily: "+question.Title, maxInlineTitleRunes),
+		description: question.Difficulty,
+		text:        formatLeetCodeMessage(question),
+	}, "success"
+}
+
+func resolveInlineAsk(ctx context.Context, query *models.InlineQuery, question string) (inlineAnswer, string) {
+	title := truncateRunes("Ask: "+question, maxInlineTitleRunes)
+	if textExplainer == nil {
+		return inlineTextAnswer("ask", title, "Ask feature is not configured. Please set GEMINI_API_KEY."), "not_configured"
+	}
+
+	allowed, retryAfter := allowInlineAskRequest(query)
+	if !allowed {
+		recordRateLimited(ctx, "inline_ask")
+		log.Warn().
+			Int64("user_id", inlineQueryUserID(query)).
+			Dur("retry_after", retryAfter).
+			Msg("Inline ask request rate limited")
+		return inlineTextAnswer("ask", title, "Rate limit reached for ask requests. Please try again shortly."), "rate_limited"
+	}
+
+	askCtx, cancel := context.WithTimeout(ctx, inlineAskTimeout)
+	defer cancel()
+
+	answer, err := textExplainer.explainWithLanguage(askCtx, "", question, shouldRespondInBurmese(question))
+	if err != nil {
+		outcome := "error"
+		switch {
+		case errors.Is(err, ErrExplainBlocked):
+			outcome = "blocked"
+			log.Warn().Err(err).Msg("Inline ask question blocked by safety filters")
+		case errors.Is(err, ErrExplainTimeout):
+			outcome = "timeout"
+			log.Warn().Err(err).Msg("Inline ask question timed out")
+		default:
+			log.Error().Err(err).Msg("Failed to answer inline ask question")
+		}
+		return inlineTextAnswer("ask", title, explainErrorToUserText(err)), outcome
+	}
+
+	// Inline results are sent by the user's client, so there is no chance to
+	// retry with a plain-text fallback if MarkdownV2 parsing fails; send the
+	// plain rendering instead.
+	text := plainTelegramMarkdownText(answer)
+	return inlineAnswer{
+		id:          "ask",
+		title:       title,
+		description: truncateRunes(text, maxInlineDescriptionRunes),
+		text:        text,
+	}, "success"
+}
+
+func inlineTextAnswer(id, title, text string) inlineAnswer {
+	return inlineAnswer{
+		id:          id,
+		title:       truncateRunes(title, maxInlineTitleRunes),
+		description: truncateRunes(text, maxInlineDescriptionRunes),
+		text:        text,
+	}
+}
+
+// allowInlineAskRequest shares explainLimiter with the chat ask flow under a
+// per-user key, since an inline query carries no chat.
+func allowInlineAskRequest(query *models.InlineQuery) (bool, time.Duration) {
+	if explainLimiter == nil {
+		return true, 0
+	}
+	key := fmt.Sprintf("inline:user:%d", inlineQueryUserID(query))
+	return explainLimiter.allow(key, time.Now())
+}
+
+func inlineQueryUserID(query *models.InlineQuery) int64 {
+	if query == nil || query.From == nil {
+		return 0
+	}
+	return query.From.ID
+}
+
+// answerInlineQuery replies with a single article. Results are marked
+// personal so Telegram's server-side cache never hands an allowlisted user's
+// answer to someone outside ALLOWED_USERNAMES.
+func answerInlineQuery(ctx context.Context, b *bot.Bot, queryID string, answer inlineAnswer) {
+	_, err := b.AnswerInlineQuery(ctx, &bot.AnswerInlineQueryParams{
+		InlineQueryID: queryID,
+		Results: []models.InlineQueryResult{
+			&models.InlineQueryResultArticle{
+				ID:          answer.id,
+				Title:       answer.title,
+				Description: answer.description,
+				InputMessageContent: &models.InputTextMessageContent{
+					MessageText: answer.text,
+				},
+			},
+		},
+		CacheTime:  int(inlineQueryCacheTTL.Seconds()),
+		IsPersonal: true,
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("result_id", answer.id).Msg("Failed to answer inline query")
+	}
+}
@@ -0,0 +1,244 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func TestParseInlineQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantKind inlineQueryKind
+		wantArg  string
+	}{
+		{query: "$aapl", wantKind: inlineQueryStock, wantArg: "AAPL"},
+		{query: "  $BRK.A ", wantKind: inlineQueryStock, wantArg: "BRK.A"},
+		{query: "$", wantKind: inlineQueryUnknown},
+		{query: "$AAPL 7d", wantKind: inlineQueryUnknown},
+		{query: "LC", wantKind: inlineQueryLeetCode},
+		{query: "ask what is a mutex?", wantKind: inlineQueryAsk, wantArg: "what is a mutex?"},
+		{query: "Ask   spaced  ", wantKind: inlineQueryAsk, wantArg: "spaced"},
+		{query: "ask", wantKind: inlineQueryUnknown},
+		{query: "ask   ", wantKind: inlineQueryUnknown},
+		{query: "what is a mutex?", wantKind: inlineQueryUnknown},
+		{query: "", wantKind: inlineQueryUnknown},
+	}
+
+	for _, tt := range tests {
+		got := parseInlineQuery(tt.query)
+		if got.kind != tt.wantKind || got.arg != tt.wantArg {
+			t.Errorf("parseInlineQuery(%q) = {%d %q}, want {%d %q}", tt.query, got.kind, got.arg, tt.wantKind, tt.wantArg)
+		}
+	}
+}
+
+func TestInlineQueryRequestCacheKey(t *testing.T) {
+	if parseInlineQuery("$aapl").cacheKey() != parseInlineQuery("$AAPL").cacheKey() {
+		t.Fatal("expected case-insensitive symbols to share a cache key")
+	}
+	if parseInlineQuery("lc").cacheKey() == parseInlineQuery("$LC").cacheKey() {
+		t.Fatal("expected lc and $LC to use different cache keys")
+	}
+}
+
+func TestInlineCacheExpiresAndEvicts(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTTLCache[inlineAnswer]("test", time.Minute, 2)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	cache.set("a", inlineAnswer{id: "a"})
+	now = now.Add(30 * time.Second)
+	if got, ok := cache.get(ctx, "a"); !ok || got.id != "a" {
+		t.Fatalf("expected cached answer, got %+v ok=%v", got, ok)
+	}
+	now = now.Add(30 * time.Second)
+	if _, ok := cache.get(ctx, "a"); ok {
+		t.Fatal("expected entry to expire after TTL")
+	}
+
+	cache.set("a", inlineAnswer{id: "a"})
+	cache.set("b", inlineAnswer{id: "b"})
+	cache.set("c", inlineAnswer{id: "c"})
+	if _, ok := cache.get(ctx, "a"); ok {
+		t.Fatal("expected oldest entry to be evicted at capacity")
+	}
+	if _, ok := cache.get(ctx, "c"); !ok {
+		t.Fatal("expected newest entry to be cached")
+	}
+}
+
+func TestEnforceChatAccess_InlineQueryUsesUsernameAllowlist(t *testing.T) {
+	prev := allowedUsernames
+	defer func() { allowedUsernames = prev }()
+	allowedUsernames = map[string]struct{}{"alice": {}}
+
+	allowed := &models.Update{InlineQuery: &models.InlineQuery{
+		ID:   "q1",
+		From: &models.User{ID: 1, Username: "Alice"},
+	}}
+	if !enforceChatAccess(context.Background(), nil, allowed) {
+		t.Fatal("expected allowlisted user's inline query to be allowed")
+	}
+
+	denied := &models.Update{InlineQuery: &models.InlineQuery{
+		ID:   "q2",
+		From: &models.User{ID: 2, Username: "mallory"},
+	}}
+	if enforceChatAccess(context.Background(), nil, denied) {
+		t.Fatal("expected non-allowlisted user's inline query to be ignored")
+	}
+
+	noUsername := &models.Update{InlineQuery: &models.InlineQuery{
+		ID:   "q3",
+		From: &models.User{ID: 3},
+	}}
+	if enforceChatAccess(context.Background(), nil, noUsername) {
+		t.Fatal("expected inline query without username to be ignored")
+	}
+}
+
+// inlineAnswerServer captures answerInlineQuery calls from the bot client.
+type inlineAnswerServer struct {
+	mu         sync.Mutex
+	results    []string
+	cacheTimes []string
+	personal   []string
+}
+
+func (s *inlineAnswerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/answerInlineQuery") {
+		const maxFormSize = 1 << 20
+		r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+		if err := r.ParseMultipartForm(maxFormSize); err == nil {
+			s.mu.Lock()
+			s.results = append(s.results, r.FormValue("results"))
+			s.cacheTimes = append(s.cacheTimes, r.FormValue("cache_time"))
+			s.personal = append(s.personal, r.FormValue("is_personal"))
+			s.mu.Unlock()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": true})
+}
+
+func (s *inlineAnswerServer) answers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.results...)
+}
+
+func newInlineTestBot(t *testing.T) (*bot.Bot, *inlineAnswerServer) {
+	t.Helper()
+	srv := &inlineAnswerServer{}
+	server := httptest.NewTestServer(t, srv)
+	server.Start()
+
+	b, err := bot.New("dummy:test-token", bot.WithServerURL(server.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatalf("create test bot: %v", err)
+	}
+	return b, srv
+}
+
+func withFreshInlineCache(t *testing.T) {
+	t.Helper()
+	prev := inlineCache
+	inlineCache = newTTLCache[inlineAnswer]("inline_query", inlineQueryCacheTTL, inlineQueryCacheMaxEntries)
+	t.Cleanup(func() { inlineCache = prev })
+}
+
+func TestInlineQueryHandler_LeetCodeIsCachedPerQuery(t *testing.T) {
+	withFreshInlineCache(t)
+
+	var calls int
+	var mu sync.Mutex
+	lcServer := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"activeDailyCodingChallengeQuestion":{"question":{"title":"Two Sum","titleSlug":"two-sum","difficulty":"Easy"}}}}`))
+	}))
+	lcServer.Start()
+	useRedirectedHTTPClient(t, lcServer.URL)
+
+	b, srv := newInlineTestBot(t)
+	update := &models.Update{InlineQuery: &models.InlineQuery{
+		ID:    "q1",
+		From:  &models.User{ID: 1, Username: "alice"},
+		Query: "lc",
+	}}
+
+	inlineQueryHandler(context.Background(), b, update)
+	inlineQueryHandler(context.Background(), b, update)
+
+	mu.Lock()
+	gotCalls := calls
+	mu.Unlock()
+	if gotCalls != 1 {
+		t.Fatalf("expected LeetCode to be fetched once, got %d", gotCalls)
+	}
+
+	answers := srv.answers()
+	if len(answers) != 2 {
+		t.Fatalf("expected 2 inline answers, got %d", len(answers))
+	}
+	for _, answer := range answers {
+		if !strings.Contains(answer, "two-sum") || !strings.Contains(answer, `"type":"article"`) {
+			t.Fatalf("expected LeetCode article, got %s", answer)
+		}
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.cacheTimes[0] != "60" || srv.personal[0] != "true" {
+		t.Fatalf("expected personal results with 60s cache, got cache_time=%q is_personal=%q", srv.cacheTimes[0], srv.personal[0])
+	}
+}
+
+func TestInlineQueryHandler_UnknownQueryGetsUsage(t *testing.T) {
+	withFreshInlineCache(t)
+	b, srv := newInlineTestBot(t)
+
+	inlineQueryHandler(context.Background(), b, &models.Update{InlineQuery: &models.InlineQuery{
+		ID:    "q1",
+		From:  &models.User{ID: 1, Username: "alice"},
+		Query: "hello",
+	}})
+
+	answers := srv.answers()
+	if len(answers) != 1 || !strings.Contains(answers[0], "Inline usage") {
+		t.Fatalf("expected usage article, got %v", answers)
+	}
+}
+
+func TestInlineQueryHandler_AskNotConfigured(t *testing.T) {
+	withFreshInlineCache(t)
+	prev := textExplainer
+	textExplainer = nil
+	defer func() { textExplainer = prev }()
+
+	b, srv := newInlineTestBot(t)
+	inlineQueryHandler(context.Background(), b, &models.Update{InlineQuery: &models.InlineQuery{
+		ID:    "q1",
+		From:  &models.User{ID: 1, Username: "alice"},
+		Query: "ask what is a mutex?",
+	}})
+
+	answers := srv.answers()
+	if len(answers) != 1 || !strings.Contains(answers[0], "not configured") {
+		t.Fatalf("expected not-configured article, got %v", answers)
+	}
+	if _, ok := inlineCache.get(context.Background(), "ask:what is a mutex?"); ok {
+		t.Fatal("expected failed answers not to be cached")
+	}
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultBotDataDir = "data"
+
+// botDataPath returns the path of a state file inside BOT_DATA_DIR (default
+// ./data). Per-chat settings and other state that must survive restarts live
+// there; mount it as a volume in containers.
+func botDataPath(name string) string {
+	return filepath.Join(cmp.Or(getenvTrim("BOT_DATA_DIR"), defaultBotDataDir), name)
+}
+
+// readJSONFile decodes path into v. A missing file is not an error: it
+// reports false so callers can start from an empty state.
+func readJSONFile(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from operator config
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// writeJSONFileAtomic encodes v to path through a temp file and rename, so a
+// crash mid-write never leaves a truncated state file behind.
+func writeJSONFileAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file for %s: %w", path, err)
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write %s: %w", tmpName, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", tmpName, err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("rename %s: %w", tmpName, err)
+	}
+	return nil
+}
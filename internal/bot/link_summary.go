@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	autoSummaryCommand       = "!autosummary"
+	autoSummaryAdminOnlyMsg  = "Only chat admins can change auto-summaries."
+	autoSummarySaveFailedMsg = "Auto-summaries changed, but the setting could not be saved and will reset when the bot restarts."
+	autoSummaryUsageMsg      = "Usage: !autosummary [on|off]"
+
+	// autoSummaryQuestion is what Gemini is asked about the extracted page.
+	autoSummaryQuestion = "Give a short TL;DR of this article in one paragraph of at most four sentences. No headings or bullet points."
+
+	// A posted link is summarized at most once per cooldown per chat, so a
+	// busy link-sharing thread is not drowned in bot replies.
+	defaultAutoSummaryCooldown = 10 * time.Minute
+	// defaultAutoSummaryMinChars skips links whose extracted excerpts are too
+	// short to need a summary (or to come from an article at all). The
+	// excerpts are capped at maxParallelExtractExcerptsPerItem of
+	// maxParallelExtractExcerptRuneLen runes, so this measures what the
+	// extractor returned, not the whole page.
+	defaultAutoSummaryMinChars = 1500
+)
+
+var autoSummaryLimiter *memoryRateLimiter
+
+// shouldHandleAutoSummary matches a group message that carries exactly one
+// article link, in a chat that turned auto-summaries on. Commands, messages
+// from bots, tweet links (see xLinkHandler), YouTube links and links the
+// chat's domain lists rule out are left alone. It is registered after the ask
+// handlers, so a message that also mentions the bot gets a normal answer.
+func shouldHandleAutoSummary(update *models.Update) bool {
+	if update == nil || update.Message == nil {
+		return false
+	}
+	message := update.Message
+	if isPrivateMessage(message) || (message.From != nil && message.From.IsBot) {
+		return false
+	}
+	text := strings.TrimSpace(message.Text)
+	if text == "" || strings.HasPrefix(text, "!") || strings.HasPrefix(text, "/") {
+		return false
+	}
+	if !chatSettingsInstance.get(message.Chat.ID).AutoSummary {
+		return false
+	}
+	_, ok := autoSummaryURL(message)
+	return ok
+}
+
+// autoSummaryURL returns the message's only link when it is one worth
+// summarizing.
+func autoSummaryURL(message *models.Message) (string, bool) {
+	candidates := urlsFromEntities(message.Text, message.Entities)
+	candidates = append(candidates, scanTextURLs(message.Text)...)
+
+	var link string
+	for _, raw := range candidates {
+		normalized, ok := normalizeExtractURL(raw)
+		if !ok || normalized == link {
+			continue
+		}
+		if link != "" {
+			return "", false
+		}
+		link = normalized
+	}
+	if link == "" || len(extractFixedXLinks(link)) > 0 {
+		return "", false
+	}
+	if _, video := youtubeVideoID(link); video {
+		return "", false
+	}
+	if _, reason := domainPolicyFor(message).checkURL(link); reason != "" {
+		return "", false
+	}
+	return link, true
+}
+
+// autoSummaryHandler replies to a posted article link with a one-paragraph
+// TL;DR. The reply is unsolicited, so every failure — extraction, excerpts
+// too short to bother, cooldown, Gemini — skips quietly instead of posting an
+// error into the conversation.
+func autoSummaryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	message := update.Message
+	link, ok := autoSummaryURL(message)
+	if !ok {
+		return
+	}
+	if textExplainer == nil {
+		appotel.RecordOutcome(ctx, "not_configured")
+		return
+	}
+
+	results := extractPages(ctx, domainPolicyFor(message), []string{link}, autoSummaryQuestion)
+	if chars := extractedRuneCount(results); chars < loadAutoSummaryMinChars() {
+		appotel.RecordOutcome(ctx, "skipped")
+		log.Info().Int64("chat_id", message.Chat.ID).Int("chars", chars).Msg("Posted link too short or not extractable; skipping auto-summary")
+		return
+	}
+	// The cooldown is only taken once there is something to summarize, so a
+	// short or unreadable link does not hold back the next article.
+	if allowed, _ := autoSummaryLimiter.allow(strconv.FormatInt(message.Chat.ID, 10), time.Now()); !allowed {
+		appotel.RecordOutcome(ctx, "rate_limited")
+		return
+	}
+
+	summary, err := textExplainer.explainWithExtractResults(ctx, "", autoSummaryQuestion, results, shouldRespondInBurmese(message.Text))
+	if err != nil {
+		appotel.RecordOutcome(ctx, "error")
+		log.Warn().Err(err).Int64("chat_id", message.Chat.ID).Msg("Auto-summary failed; skipping")
+		return
+	}
+
+	text := "TL;DR: " + strings.TrimSpace(summary)
+	params := &bot.SendMessageParams{
+		ChatID:          message.Chat.ID,
+		MessageThreadID: message.MessageThreadID,
+		Text:            formatTelegramMarkdown(text),
+		ParseMode:       models.ParseModeMarkdown,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	}
+	if _, err := b.SendMessage(ctx, params); err != nil {
+		log.Warn().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to send markdown auto-summary; trying plain text")
+		params.Text = text
+		params.ParseMode = ""
+		if _, err := b.SendMessage(ctx, params); err != nil {
+			appotel.RecordOutcome(ctx, "error")
+			return
+		}
+	}
+	appotel.RecordOutcome(ctx, "success")
+}
+
+// extractedRuneCount totals the runes in the excerpts of results. It is at
+// most maxParallelExtractExcerptsPerItem*maxParallelExtractExcerptRuneLen per
+// link however long the page is.
+func extractedRuneCount(results []parallelExtractResult) int {
+	total := 0
+	for _, result := range results {
+		for _, excerpt := range result.Excerpts {
+			total += runeLen(excerpt)
+		}
+	}
+	return total
+}
+
+func shouldHandleAutoSummaryCommand(update *models.Update) bool {
+	if update == nil || update.Message == nil {
+		return false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(update.Message.Text), autoSummaryCommand)
+	return ok && (rest == "" || startsWithSpace(rest))
+}
+
+// autoSummaryCommandHandler shows whether posted links are summarized with
+// "!autosummary" and lets admins switch it with "!autosummary on|off".
+func autoSummaryCommandHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	message := update.Message
+	arg := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(message.Text), autoSummaryCommand)))
+
+	var enable bool
+	switch arg {
+	case "":
+		state := "off"
+		if chatSettingsInstance.get(message.Chat.ID).AutoSummary {
+			state = "on"
+		}
+		appotel.RecordOutcome(ctx, "success")
+		sendSettingsReply(ctx, b, message, fmt.Sprintf(
+			"Auto-summaries are %s. When on, a message with a single article link gets a short TL;DR.\nAdmins can switch with !autosummary on|off.",
+			state,
+		))
+		return
+	case "on":
+		enable = true
+	case "off":
+	default:
+		sendSettingsReply(ctx, b, message, autoSummaryUsageMsg)
+		return
+	}
+
+	if !isChatAdmin(ctx, b, message) {
+		appotel.RecordOutcome(ctx, "blocked")
+		sendSettingsReply(ctx, b, message, autoSummaryAdminOnlyMsg)
+		return
+	}
+
+	err := chatSettingsInstance.update(message.Chat.ID, func(settings *chatSettings) {
+		settings.AutoSummary = enable
+	})
+	if err != nil {
+		appotel.RecordOutcome(ctx, "error")
+		log.Error().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to persist chat auto-summary setting")
+		sendSettingsReply(ctx, b, message, autoSummarySaveFailedMsg)
+		return
+	}
+
+	log.Info().Int64("chat_id", message.Chat.ID).Bool("enabled", enable).Msg("Chat auto-summary changed")
+	appotel.RecordOutcome(ctx, "success")
+	sendSettingsReply(ctx, b, message, "Auto-summaries turned "+arg+".")
+}
+
+// loadAutoSummaryLimiter reads AUTO_SUMMARY_COOLDOWN_SECONDS, the per-chat
+// gap between two auto-summaries.
+func loadAutoSummaryLimiter() *memoryRateLimiter {
+	cooldown := defaultAutoSummaryCooldown
+	if raw := getenvTrim("AUTO_SUMMARY_COOLDOWN_SECONDS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			cooldown = time.Duration(n) * time.Second
+		} else {
+			log.Warn().Str("value", raw).Msg("Invalid AUTO_SUMMARY_COOLDOWN_SECONDS value; using default")
+		}
+	}
+	return newMemoryRateLimiter(1, cooldown)
+}
+
+// loadAutoSummaryMinChars reads AUTO_SUMMARY_MIN_CHARS, the extracted text
+// length below which a page is not summarized.
+func loadAutoSummaryMinChars() int {
+	raw := getenvTrim("AUTO_SUMMARY_MIN_CHARS")
+	if raw == "" {
+		return defaultAutoSummaryMinChars
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		log.Warn().Str("value", raw).Msg("Invalid AUTO_SUMMARY_MIN_CHARS value; using default")
+		return defaultAutoSummaryMinChars
+	}
+	return n
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func withAutoSummaryChat(t *testing.T) *chatSettingsStore {
+	t.Helper()
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	if err := store.update(-100, func(s *chatSettings) { s.AutoSummary = true }); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	return store
+}
+
+func TestShouldHandleAutoSummary(t *testing.T) {
+	withAutoSummaryChat(t)
+	t.Setenv("EXTRACT_ALLOWED_DOMAINS", "")
+	t.Setenv("EXTRACT_DENIED_DOMAINS", "ft.com")
+
+	for text, want := range map[string]bool{
+		"worth a read https://example.com/long-article":         true,
+		"https://example.com/a and again https://EXAMPLE.com/a": true,
+		"two links https://example.com/a https://example.org/b": false,
+		"https://x.com/someone/status/123":                      false,
+		"https://youtu.be/dQw4w9WgXcQ":                          false,
+		"https://www.ft.com/content/abc":                        false,
+		"!tr https://example.com/article":                       false,
+		"no links here":                                         false,
+		"http://127.0.0.1/admin":                                false,
+	} {
+		if got := shouldHandleAutoSummary(groupTextUpdate(text)); got != want {
+			t.Errorf("shouldHandleAutoSummary(%q) = %v, want %v", text, got, want)
+		}
+	}
+
+	if shouldHandleAutoSummary(privateTextUpdate("https://example.com/article")) {
+		t.Error("expected private chats to be ignored")
+	}
+	fromBot := groupTextUpdate("https://example.com/article")
+	fromBot.Message.From = &models.User{ID: 9, IsBot: true}
+	if shouldHandleAutoSummary(fromBot) {
+		t.Error("expected messages from bots to be ignored")
+	}
+	other := groupTextUpdate("https://example.com/article")
+	other.Message.Chat.ID = -200
+	if shouldHandleAutoSummary(other) {
+		t.Error("expected chats without auto-summaries to be ignored")
+	}
+}
+
+func setupAutoSummaryPage(t *testing.T) {
+	t.Helper()
+	t.Setenv("PARALLEL_API_KEY", "")
+	t.Setenv("EXTRACT_ENABLED", "")
+	t.Setenv("LOCAL_EXTRACT_ENABLED", "")
+	t.Setenv("EXTRACT_ALLOWED_DOMAINS", "")
+	t.Setenv("EXTRACT_DENIED_DOMAINS", "")
+
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(testArticleHTML))
+	}))
+	server.Start()
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parse server URL: %v", err)
+	}
+
+	prevClient := localExtractHTTPClient
+	localExtractHTTPClient = &http.Client{
+		Transport:     &rewriteHostTransport{base: http.DefaultTransport, target: target},
+		CheckRedirect: checkLocalExtractRedirect,
+	}
+	prevExplainer, prevLimiter := textExplainer, autoSummaryLimiter
+	textExplainer = &geminiExplainer{generator: &capturingGenerator{}}
+	autoSummaryLimiter = newMemoryRateLimiter(1, time.Hour)
+	t.Cleanup(func() {
+		localExtractHTTPClient = prevClient
+		textExplainer, autoSummaryLimiter = prevExplainer, prevLimiter
+	})
+}
+
+func TestAutoSummaryHandlerRepliesOncePerCooldown(t *testing.T) {
+	withAutoSummaryChat(t)
+	setupAutoSummaryPage(t)
+	t.Setenv("AUTO_SUMMARY_MIN_CHARS", "50")
+	b, srv := newTestBot(t)
+
+	autoSummaryHandler(context.Background(), b, groupTextUpdate("https://news.example.com/acme-prices"))
+	srv.mu.Lock()
+	reply, sent := srv.lastMessage, len(srv.requestLog)
+	srv.mu.Unlock()
+	if !strings.HasPrefix(reply, "TL;DR: explanation") {
+		t.Fatalf("expected a TL;DR reply, got %q", reply)
+	}
+
+	autoSummaryHandler(context.Background(), b, groupTextUpdate("https://news.example.com/other"))
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requestLog) != sent {
+		t.Fatalf("expected the cooldown to suppress a second summary, got %v", srv.requestLog)
+	}
+}
+
+func TestAutoSummaryHandlerSkipsShortPagesQuietly(t *testing.T) {
+	withAutoSummaryChat(t)
+	setupAutoSummaryPage(t)
+	t.Setenv("AUTO_SUMMARY_MIN_CHARS", "100000")
+	b, srv := newTestBot(t)
+
+	autoSummaryHandler(context.Background(), b, groupTextUpdate("https://news.example.com/acme-prices"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requestLog) != 0 {
+		t.Fatalf("expected no reply for a short page, got %v", srv.requestLog)
+	}
+	srv.mu.Unlock()
+
+	// The skipped link must not have used up the chat's cooldown.
+	t.Setenv("AUTO_SUMMARY_MIN_CHARS", "50")
+	autoSummaryHandler(context.Background(), b, groupTextUpdate("https://news.example.com/acme-prices"))
+	srv.mu.Lock()
+	if !strings.HasPrefix(srv.lastMessage, "TL;DR: explanation") {
+		t.Fatalf("expected a TL;DR reply after a skipped link, got %q", srv.lastMessage)
+	}
+}
+
+func TestAutoSummaryCommandHandler(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	b, srv := newTestBot(t)
+
+	send := func(text string) string {
+		update := privateTextUpdate(text)
+		update.Message.From = &models.User{ID: 42}
+		autoSummaryCommandHandler(context.Background(), b, update)
+		return srv.lastMessage
+	}
+
+	if reply := send("!autosummary"); !strings.HasPrefix(reply, "Auto-summaries are off.") {
+		t.Fatalf("unexpected status %q", reply)
+	}
+	if reply := send("!autosummary ON"); reply != "Auto-summaries turned on." || !store.get(42).AutoSummary {
+		t.Fatalf("expected auto-summaries enabled, got %q", reply)
+	}
+	if reply := send("!autosummary maybe"); reply != autoSummaryUsageMsg {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+	send("!autosummary off")
+	if !store.get(42).isZero() {
+		t.Fatalf("expected off to reset the chat, got %+v", store.get(42))
+	}
+}
+
+func TestLoadAutoSummaryMinChars(t *testing.T) {
+	t.Setenv("AUTO_SUMMARY_MIN_CHARS", "")
+	if got := loadAutoSummaryMinChars(); got != defaultAutoSummaryMinChars {
+		t.Fatalf("expected default, got %d", got)
+	}
+	t.Setenv("AUTO_SUMMARY_MIN_CHARS", "-1")
+	if got := loadAutoSummaryMinChars(); got != defaultAutoSummaryMinChars {
+		t.Fatalf("expected default for invalid value, got %d", got)
+	}
+	t.Setenv("AUTO_SUMMARY_MIN_CHARS", "800")
+	if got := loadAutoSummaryMinChars(); got != 800 {
+		t.Fatalf("expected 800, got %d", got)
+	}
+}
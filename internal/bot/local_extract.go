@@ -0,0 +1,487 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultLocalExtractTimeout  = 10 * time.Second
+	defaultLocalExtractMaxBytes = 2 << 20
+	maxLocalExtractRedirects    = 5
+
+	// minLocalParagraphRunes drops menu items, buttons and captions that
+	// survive tag stripping; a page made only of short lines keeps them.
+	minLocalParagraphRunes = 40
+
+	localExtractUserAgent = "Mozilla/5.0 (compatible; csy-helper-bot/1.0; +https://gitlab.com/yelinaung/csy-helper-bot)"
+	localExtractAccept    = "text/html,application/xhtml+xml,text/plain;q=0.9"
+)
+
+var errUnsupportedContentType = errors.New("unsupported content type")
+
+// localExtractHTTPClient is separate from httpClient so redirects can be
+// re-validated and the per-page timeout comes from the request context. Its
+// transport refuses hosts that resolve to internal addresses (see
+// ssrf_guard.go): unlike Parallel, this fetch runs inside our network.
+var localExtractHTTPClient = &http.Client{
+	Transport:     newSSRFGuardTransport(net.DefaultResolver),
+	CheckRedirect: checkLocalExtractRedirect,
+}
+
+// localExtractor fetches pages itself and cuts excerpts locally. It returns
+// the same parallelExtractResult shape as Parallel Extract so the grounded
+// explainer cannot tell the two apart; it is the fallback when Parallel is
+// unconfigured or fails.
+type localExtractor struct {
+	client   *http.Client
+	timeout  time.Duration
+	maxBytes int64
+	// policy is the asking chat's domain policy, re-checked on every
+	// redirect hop.
+	policy domainPolicy
+}
+
+// localPage is the readable content of a fetched page.
+type localPage struct {
+	title       string
+	publishDate string
+	paragraphs  []string
+}
+
+// newLocalExtractor builds a local extractor from the environment that
+// follows redirects only within policy. It returns nil when EXTRACT_ENABLED
+// or LOCAL_EXTRACT_ENABLED is explicitly false.
+func newLocalExtractor(policy domainPolicy) *localExtractor {
+	if !loadExtractEnabled() || !loadLocalExtractEnabled() {
+		return nil
+	}
+	return &localExtractor{
+		client:   localExtractHTTPClient,
+		timeout:  loadLocalExtractTimeout(),
+		maxBytes: loadLocalExtractMaxBytes(),
+		policy:   policy,
+	}
+}
+
+// extract fetches every URL concurrently and keeps the pages that yielded
+// readable text, in request order. Per-URL failures are logged and skipped;
+// an error is returned only when every URL failed.
+func (l *localExtractor) extract(ctx context.Context, urls []string, objective string) (results []parallelExtractResult, err error) {
+	if l == nil {
+		return nil, errors.New("local extractor not configured")
+	}
+
+	ctx, span := tracer().Start(
+		ctx, "local.extract",
+		trace.WithAttributes(
+			attribute.Int("local_extract.urls_count", len(urls)),
+			attribute.Int("local_extract.objective_len", len(strings.TrimSpace(objective))),
+		),
+	)
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	objective = strings.TrimSpace(objective)
+	if objective == "" {
+		return nil, errors.New("extract objective is required")
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("at least one URL is required")
+	}
+
+	pages := make([]*localPage, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Go(func() {
+			pages[i], errs[i] = l.fetchPage(ctx, u)
+		})
+	}
+	wg.Wait()
+
+	for i, page := range pages {
+		if errs[i] != nil {
+			log.Warn().
+				Str("host", urlHost(urls[i])).
+				Str("error", sanitizeExtractErrorContent(errs[i].Error())).
+				Msg("Local extract failed for URL")
+			continue
+		}
+		results = append(results, parallelExtractResult{
+			URL:         urls[i],
+			Title:       page.title,
+			PublishDate: page.publishDate,
+			Excerpts:    selectExcerpts(page.paragraphs, objective, maxParallelExtractExcerptsPerItem),
+		})
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("local extract failed for all URLs: %w", errors.Join(errs...))
+	}
+
+	sanitized := sanitizeParallelExtractResults(results)
+	span.SetAttributes(attribute.Int("local_extract.excerpts_count", countExtractExcerpts(sanitized)))
+	log.Info().
+		Int("result_count", len(sanitized)).
+		Int("url_count", len(urls)).
+		Msg("Local extract completed")
+	return sanitized, nil
+}
+
+// fetchPage downloads one page within the time and size limits and converts
+// it to paragraphs. HTML and plain text are supported; anything else (PDFs,
+// images, JSON) is rejected rather than fed to the model as noise.
+func (l *localExtractor) fetchPage(ctx context.Context, rawURL string) (*localPage, error) {
+	timeout := cmp.Or(l.timeout, defaultLocalExtractTimeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create page request: %w", err)
+	}
+	req.Header.Set("User-Agent", localExtractUserAgent)
+	req.Header.Set("Accept", localExtractAccept)
+
+	// A shallow copy keeps the SSRF-guarded transport, which vets every hop's
+	// address, and adds the domain policy to the redirect check.
+	client := *cmp.Or(l.client, localExtractHTTPClient)
+	client.CheckRedirect = l.checkRedirect
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch page: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch page returned status %s", resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType = "text/html"
+	}
+
+	maxBytes := cmp.Or(l.maxBytes, defaultLocalExtractMaxBytes)
+	// A page larger than the limit is truncated, not rejected: the lead of an
+	// article is what the excerpts are most likely to come from anyway.
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBytes), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("decode page charset: %w", err)
+	}
+
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		return parseHTMLPage(body)
+	case "text/plain":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("read page: %w", err)
+		}
+		return &localPage{paragraphs: splitTextParagraphs(string(data))}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedContentType, mediaType)
+	}
+}
+
+// checkLocalExtractRedirect applies the same URL rules as the initial link to
+// every redirect hop, so a public link cannot bounce the fetch to a literal
+// private address or a non-HTTP scheme.
+func checkLocalExtractRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxLocalExtractRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxLocalExtractRedirects)
+	}
+	if _, ok := normalizeExtractURL(req.URL.String()); !ok {
+		return errors.New("redirect to disallowed URL")
+	}
+	return nil
+}
+
+// checkRedirect adds the extractor's domain policy to
+// checkLocalExtractRedirect, so an allowed link cannot bounce the fetch to a
+// denied or non-allowlisted domain.
+func (l *localExtractor) checkRedirect(req *http.Request, via []*http.Request) error {
+	if err := checkLocalExtractRedirect(req, via); err != nil {
+		return err
+	}
+	if host, reason := l.policy.checkURL(req.URL.String()); reason != "" {
+		return fmt.Errorf("redirect to %s: %s", host, reason)
+	}
+	return nil
+}
+
+// skippedHTMLElements hold navigation, chrome or non-text content.
+var skippedHTMLElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Nav: true, atom.Header: true, atom.Footer: true,
+	atom.Aside: true, atom.Form: true, atom.Iframe: true, atom.Button: true,
+	atom.Select: true, atom.Head: true,
+}
+
+// blockHTMLElements end the current paragraph.
+var blockHTMLElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Main: true, atom.Li: true, atom.Ul: true, atom.Ol: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Tr: true,
+	atom.Br: true, atom.Hr: true, atom.Dd: true, atom.Dt: true, atom.Figcaption: true,
+}
+
+// parseHTMLPage strips markup to paragraphs of readable text and picks the
+// title (og:title, then <title>, then the first <h1>) and publish date.
+func parseHTMLPage(r io.Reader) (*localPage, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse html: %w", err)
+	}
+
+	page := &localPage{}
+	var ogTitle, docTitle, firstH1 string
+	var current strings.Builder
+	flush := func() {
+		if text := strings.Join(strings.Fields(current.String()), " "); text != "" {
+			page.paragraphs = append(page.paragraphs, text)
+		}
+		current.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if docTitle == "" {
+					docTitle = nodeText(n)
+				}
+				return
+			case atom.Meta:
+				ogTitle, page.publishDate = readMetaTag(n, ogTitle, page.publishDate)
+				return
+			case atom.H1:
+				if firstH1 == "" {
+					firstH1 = nodeText(n)
+				}
+			}
+			if skippedHTMLElements[n.DataAtom] {
+				// <head> still carries the title and meta tags.
+				if n.DataAtom == atom.Head {
+					for c := n.FirstChild; c != nil; c = c.NextSibling {
+						walk(c)
+					}
+				}
+				return
+			}
+		}
+
+		block := n.Type == html.ElementNode && blockHTMLElements[n.DataAtom]
+		if block {
+			flush()
+		}
+		if n.Type == html.TextNode {
+			current.WriteString(n.Data)
+			current.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			flush()
+		}
+	}
+	walk(doc)
+	flush()
+
+	page.title = cmp.Or(ogTitle, docTitle, firstH1)
+	return page, nil
+}
+
+// readMetaTag picks og:title and the article publish date out of a <meta>
+// tag, keeping values already found.
+func readMetaTag(n *html.Node, ogTitle, publishDate string) (string, string) {
+	var key, content string
+	for _, a := range n.Attr {
+		switch strings.ToLower(a.Key) {
+		case "property", "name", "itemprop":
+			key = strings.ToLower(a.Val)
+		case "content":
+			content = strings.TrimSpace(a.Val)
+		}
+	}
+	switch key {
+	case "og:title":
+		if ogTitle == "" {
+			ogTitle = content
+		}
+	case "article:published_time", "datepublished", "date", "pubdate":
+		if publishDate == "" && len(content) >= len(dateFormatPattern) {
+			if _, err := time.Parse(dateFormatPattern, content[:len(dateFormatPattern)]); err == nil {
+				publishDate = content[:len(dateFormatPattern)]
+			}
+		}
+	}
+	return ogTitle, publishDate
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// splitTextParagraphs splits plain text on blank lines.
+func splitTextParagraphs(text string) []string {
+	var paragraphs []string
+	for block := range strings.SplitSeq(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p := strings.Join(strings.Fields(block), " "); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// objectiveStopWords are question words too common to say which paragraph is
+// relevant.
+var objectiveStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "what": true,
+	"which": true, "who": true, "why": true, "how": true, "does": true, "did": true,
+	"this": true, "that": true, "with": true, "from": true, "about": true, "into": true,
+	"can": true, "you": true, "your": true, "tell": true, "page": true, "article": true,
+	"link": true, "summarize": true, "summary": true, "explain": true, "key": true,
+	"points": true, "there": true, "their": true, "they": true, "have": true, "has": true,
+}
+
+// objectiveTerms lowercases the objective into distinct content words.
+func objectiveTerms(objective string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(objective), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) && !unicode.Is(unicode.Mc, r)
+	}) {
+		if runeLen(word) < 3 || objectiveStopWords[word] || slices.Contains(terms, word) {
+			continue
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// selectExcerpts picks up to limit paragraphs for the objective: those that
+// mention the most objective terms, kept in page order. When nothing matches
+// (or the objective is the generic summary request) the page lead is used.
+func selectExcerpts(paragraphs []string, objective string, limit int) []string {
+	candidates := make([]string, 0, len(paragraphs))
+	for _, p := range paragraphs {
+		if runeLen(p) >= minLocalParagraphRunes {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = paragraphs
+	}
+
+	type scored struct {
+		index int
+		score int
+	}
+	var matches []scored
+	if terms := objectiveTerms(objective); len(terms) > 0 && objective != defaultExtractObjective {
+		for i, p := range candidates {
+			lower := strings.ToLower(p)
+			score := 0
+			for _, term := range terms {
+				if strings.Contains(lower, term) {
+					score++
+				}
+			}
+			if score > 0 {
+				matches = append(matches, scored{index: i, score: score})
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return slices.Clone(candidates[:min(limit, len(candidates))])
+	}
+
+	slices.SortStableFunc(matches, func(a, b scored) int { return b.score - a.score })
+	matches = matches[:min(limit, len(matches))]
+	slices.SortFunc(matches, func(a, b scored) int { return a.index - b.index })
+
+	excerpts := make([]string, 0, len(matches))
+	for _, m := range matches {
+		excerpts = append(excerpts, candidates[m.index])
+	}
+	return excerpts
+}
+
+// loadLocalExtractEnabled reads LOCAL_EXTRACT_ENABLED, defaulting to enabled.
+func loadLocalExtractEnabled() bool {
+	raw := getenvTrim("LOCAL_EXTRACT_ENABLED")
+	if raw == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Warn().Str("value", raw).Msg("Invalid LOCAL_EXTRACT_ENABLED value; defaulting to enabled")
+		return true
+	}
+	return enabled
+}
+
+// loadLocalExtractTimeout reads LOCAL_EXTRACT_TIMEOUT_SECONDS, the per-page
+// fetch budget, defaulting to 10s.
+func loadLocalExtractTimeout() time.Duration {
+	raw := getenvTrim("LOCAL_EXTRACT_TIMEOUT_SECONDS")
+	if raw == "" {
+		return defaultLocalExtractTimeout
+	}
+	seconds, err := strconv.Atoi(raw)
+	if err != nil || seconds <= 0 {
+		return defaultLocalExtractTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// loadLocalExtractMaxBytes reads LOCAL_EXTRACT_MAX_BYTES, how much of a page
+// body is read, defaulting to 2 MiB.
+func loadLocalExtractMaxBytes() int64 {
+	raw := getenvTrim("LOCAL_EXTRACT_MAX_BYTES")
+	if raw == "" {
+		return defaultLocalExtractMaxBytes
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		return defaultLocalExtractMaxBytes
+	}
+	return n
+}
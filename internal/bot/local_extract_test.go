@@ -0,0 +1,342 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testArticleHTML = `<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Acme raises prices">
+<meta property="article:published_time" content="2026-03-04T10:00:00Z">
+<style>body { color: red; }</style>
+<script>var secret = "do not include";</script>
+</head><body>
+<nav><a href="/">Home</a> <a href="/pricing">Pricing navigation link list</a></nav>
+<article>
+<h1>Acme raises prices</h1>
+<p>Acme announced on Monday that it is changing its subscription plans for all customers worldwide.</p>
+<p>The Pro plan now costs $12 per month, up from $10, while the Team plan costs $30 per seat.</p>
+<div>Short</div>
+<p>Analysts expect competitors to follow with their own adjustments later in the year.</p>
+</article>
+<footer>Copyright Acme Corporation. All rights reserved. Contact us for more details.</footer>
+</body></html>`
+
+func newTestLocalExtractor(t *testing.T, handler http.HandlerFunc) (*localExtractor, string) {
+	t.Helper()
+	server := httptest.NewTestServer(t, handler)
+	server.Start()
+	return &localExtractor{
+		client:   &http.Client{CheckRedirect: checkLocalExtractRedirect},
+		timeout:  5 * time.Second,
+		maxBytes: defaultLocalExtractMaxBytes,
+	}, server.URL
+}
+
+func TestLocalExtractor_HTMLPage(t *testing.T) {
+	t.Parallel()
+
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != localExtractUserAgent {
+			t.Errorf("unexpected user agent %q", r.Header.Get("User-Agent"))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testArticleHTML))
+	})
+
+	results, err := extractor.extract(context.Background(), []string{baseURL + "/news"}, "how much does the pro plan cost per month?")
+	if err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	result := results[0]
+	if result.URL != baseURL+"/news" || result.Title != "Acme raises prices" || result.PublishDate != "2026-03-04" {
+		t.Fatalf("unexpected result metadata: %+v", result)
+	}
+	joined := strings.Join(result.Excerpts, " ")
+	if !strings.Contains(joined, "$12 per month") || strings.Contains(joined, "Analysts") {
+		t.Fatalf("expected only paragraphs matching the objective, got %q", result.Excerpts)
+	}
+	for _, unwanted := range []string{"do not include", "color: red", "navigation link", "Copyright"} {
+		if strings.Contains(joined, unwanted) {
+			t.Errorf("expected %q to be stripped, got %q", unwanted, joined)
+		}
+	}
+}
+
+func TestLocalExtractor_PlainTextAndCharset(t *testing.T) {
+	t.Parallel()
+
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+		// "Café prices rose sharply this year across the whole region." in Latin-1.
+		_, _ = w.Write([]byte("Caf\xe9 prices rose sharply this year across the whole region.\n\nSecond paragraph about something else entirely here."))
+	})
+
+	results, err := extractor.extract(context.Background(), []string{baseURL}, defaultExtractObjective)
+	if err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	if len(results) != 1 || len(results[0].Excerpts) != 2 {
+		t.Fatalf("expected both paragraphs as excerpts, got %+v", results)
+	}
+	if !strings.HasPrefix(results[0].Excerpts[0], "Café prices") {
+		t.Fatalf("expected Latin-1 text to be decoded, got %q", results[0].Excerpts[0])
+	}
+}
+
+func TestLocalExtractor_RejectsUnsupportedContentType(t *testing.T) {
+	t.Parallel()
+
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.7"))
+	})
+
+	_, err := extractor.extract(context.Background(), []string{baseURL}, "objective")
+	if !errors.Is(err, errUnsupportedContentType) {
+		t.Fatalf("expected unsupported content type error, got %v", err)
+	}
+}
+
+func TestLocalExtractor_SkipsFailedURLs(t *testing.T) {
+	t.Parallel()
+
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(testArticleHTML))
+	})
+
+	results, err := extractor.extract(context.Background(), []string{baseURL + "/missing", baseURL + "/ok"}, "objective")
+	if err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	if len(results) != 1 || results[0].URL != baseURL+"/ok" {
+		t.Fatalf("expected only the working URL, got %+v", results)
+	}
+}
+
+func TestLocalExtractor_TruncatesLargePages(t *testing.T) {
+	t.Parallel()
+
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("a", 100) + "\n\n" + strings.Repeat("b", 100)))
+	})
+	extractor.maxBytes = 50
+
+	results, err := extractor.extract(context.Background(), []string{baseURL}, defaultExtractObjective)
+	if err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	if len(results[0].Excerpts) != 1 || results[0].Excerpts[0] != strings.Repeat("a", 50) {
+		t.Fatalf("expected body to be cut at maxBytes, got %q", results[0].Excerpts)
+	}
+}
+
+func TestLocalExtractor_Timeout(t *testing.T) {
+	t.Parallel()
+
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	extractor.timeout = 50 * time.Millisecond
+
+	if _, err := extractor.extract(context.Background(), []string{baseURL}, "objective"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestLocalExtractor_RejectsRedirectToDisallowedURL(t *testing.T) {
+	t.Parallel()
+
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://files.example.com/secret", http.StatusFound)
+	})
+
+	if _, err := extractor.extract(context.Background(), []string{baseURL}, "objective"); err == nil {
+		t.Fatal("expected redirect to a non-HTTP URL to fail")
+	}
+}
+
+func TestLocalExtractor_RejectsRedirectToDeniedDomain(t *testing.T) {
+	t.Parallel()
+
+	var pageHits atomic.Int32
+	page := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pageHits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(testArticleHTML))
+	}))
+	page.Start()
+	extractor, baseURL := newTestLocalExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://news.blocked.example/article", http.StatusFound)
+	})
+	// The redirect target resolves to the page server, standing in for DNS.
+	extractor.client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if strings.HasPrefix(addr, "news.blocked.example:") {
+				addr = page.Listener.Addr().String()
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	extractor.policy = domainPolicy{chatDenied: []string{"blocked.example"}}
+
+	if _, err := extractor.extract(context.Background(), []string{baseURL}, "objective"); err == nil {
+		t.Fatal("expected redirect to a denied domain to fail")
+	}
+	if pageHits.Load() != 0 {
+		t.Fatal("expected the denied host never to be fetched")
+	}
+
+	extractor.policy = domainPolicy{}
+	if _, err := extractor.extract(context.Background(), []string{baseURL}, "objective"); err != nil || pageHits.Load() != 1 {
+		t.Fatalf("expected the redirect to be followed without a policy, got %v", err)
+	}
+}
+
+func TestLocalExtractor_NilAndInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	var nilExtractor *localExtractor
+	if _, err := nilExtractor.extract(context.Background(), []string{"https://example.com"}, "objective"); err == nil {
+		t.Fatal("expected nil extractor error")
+	}
+	extractor := &localExtractor{}
+	if _, err := extractor.extract(context.Background(), []string{"https://example.com"}, " "); err == nil {
+		t.Fatal("expected empty objective error")
+	}
+	if _, err := extractor.extract(context.Background(), nil, "objective"); err == nil {
+		t.Fatal("expected empty URLs error")
+	}
+}
+
+func TestParseHTMLPageTitleFallbacks(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		`<html><head><title> Doc  title </title></head><body><h1>Heading</h1></body></html>`: "Doc title",
+		`<html><body><h1>Only <em>heading</em></h1><p>text</p></body></html>`:                "Only heading",
+		`<html><body><p>no title</p></body></html>`:                                          "",
+	}
+	for doc, want := range tests {
+		page, err := parseHTMLPage(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("parseHTMLPage error: %v", err)
+		}
+		if page.title != want {
+			t.Errorf("title for %q = %q, want %q", doc, page.title, want)
+		}
+	}
+}
+
+func TestSelectExcerpts(t *testing.T) {
+	t.Parallel()
+
+	paragraphs := []string{
+		"Intro paragraph that sets the scene for the rest of the article.",
+		"Menu",
+		"Battery life is rated at twenty hours of continuous video playback.",
+		"The display is a bright OLED panel with a fast refresh rate.",
+		"Battery charging reaches fifty percent in thirty minutes with the display off.",
+	}
+
+	got := selectExcerpts(paragraphs, "how long does the battery last and how fast is charging?", 2)
+	want := []string{paragraphs[2], paragraphs[4]}
+	if !slices.Equal(got, want) {
+		t.Fatalf("relevant excerpts = %q, want %q", got, want)
+	}
+
+	lead := selectExcerpts(paragraphs, defaultExtractObjective, 2)
+	if !slices.Equal(lead, []string{paragraphs[0], paragraphs[2]}) {
+		t.Fatalf("expected page lead without short lines, got %q", lead)
+	}
+
+	if got := selectExcerpts(paragraphs, "quantum entanglement", 1); !slices.Equal(got, paragraphs[:1]) {
+		t.Fatalf("expected lead when nothing matches, got %q", got)
+	}
+
+	short := []string{"Hi", "Yo"}
+	if got := selectExcerpts(short, defaultExtractObjective, 4); !slices.Equal(got, short) {
+		t.Fatalf("expected short-only pages to keep their lines, got %q", got)
+	}
+}
+
+func TestObjectiveTerms(t *testing.T) {
+	t.Parallel()
+
+	got := objectiveTerms("What is the PRICE of the Pro plan? price, plan!")
+	if !slices.Equal(got, []string{"price", "pro", "plan"}) {
+		t.Fatalf("objectiveTerms = %q", got)
+	}
+}
+
+func TestExtractPagesFallsBackToLocalFetch(t *testing.T) {
+	t.Setenv("PARALLEL_API_KEY", "")
+	t.Setenv("EXTRACT_ENABLED", "")
+	t.Setenv("LOCAL_EXTRACT_ENABLED", "")
+
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(testArticleHTML))
+	}))
+	server.Start()
+
+	// The test server listens on loopback, which the production SSRF guard
+	// refuses; see TestLocalExtractHTTPClientRefusesLoopback.
+	prev := localExtractHTTPClient
+	localExtractHTTPClient = &http.Client{CheckRedirect: checkLocalExtractRedirect}
+	t.Cleanup(func() { localExtractHTTPClient = prev })
+
+	results := extractPages(context.Background(), domainPolicy{}, []string{server.URL}, "pro plan price")
+	if len(results) != 1 || results[0].Title != "Acme raises prices" {
+		t.Fatalf("expected local fetch results, got %+v", results)
+	}
+
+	t.Setenv("LOCAL_EXTRACT_ENABLED", "false")
+	if results := extractPages(context.Background(), domainPolicy{}, []string{server.URL}, "pro plan price"); results != nil {
+		t.Fatalf("expected no results with both extractors disabled, got %+v", results)
+	}
+}
+
+func TestLoadLocalExtractSettings(t *testing.T) {
+	t.Setenv("LOCAL_EXTRACT_TIMEOUT_SECONDS", "")
+	t.Setenv("LOCAL_EXTRACT_MAX_BYTES", "")
+	if loadLocalExtractTimeout() != defaultLocalExtractTimeout || loadLocalExtractMaxBytes() != defaultLocalExtractMaxBytes {
+		t.Fatal("expected defaults")
+	}
+
+	t.Setenv("LOCAL_EXTRACT_TIMEOUT_SECONDS", "3")
+	t.Setenv("LOCAL_EXTRACT_MAX_BYTES", "1024")
+	if loadLocalExtractTimeout() != 3*time.Second || loadLocalExtractMaxBytes() != 1024 {
+		t.Fatal("expected configured values")
+	}
+
+	t.Setenv("LOCAL_EXTRACT_TIMEOUT_SECONDS", "-1")
+	t.Setenv("LOCAL_EXTRACT_MAX_BYTES", "abc")
+	if loadLocalExtractTimeout() != defaultLocalExtractTimeout || loadLocalExtractMaxBytes() != defaultLocalExtractMaxBytes {
+		t.Fatal("expected invalid values to fall back to defaults")
+	}
+
+	t.Setenv("LOCAL_EXTRACT_ENABLED", "nope")
+	if !loadLocalExtractEnabled() {
+		t.Fatal("expected invalid LOCAL_EXTRACT_ENABLED to default to enabled")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
@@ -84,6 +85,7 @@ type parallelExtractor struct {
 	apiKey  string
 	timeout time.Duration
 	maxURLs int
+	cache   *ttlCache[parallelExtractResult]
 }
 
 // newParallelExtractor builds an extractor from the environment. It returns
@@ -102,12 +104,87 @@ func newParallelExtractor() *parallelExtractor {
 		apiKey:  apiKey,
 		timeout: loadExtractTimeout(),
 		maxURLs: loadExtractMaxURLs(),
+		cache:   parallelExtractCache,
 	}
 }
 
+// extractCacheKey keys a page extraction by its normalized URL and the
+// objective, since the excerpts Parallel returns depend on both.
+func extractCacheKey(rawURL, objective string) string {
+	return normalizeCacheURL(rawURL) + "\x00" + objective
+}
+
+// normalizeCacheURL canonicalizes the parts of a URL that do not change the
+// page: scheme and host case, a default port, and the fragment. Unparseable
+// input is used as-is.
+func normalizeCacheURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// cachedExtractResults splits urls into those with a live cached result and
+// those that still need fetching.
+func (p *parallelExtractor) cachedExtractResults(ctx context.Context, urls []string, objective string) (cached map[string]parallelExtractResult, missing []string) {
+	cached = make(map[string]parallelExtractResult)
+	for _, u := range urls {
+		key := extractCacheKey(u, objective)
+		if result, ok := p.cache.get(ctx, key); ok {
+			cached[key] = result
+			continue
+		}
+		missing = append(missing, u)
+	}
+	return cached, missing
+}
+
+// mergeExtractResults returns results in the order of the requested urls,
+// followed by any fetched results that did not match a requested URL (e.g.
+// after a redirect).
+func mergeExtractResults(urls []string, objective string, cached map[string]parallelExtractResult, fetched []parallelExtractResult) []parallelExtractResult {
+	byKey := maps.Clone(cached)
+	var unmatched []parallelExtractResult
+	requested := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		requested[extractCacheKey(u, objective)] = true
+	}
+	for _, r := range fetched {
+		key := extractCacheKey(r.URL, objective)
+		if requested[key] {
+			byKey[key] = r
+		} else {
+			unmatched = append(unmatched, r)
+		}
+	}
+
+	merged := make([]parallelExtractResult, 0, len(byKey)+len(unmatched))
+	for _, u := range urls {
+		key := extractCacheKey(u, objective)
+		if r, ok := byKey[key]; ok {
+			merged = append(merged, r)
+			delete(byKey, key)
+		}
+	}
+	return append(merged, unmatched...)
+}
+
 // extract calls the Parallel Extract API for the given URLs, focused on the
 // given objective. Per-URL failures reported in the response are logged and
 // skipped; only a transport-level or non-200 failure returns an error.
+// Successful page results are cached per URL and objective, and only the
+// URLs without a cached result are sent to Parallel.
 func (p *parallelExtractor) extract(ctx context.Context, urls []string, objective string) (results []parallelExtractResult, err error) {
 	if p == nil {
 		return nil, errors.New("parallel extractor not configured")
@@ -137,7 +214,13 @@ func (p *parallelExtractor) extract(ctx context.Context, urls []string, objectiv
 		return nil, errors.New("at least one URL is required")
 	}
 
-	reqBody := parallelExtractRequest{URLs: urls, Objective: objective}
+	cached, missing := p.cachedExtractResults(ctx, urls, objective)
+	span.SetAttributes(attribute.Int("parallel.cache_hits", len(cached)))
+	if len(missing) == 0 {
+		return mergeExtractResults(urls, objective, cached, nil), nil
+	}
+
+	reqBody := parallelExtractRequest{URLs: missing, Objective: objective}
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("marshal parallel extract request: %w", err)
@@ -184,6 +267,10 @@ func (p *parallelExtractor) extract(ctx context.Context, urls []string, objectiv
 	}
 
 	sanitized := sanitizeParallelExtractResults(extractResp.Results)
+	for _, r := range sanitized {
+		p.cache.set(extractCacheKey(r.URL, objective), r)
+	}
+	sanitized = mergeExtractResults(urls, objective, cached, sanitized)
 
 	span.SetAttributes(attribute.Int("parallel.excerpts_count", countExtractExcerpts(sanitized)))
 
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -416,3 +417,73 @@ func TestLoadExtractEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestParallelExtractor_FetchesOnlyUncachedURLs(t *testing.T) {
+	t.Parallel()
+
+	var requests []parallelExtractRequest
+	var mu sync.Mutex
+	extractor := newTestParallelExtractor(t, func(w http.ResponseWriter, r *http.Request) {
+		var req parallelExtractRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		resp := parallelExtractResponse{}
+		for _, u := range req.URLs {
+			resp.Results = append(resp.Results, parallelExtractResult{URL: u, Title: "Page " + u, Excerpts: []string{"text"}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	extractor.cache = newTTLCache[parallelExtractResult]("test", time.Minute, 10)
+	ctx := context.Background()
+
+	if _, err := extractor.extract(ctx, []string{"https://a.example/x"}, "objective"); err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	// Same page with a fragment and different host case is a cache hit.
+	results, err := extractor.extract(ctx, []string{"https://b.example/y", "https://A.example/x#top"}, "objective")
+	if err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+
+	if len(requests) != 2 || len(requests[1].URLs) != 1 || requests[1].URLs[0] != "https://b.example/y" {
+		t.Fatalf("expected only the uncached URL to be fetched, got %+v", requests)
+	}
+	if len(results) != 2 || results[0].URL != "https://b.example/y" || results[1].URL != "https://a.example/x" {
+		t.Fatalf("expected results in request order, got %+v", results)
+	}
+
+	// Everything cached: no API call at all.
+	if _, err := extractor.extract(ctx, []string{"https://a.example/x", "https://b.example/y"}, "objective"); err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected fully cached extract to skip the API, got %d calls", len(requests))
+	}
+
+	// A different objective yields different excerpts, so it misses.
+	if _, err := extractor.extract(ctx, []string{"https://a.example/x"}, "another objective"); err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("expected a new objective to miss the cache, got %d calls", len(requests))
+	}
+}
+
+func TestNormalizeCacheURL(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"HTTPS://Example.COM:443/Path?q=1#frag": "https://example.com/Path?q=1",
+		"http://example.com:80":                 "http://example.com/",
+		"https://example.com:8443/a":            "https://example.com:8443/a",
+		"not a url":                             "not a url",
+	}
+	for in, want := range tests {
+		if got := normalizeCacheURL(in); got != want {
+			t.Errorf("normalizeCacheURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +29,11 @@ const (
 	maxParallelExcerptRuneLen  = 300
 	maxParallelExcerptsPerItem = 3
 
+	// Repeat asks about the same topic or link within a few minutes reuse the
+	// earlier Parallel response instead of paying for another call.
+	defaultParallelCacheTTL        = 5 * time.Minute
+	defaultParallelCacheMaxEntries = 200
+
 	// maxParallelErrorBodyBytes bounds how much of an error response is
 	// surfaced in errors, keeping quota/validation details without logging
 	// large payloads.
@@ -79,6 +85,7 @@ type parallelSearcher struct {
 	apiKey     string
 	timeout    time.Duration
 	maxResults int
+	cache      *ttlCache[[]parallelSearchResult]
 }
 
 // newParallelSearcher builds a searcher from the environment. It returns nil
@@ -93,10 +100,37 @@ func newParallelSearcher() *parallelSearcher {
 		apiKey:     apiKey,
 		timeout:    loadParallelTimeout(),
 		maxResults: loadParallelMaxResults(),
+		cache:      parallelSearchCache,
 	}
 }
 
-// search queries the Parallel.ai Search API for fresh web excerpts.
+// The web search and extract caches outlive the per-request searchers and
+// extractor, so they are built once by initParallelCaches. All stay nil,
+// which disables caching, until it runs.
+var (
+	parallelSearchCache  *ttlCache[[]parallelSearchResult]
+	parallelExtractCache *ttlCache[parallelExtractResult]
+	exaWebSearchCache    *ttlCache[[]promptWebResult]
+)
+
+// initParallelCaches builds the shared caches from PARALLEL_CACHE_TTL_SECONDS
+// and PARALLEL_CACHE_MAX_ENTRIES. The Exa web search cache uses the same
+// settings.
+func initParallelCaches() {
+	ttl, maxEntries := loadParallelCacheTTL(), loadParallelCacheMaxEntries()
+	parallelSearchCache = newTTLCache[[]parallelSearchResult]("parallel_search", ttl, maxEntries)
+	parallelExtractCache = newTTLCache[parallelExtractResult]("parallel_extract", ttl, maxEntries)
+	exaWebSearchCache = newTTLCache[[]promptWebResult]("exa_web_search", ttl, maxEntries)
+}
+
+// parallelSearchCacheKey joins the objective and queries with NUL, which
+// Telegram message text never contains, so distinct inputs never collide.
+func parallelSearchCacheKey(objective string, queries []string) string {
+	return objective + "\x00" + strings.Join(queries, "\x00")
+}
+
+// search queries the Parallel.ai Search API for fresh web excerpts. Non-empty
+// results are cached per objective and queries.
 func (p *parallelSearcher) search(ctx context.Context, objective string, queries []string) (results []parallelSearchResult, err error) {
 	if p == nil {
 		return nil, errors.New("parallel searcher not configured")
@@ -123,6 +157,12 @@ func (p *parallelSearcher) search(ctx context.Context, objective string, queries
 		return nil, errors.New("search objective is required")
 	}
 
+	cacheKey := parallelSearchCacheKey(objective, queries)
+	if cached, ok := p.cache.get(ctx, cacheKey); ok {
+		span.SetAttributes(attribute.Bool("parallel.cache_hit", true))
+		return slices.Clone(cached), nil
+	}
+
 	reqBody := parallelSearchRequest{
 		Objective:        objective,
 		SearchQueries:    queries,
@@ -173,7 +213,11 @@ func (p *parallelSearcher) search(ctx context.Context, objective string, queries
 		Int("result_count", len(searchResp.Results)).
 		Msg("Parallel search completed")
 
-	return sanitizeParallelResults(searchResp.Results), nil
+	results = sanitizeParallelResults(searchResp.Results)
+	if len(results) > 0 {
+		p.cache.set(cacheKey, slices.Clone(results))
+	}
+	return results, nil
 }
 
 // sanitizeParallelResults applies per-field sanitization with rune budgets,
@@ -230,3 +274,31 @@ func loadParallelMaxResults() int {
 	}
 	return min(n, parallelMaxResultsCap)
 }
+
+// loadParallelCacheTTL reads PARALLEL_CACHE_TTL_SECONDS for the search and
+// extract caches, defaulting to 5 minutes. 0 disables caching.
+func loadParallelCacheTTL() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("PARALLEL_CACHE_TTL_SECONDS"))
+	if raw == "" {
+		return defaultParallelCacheTTL
+	}
+	seconds, err := strconv.Atoi(raw)
+	if err != nil || seconds < 0 {
+		return defaultParallelCacheTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// loadParallelCacheMaxEntries reads PARALLEL_CACHE_MAX_ENTRIES, the bound on
+// each of the search and extract caches, defaulting to 200.
+func loadParallelCacheMaxEntries() int {
+	raw := strings.TrimSpace(os.Getenv("PARALLEL_CACHE_MAX_ENTRIES"))
+	if raw == "" {
+		return defaultParallelCacheMaxEntries
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return defaultParallelCacheMaxEntries
+	}
+	return n
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -258,3 +259,108 @@ func TestLoadParallelMaxResults(t *testing.T) {
 		})
 	}
 }
+
+func TestParallelSearcher_CachesResults(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	searcher := newTestParallelSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(parallelSearchResponse{Results: []parallelSearchResult{
+			{URL: "https://example.com", Title: "Result", Excerpts: []string{"excerpt"}},
+		}})
+	})
+	searcher.cache = newTTLCache[[]parallelSearchResult]("test", time.Minute, 10)
+
+	for range 2 {
+		results, err := searcher.search(context.Background(), "objective", []string{"q1", "q2"})
+		if err != nil || len(results) != 1 {
+			t.Fatalf("search() = %v, %v", results, err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected the repeat search to hit the cache, got %d API calls", got)
+	}
+
+	if _, err := searcher.search(context.Background(), "objective", []string{"q1"}); err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected different queries to miss the cache, got %d API calls", got)
+	}
+}
+
+func TestParallelSearcher_DoesNotCacheEmptyResults(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	searcher := newTestParallelSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(parallelSearchResponse{})
+	})
+	searcher.cache = newTTLCache[[]parallelSearchResult]("test", time.Minute, 10)
+
+	for range 2 {
+		if _, err := searcher.search(context.Background(), "objective", nil); err != nil {
+			t.Fatalf("search() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected empty results to be refetched, got %d API calls", got)
+	}
+}
+
+func TestLoadParallelCacheSettings(t *testing.T) {
+	tests := []struct {
+		ttl, size   string
+		wantTTL     time.Duration
+		wantEntries int
+	}{
+		{wantTTL: defaultParallelCacheTTL, wantEntries: defaultParallelCacheMaxEntries},
+		{ttl: "0", size: "5", wantTTL: 0, wantEntries: 5},
+		{ttl: "60", size: "0", wantTTL: time.Minute, wantEntries: defaultParallelCacheMaxEntries},
+		{ttl: "abc", size: "-1", wantTTL: defaultParallelCacheTTL, wantEntries: defaultParallelCacheMaxEntries},
+	}
+	for _, tt := range tests {
+		t.Setenv("PARALLEL_CACHE_TTL_SECONDS", tt.ttl)
+		t.Setenv("PARALLEL_CACHE_MAX_ENTRIES", tt.size)
+		if got := loadParallelCacheTTL(); got != tt.wantTTL {
+			t.Errorf("loadParallelCacheTTL(%q) = %v, want %v", tt.ttl, got, tt.wantTTL)
+		}
+		if got := loadParallelCacheMaxEntries(); got != tt.wantEntries {
+			t.Errorf("loadParallelCacheMaxEntries(%q) = %d, want %d", tt.size, got, tt.wantEntries)
+		}
+	}
+}
+
+func TestNewParallelSearcher_SharesCacheAcrossRequests(t *testing.T) {
+	origSearch, origExtract, origExa := parallelSearchCache, parallelExtractCache, exaWebSearchCache
+	t.Cleanup(func() {
+		parallelSearchCache, parallelExtractCache, exaWebSearchCache = origSearch, origExtract, origExa
+	})
+	t.Setenv("PARALLEL_API_KEY", "test-key")
+	t.Setenv("PARALLEL_CACHE_TTL_SECONDS", "")
+	t.Setenv("PARALLEL_CACHE_MAX_ENTRIES", "")
+	initParallelCaches()
+
+	var calls atomic.Int32
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(parallelSearchResponse{Results: []parallelSearchResult{
+			{URL: "https://example.com", Title: "Result", Excerpts: []string{"excerpt"}},
+		}})
+	}))
+	server.Start()
+
+	// Every web search builds a fresh searcher, so only a shared cache hits.
+	for range 2 {
+		searcher := newParallelSearcher()
+		searcher.baseURL = server.URL
+		if _, err := searcher.search(context.Background(), "objective", []string{"q"}); err != nil {
+			t.Fatalf("search() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected the second searcher to hit the shared cache, got %d API calls", got)
+	}
+}
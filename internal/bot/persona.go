@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	personaCommand        = "!persona"
+	personaAdminOnlyMsg   = "Only chat admins can change the persona."
+	personaSaveFailedMsg  = "Persona changed, but it could not be saved and will reset when the bot restarts."
+	personaUnknownMsgTmpl = "Unknown persona %q. Available: %s"
+)
+
+func shouldHandlePersona(update *models.Update) bool {
+	if update == nil || update.Message == nil {
+		return false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(update.Message.Text), personaCommand)
+	return ok && (rest == "" || startsWithSpace(rest))
+}
+
+// personaHandler shows the chat's persona with "!persona" and lets admins
+// switch it with "!persona NAME" ("!persona default" resets it).
+func personaHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	message := update.Message
+	arg := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(message.Text), personaCommand)))
+	set := prompts()
+
+	if arg == "" {
+		current := chatSettingsInstance.get(message.Chat.ID).Persona
+		if current == "" {
+			current = defaultPersona
+		}
+		appotel.RecordOutcome(ctx, "success")
+		sendSettingsReply(ctx, b, message, fmt.Sprintf(
+			"Current persona: %s\nAvailable: %s\nAdmins can switch with !persona NAME.",
+			current, strings.Join(set.personaNames(), ", "),
+		))
+		return
+	}
+
+	if !set.hasPersona(arg) {
+		sendSettingsReply(ctx, b, message, fmt.Sprintf(personaUnknownMsgTmpl, arg, strings.Join(set.personaNames(), ", ")))
+		return
+	}
+
+	if !isChatAdmin(ctx, b, message) {
+		appotel.RecordOutcome(ctx, "blocked")
+		sendSettingsReply(ctx, b, message, personaAdminOnlyMsg)
+		return
+	}
+
+	err := chatSettingsInstance.update(message.Chat.ID, func(settings *chatSettings) {
+		settings.Persona = ""
+		if arg != defaultPersona {
+			settings.Persona = arg
+		}
+	})
+	if err != nil {
+		appotel.RecordOutcome(ctx, "error")
+		log.Error().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to persist chat persona")
+		sendSettingsReply(ctx, b, message, personaSaveFailedMsg)
+		return
+	}
+
+	log.Info().Int64("chat_id", message.Chat.ID).Str("persona", arg).Msg("Chat persona changed")
+	appotel.RecordOutcome(ctx, "success")
+	sendSettingsReply(ctx, b, message, "Persona set to "+arg+".")
+}
+
+func sendSettingsReply(ctx context.Context, b *bot.Bot, message *models.Message, text string) {
+	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          message.Chat.ID,
+		MessageThreadID: message.MessageThreadID,
+		Text:            text,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+}
+
+// isChatAdmin reports whether the sender may change chat settings. In a
+// private chat the (already allowlisted) user owns the chat; in groups the
+// sender must be an administrator or the creator. Lookup failures deny.
+func isChatAdmin(ctx context.Context, b *bot.Bot, message *models.Message) bool {
+	if message == nil || message.From == nil {
+		return false
+	}
+	if isPrivateMessage(message) {
+		return true
+	}
+
+	member, err := b.GetChatMember(ctx, &bot.GetChatMemberParams{
+		ChatID: message.Chat.ID,
+		UserID: message.From.ID,
+	})
+	if err != nil {
+		log.Warn().Err(err).Int64("chat_id", message.Chat.ID).Int64("user_id", message.From.ID).Msg("Failed to look up chat member")
+		return false
+	}
+	return member.Type == models.ChatMemberTypeOwner || member.Type == models.ChatMemberTypeAdministrator
+}
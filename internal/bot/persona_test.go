@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestShouldHandlePersona(t *testing.T) {
+	for text, want := range map[string]bool{
+		"!persona":       true,
+		"!persona terse": true,
+		"!personas":      false,
+		"persona":        false,
+	} {
+		if got := shouldHandlePersona(groupTextUpdate(text)); got != want {
+			t.Errorf("shouldHandlePersona(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestPersonaHandler_ShowsCurrentPersona(t *testing.T) {
+	withChatSettingsStore(t, newChatSettingsStore(""))
+	b, srv := newTestBot(t)
+
+	personaHandler(context.Background(), b, groupTextUpdate("!persona"))
+
+	if !strings.Contains(srv.lastMessage, "Current persona: default") || !strings.Contains(srv.lastMessage, "terse") {
+		t.Fatalf("expected persona listing, got %q", srv.lastMessage)
+	}
+}
+
+func TestPersonaHandler_PrivateChatOwnerCanSet(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	b, srv := newTestBot(t)
+
+	update := privateTextUpdate("!persona Terse")
+	update.Message.From = &models.User{ID: 42}
+	personaHandler(context.Background(), b, update)
+
+	if got := store.get(42).Persona; got != "terse" {
+		t.Fatalf("expected persona to be stored, got %q", got)
+	}
+	if srv.lastMessage != "Persona set to terse." {
+		t.Fatalf("unexpected reply %q", srv.lastMessage)
+	}
+
+	reset := privateTextUpdate("!persona default")
+	reset.Message.From = &models.User{ID: 42}
+	personaHandler(context.Background(), b, reset)
+	if got := store.get(42).Persona; got != "" {
+		t.Fatalf("expected default to clear the persona, got %q", got)
+	}
+}
+
+func TestPersonaHandler_UnknownPersona(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	b, srv := newTestBot(t)
+
+	personaHandler(context.Background(), b, privateTextUpdate("!persona pirate"))
+
+	if !strings.Contains(srv.lastMessage, `Unknown persona "pirate"`) {
+		t.Fatalf("expected unknown-persona reply, got %q", srv.lastMessage)
+	}
+	if !store.get(42).isZero() {
+		t.Fatal("expected settings to stay unchanged")
+	}
+}
+
+func TestPersonaHandler_GroupNonAdminDenied(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	// The fake Telegram server answers getChatMember with a message object,
+	// which does not decode as a chat member, so the lookup fails closed.
+	b, srv := newTestBot(t)
+
+	update := groupTextUpdate("!persona terse")
+	update.Message.From = &models.User{ID: 7}
+	personaHandler(context.Background(), b, update)
+
+	if srv.lastMessage != personaAdminOnlyMsg {
+		t.Fatalf("expected admin-only reply, got %q", srv.lastMessage)
+	}
+	if !store.get(-100).isZero() {
+		t.Fatal("expected group settings to stay unchanged")
+	}
+}
@@ -0,0 +1,539 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	alertCommand        = "!alert"
+	alertsCommand       = "!alerts"
+	priceAlertsFileName = "price_alerts.json"
+
+	// maxAlertsPerUser bounds how many symbols one member can make the
+	// poller watch.
+	maxAlertsPerUser = 10
+
+	defaultAlertPollInterval = time.Minute
+	// defaultAlertBatchSize keeps the poller to a sixth of Finnhub's 60
+	// calls/minute free tier, leaving the rest for commands.
+	defaultAlertBatchSize = 10
+	maxAlertBatchSize     = 30
+
+	alertUsageMsg = "Usage: !alert SYMBOL > PRICE, !alert SYMBOL < PRICE, !alert SYMBOL -5% (move from the current price), !alert rm ID; !alerts lists this chat's alerts."
+)
+
+var errTooManyAlerts = fmt.Errorf("you already have %d alerts; remove one with !alert rm ID first", maxAlertsPerUser)
+
+// priceAlert fires once when Symbol crosses Target and is then removed.
+// Percent alerts store the move and the price it was measured from; Target
+// is derived from them when the alert is set.
+type priceAlert struct {
+	ID        int       `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	ThreadID  int       `json:"thread_id,omitempty"`
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Symbol    string    `json:"symbol"`
+	Above     bool      `json:"above"`
+	Target    float64   `json:"target"`
+	Percent   float64   `json:"percent,omitempty"`
+	Baseline  float64   `json:"baseline,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (a priceAlert) triggeredBy(price float64) bool {
+	if a.Above {
+		return price >= a.Target
+	}
+	return price <= a.Target
+}
+
+// condition renders the alert as the user wrote it, e.g. "AAPL > 250.00" or
+// "TSLA -5.00% from 250.00 (< 237.50)".
+func (a priceAlert) condition() string {
+	op := "<"
+	if a.Above {
+		op = ">"
+	}
+	if a.Percent != 0 {
+		return fmt.Sprintf("%s %+.2f%% from %.2f (%s %.2f)", a.Symbol, a.Percent, a.Baseline, op, a.Target)
+	}
+	return fmt.Sprintf("%s %s %.2f", a.Symbol, op, a.Target)
+}
+
+// priceAlertStore keeps every chat's alerts in memory and persists each change
+// to a JSON file. An empty path keeps the store memory-only.
+type priceAlertStore struct {
+	mu     sync.Mutex
+	path   string
+	nextID int
+	alerts []priceAlert
+}
+
+// priceAlertsFile is the on-disk form of priceAlertStore.
+type priceAlertsFile struct {
+	NextID int          `json:"next_id"`
+	Alerts []priceAlert `json:"alerts"`
+}
+
+var priceAlertsInstance = newPriceAlertStore("")
+
+func newPriceAlertStore(path string) *priceAlertStore {
+	return &priceAlertStore{path: path, nextID: 1}
+}
+
+// loadPriceAlertStore reads path when it exists. A corrupt file is an error
+// rather than silently dropped alerts.
+func loadPriceAlertStore(path string) (*priceAlertStore, error) {
+	store := newPriceAlertStore(path)
+	var file priceAlertsFile
+	if _, err := readJSONFile(path, &file); err != nil {
+		return nil, err
+	}
+	store.alerts = file.Alerts
+	store.nextID = max(file.NextID, 1)
+	for _, alert := range store.alerts {
+		store.nextID = max(store.nextID, alert.ID+1)
+	}
+	return store, nil
+}
+
+// initPriceAlerts loads the persisted alerts from BOT_DATA_DIR, falling back
+// to a memory-only store so a bad file never keeps the bot down.
+func initPriceAlerts() {
+	path := botDataPath(priceAlertsFileName)
+	store, err := loadPriceAlertStore(path)
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to load price alerts; new alerts will not persist")
+		priceAlertsInstance = newPriceAlertStore("")
+		return
+	}
+	priceAlertsInstance = store
+	log.Info().Str("path", path).Int("alerts", len(store.alerts)).Msg("Loaded price alerts")
+}
+
+func (s *priceAlertStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFileAtomic(s.path, priceAlertsFile{NextID: s.nextID, Alerts: s.alerts})
+}
+
+// add assigns alert an ID and stores it. The in-memory change is kept even
+// when persisting fails.
+func (s *priceAlertStore) add(alert priceAlert) (priceAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owned := 0
+	for _, existing := range s.alerts {
+		if existing.UserID == alert.UserID {
+			owned++
+		}
+	}
+	if owned >= maxAlertsPerUser {
+		return priceAlert{}, errTooManyAlerts
+	}
+
+	alert.ID = s.nextID
+	s.nextID++
+	s.alerts = append(s.alerts, alert)
+	return alert, s.saveLocked()
+}
+
+// forChat returns the chat's alerts in the order they were set.
+func (s *priceAlertStore) forChat(chatID int64) []priceAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []priceAlert
+	for _, alert := range s.alerts {
+		if alert.ChatID == chatID {
+			out = append(out, alert)
+		}
+	}
+	return out
+}
+
+func (s *priceAlertStore) get(chatID int64, id int) (priceAlert, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.alerts, func(a priceAlert) bool { return a.ID == id && a.ChatID == chatID })
+	if i < 0 {
+		return priceAlert{}, false
+	}
+	return s.alerts[i], true
+}
+
+// remove deletes the chat's alert with id and reports whether it existed.
+func (s *priceAlertStore) remove(chatID int64, id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.alerts)
+	s.alerts = slices.DeleteFunc(s.alerts, func(a priceAlert) bool { return a.ID == id && a.ChatID == chatID })
+	if len(s.alerts) == before {
+		return false, nil
+	}
+	return true, s.saveLocked()
+}
+
+// symbols returns the distinct symbols with alerts, sorted.
+func (s *priceAlertStore) symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, alert := range s.alerts {
+		out = append(out, alert.Symbol)
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// triggered returns symbol's alerts that price satisfies. They stay stored
+// until notifyPriceAlerts removes each one after its notification is sent,
+// so an alert whose send fails fires again on the next check.
+func (s *priceAlertStore) triggered(symbol string, price float64) []priceAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var fired []priceAlert
+	for _, alert := range s.alerts {
+		if alert.Symbol == symbol && alert.triggeredBy(price) {
+			fired = append(fired, alert)
+		}
+	}
+	return fired
+}
+
+// alertRequest is a parsed `!alert SYMBOL ...` condition. Percent is
+// non-zero for moves relative to the current price; otherwise the alert
+// fires when the price crosses target.
+type alertRequest struct {
+	symbol  string
+	above   bool
+	target  float64
+	percent float64
+}
+
+// parseAlertCondition parses the words after `!alert SYMBOL`: "> 250",
+// "<200", "-5%" or "+10%". Spaces between the operator and number are
+// optional.
+func parseAlertCondition(symbol string, words []string) (alertRequest, error) {
+	symbol = strings.ToUpper(symbol)
+	if !symbolRegex.MatchString(symbol) {
+		return alertRequest{}, fmt.Errorf("invalid stock symbol %q, use 1-10 characters: letters, numbers, dots (.) or dashes (-)", symbol)
+	}
+	cond := strings.ReplaceAll(strings.Join(words, ""), "$", "")
+	if cond == "" {
+		return alertRequest{}, errors.New(alertUsageMsg)
+	}
+
+	if pct, ok := strings.CutSuffix(cond, "%"); ok {
+		percent, err := strconv.ParseFloat(pct, 64)
+		if err != nil || percent == 0 || percent <= -100 || percent > 1000 {
+			return alertRequest{}, fmt.Errorf("invalid percent move %q, use e.g. -5%% or +10%%", cond)
+		}
+		return alertRequest{symbol: symbol, above: percent > 0, percent: percent}, nil
+	}
+
+	var above bool
+	switch cond[0] {
+	case '>':
+		above = true
+	case '<':
+	default:
+		return alertRequest{}, errors.New(alertUsageMsg)
+	}
+	target, err := strconv.ParseFloat(strings.TrimPrefix(cond[1:], "="), 64)
+	if err != nil || target <= 0 {
+		return alertRequest{}, fmt.Errorf("invalid price %q, use e.g. !alert %s > 250", cond[1:], symbol)
+	}
+	return alertRequest{symbol: symbol, above: above, target: target}, nil
+}
+
+// alertFromQuote turns a request into an alert against the current price.
+// It fails when the condition already holds, since the alert would fire on
+// the next poll.
+func alertFromQuote(req alertRequest, price float64) (priceAlert, error) {
+	alert := priceAlert{Symbol: req.symbol, Above: req.above, Target: req.target}
+	if req.percent != 0 {
+		alert.Percent = req.percent
+		alert.Baseline = price
+		alert.Target = price * (1 + req.percent/100)
+	}
+	if alert.triggeredBy(price) {
+		return priceAlert{}, fmt.Errorf("%s is already at %.2f", req.symbol, price)
+	}
+	return alert, nil
+}
+
+func shouldHandleAlert(update *models.Update) bool {
+	if update == nil || update.Message == nil {
+		return false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(update.Message.Text), alertCommand)
+	return ok && (rest == "" || rest == "s" || startsWithSpace(rest))
+}
+
+// alertHandler serves `!alert SYMBOL COND`, `!alert rm ID` and `!alerts`.
+func alertHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	message := update.Message
+	text := strings.TrimSpace(message.Text)
+	if text == alertsCommand {
+		appotel.RecordOutcome(ctx, "success")
+		sendSettingsReply(ctx, b, message, formatAlertList(priceAlertsInstance.forChat(message.Chat.ID)))
+		return
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(text, alertCommand))
+	switch {
+	case len(fields) == 0:
+		sendSettingsReply(ctx, b, message, alertUsageMsg)
+	case strings.EqualFold(fields[0], "rm"):
+		removeAlert(ctx, b, message, fields[1:])
+	default:
+		addAlert(ctx, b, message, fields[0], fields[1:])
+	}
+}
+
+func addAlert(ctx context.Context, b *bot.Bot, message *models.Message, symbol string, words []string) {
+	req, err := parseAlertCondition(symbol, words)
+	if err != nil {
+		sendSettingsReply(ctx, b, message, err.Error())
+		return
+	}
+	if message.From == nil {
+		sendSettingsReply(ctx, b, message, "Alerts need a sender to mention; anonymous admins cannot set them.")
+		return
+	}
+	if blockedMsg, blocked := blockedStockResponse(req.symbol); blocked {
+		appotel.RecordOutcome(ctx, "blocked")
+		sendSettingsReply(ctx, b, message, blockedMsg)
+		return
+	}
+
+	quote, err := fetchStockQuote(ctx, req.symbol)
+	if err != nil {
+		appotel.RecordOutcome(ctx, "error")
+		log.Warn().Err(err).Str("symbol", req.symbol).Msg("Failed to fetch quote for price alert")
+		sendSettingsReply(ctx, b, message, fmt.Sprintf("Could not get a quote for %s, so the alert was not set.", req.symbol))
+		return
+	}
+	alert, err := alertFromQuote(req, quote.CurrentPrice)
+	if err != nil {
+		sendSettingsReply(ctx, b, message, err.Error())
+		return
+	}
+	alert.ChatID = message.Chat.ID
+	alert.ThreadID = message.MessageThreadID
+	alert.UserID = message.From.ID
+	alert.UserName = alertUserName(message.From)
+	alert.CreatedAt = nowFunc().UTC()
+
+	alert, err = priceAlertsInstance.add(alert)
+	if errors.Is(err, errTooManyAlerts) {
+		sendSettingsReply(ctx, b, message, err.Error())
+		return
+	}
+	if err != nil {
+		// The alert is live in memory; it only fails to survive a restart.
+		log.Error().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to persist price alerts")
+	}
+
+	log.Info().Int64("chat_id", message.Chat.ID).Int("alert_id", alert.ID).Str("symbol", alert.Symbol).Msg("Price alert set")
+	appotel.RecordOutcome(ctx, "success")
+	sendSettingsReply(ctx, b, message, fmt.Sprintf(
+		"Alert #%d set: %s (now %.2f). I'll mention you here when it triggers.",
+		alert.ID, alert.condition(), quote.CurrentPrice,
+	))
+}
+
+// removeAlert deletes one of the chat's alerts. Members may remove their
+// own; admins may remove anyone's.
+func removeAlert(ctx context.Context, b *bot.Bot, message *models.Message, args []string) {
+	if len(args) != 1 {
+		sendSettingsReply(ctx, b, message, alertUsageMsg)
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		sendSettingsReply(ctx, b, message, fmt.Sprintf("%q is not an alert ID; see !alerts.", args[0]))
+		return
+	}
+	alert, ok := priceAlertsInstance.get(message.Chat.ID, id)
+	if !ok {
+		sendSettingsReply(ctx, b, message, fmt.Sprintf("No alert #%d in this chat; see !alerts.", id))
+		return
+	}
+	if (message.From == nil || message.From.ID != alert.UserID) && !isChatAdmin(ctx, b, message) {
+		appotel.RecordOutcome(ctx, "blocked")
+		sendSettingsReply(ctx, b, message, "Only the member who set an alert or a chat admin can remove it.")
+		return
+	}
+
+	if _, err := priceAlertsInstance.remove(message.Chat.ID, id); err != nil {
+		log.Error().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to persist price alerts")
+	}
+	appotel.RecordOutcome(ctx, "success")
+	sendSettingsReply(ctx, b, message, fmt.Sprintf("Removed alert #%d: %s", id, alert.condition()))
+}
+
+func formatAlertList(alerts []priceAlert) string {
+	if len(alerts) == 0 {
+		return "No price alerts in this chat. Set one with !alert AAPL > 250"
+	}
+	var sb strings.Builder
+	sb.WriteString("Price alerts in this chat")
+	for _, alert := range alerts {
+		fmt.Fprintf(&sb, "\n#%d %s · %s", alert.ID, alert.condition(), alert.UserName)
+	}
+	return sb.String()
+}
+
+func alertUserName(user *models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+	return strconv.FormatInt(user.ID, 10)
+}
+
+// alertPollConfig reads PRICE_ALERT_POLL_SECONDS and PRICE_ALERT_BATCH_SIZE.
+func alertPollConfig() (time.Duration, int) {
+	interval := defaultAlertPollInterval
+	batch := defaultAlertBatchSize
+	if raw := getenvTrim("PRICE_ALERT_POLL_SECONDS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			interval = time.Duration(n) * time.Second
+		}
+	}
+	if raw := getenvTrim("PRICE_ALERT_BATCH_SIZE"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			batch = min(n, maxAlertBatchSize)
+		}
+	}
+	return interval, batch
+}
+
+// startPriceAlertPoller checks alerts every interval during the US regular
+// session, when quotes move. Each tick quotes at most one batch of symbols,
+// rotating through them so every symbol is checked within a few ticks.
+func startPriceAlertPoller(ctx context.Context, b *bot.Bot) {
+	interval, batch := alertPollConfig()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !usMarketOpen(nowFunc()) {
+				continue
+			}
+			last = pollPriceAlerts(ctx, b, batch, last)
+		}
+	}
+}
+
+// usMarketOpen reports whether t falls in a weekday regular session.
+// Exchange holidays are not tracked; quotes simply do not move on them.
+func usMarketOpen(t time.Time) bool {
+	t = t.In(usEastern)
+	if weekday := t.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	return minute >= marketOpenMinute && minute < marketCloseMinute
+}
+
+// pollPriceAlerts quotes the batch of alert symbols that follow after in
+// sorted order, wrapping around, notifies the chats of every alert that fired
+// and returns the last symbol it checked. Resuming by symbol rather than by
+// index keeps the rotation fair while alerts come and go. A Finnhub 429 ends
+// the tick early; the rest of the batch is retried on the next one.
+func pollPriceAlerts(ctx context.Context, b *bot.Bot, batch int, after string) string {
+	symbols := priceAlertsInstance.symbols()
+	if len(symbols) == 0 {
+		return ""
+	}
+	start, found := slices.BinarySearch(symbols, after)
+	if found {
+		start++
+	}
+
+	for n := range min(batch, len(symbols)) {
+		symbol := symbols[(start+n)%len(symbols)]
+		quote, err := fetchStockQuote(ctx, symbol)
+		if err != nil {
+			if statusErr, ok := errors.AsType[*httpStatusError](err); ok && statusErr.StatusCode == http.StatusTooManyRequests {
+				recordRateLimited(ctx, "price_alerts")
+				log.Warn().Str("symbol", symbol).Msg("Finnhub rate limited the price alert poller; pausing until the next tick")
+				return after
+			}
+			log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to fetch quote for price alerts")
+		} else {
+			notifyPriceAlerts(ctx, b, symbol, quote.CurrentPrice)
+		}
+		after = symbol
+	}
+	return after
+}
+
+// notifyPriceAlerts sends a notification for each of symbol's alerts that
+// price fired and removes the alert once it is delivered, so each alert
+// notifies only once. A failed send keeps the alert for the next check,
+// except when Telegram forbids the chat, which no retry will fix.
+func notifyPriceAlerts(ctx context.Context, b *bot.Bot, symbol string, price float64) {
+	for _, alert := range priceAlertsInstance.triggered(symbol, price) {
+		text, entities := formatAlertNotification(alert, price)
+		_, sendErr := b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          alert.ChatID,
+			MessageThreadID: alert.ThreadID,
+			Text:            text,
+			Entities:        entities,
+		})
+		if sendErr != nil {
+			log.Error().Err(sendErr).Int64("chat_id", alert.ChatID).Int("alert_id", alert.ID).Msg("Failed to send price alert")
+			if !errors.Is(sendErr, bot.ErrorForbidden) {
+				continue
+			}
+		} else {
+			log.Info().Int64("chat_id", alert.ChatID).Int("alert_id", alert.ID).Str("symbol", symbol).Msg("Price alert triggered")
+		}
+		if _, err := priceAlertsInstance.remove(alert.ChatID, alert.ID); err != nil {
+			log.Error().Err(err).Msg("Failed to persist price alerts")
+		}
+	}
+}
+
+// formatAlertNotification mentions the alert's owner by user ID, which works
+// without a public username.
+func formatAlertNotification(alert priceAlert, price float64) (string, []models.MessageEntity) {
+	text := fmt.Sprintf("%s 🔔 %s is at %.2f, alert #%d hit: %s", alert.UserName, alert.Symbol, price, alert.ID, alert.condition())
+	length := 0
+	for _, r := range alert.UserName {
+		length += utf16UnitsForRune(r)
+	}
+	return text, []models.MessageEntity{{
+		Type:   models.MessageEntityTypeTextMention,
+		Offset: 0,
+		Length: length,
+		User:   &models.User{ID: alert.UserID},
+	}}
+}
@@ -0,0 +1,372 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// withPriceAlertStore swaps in a store persisted under a temp dir.
+func withPriceAlertStore(t *testing.T) *priceAlertStore {
+	t.Helper()
+	orig := priceAlertsInstance
+	t.Cleanup(func() { priceAlertsInstance = orig })
+	priceAlertsInstance = newPriceAlertStore(filepath.Join(t.TempDir(), priceAlertsFileName))
+	return priceAlertsInstance
+}
+
+// serveFinnhubQuotes answers /quote with prices[symbol] and counts requests.
+// A price of -1 answers 429.
+func serveFinnhubQuotes(t *testing.T, prices map[string]float64) *[]string {
+	t.Helper()
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get("symbol")
+		mu.Lock()
+		requested = append(requested, symbol)
+		mu.Unlock()
+		price := prices[symbol]
+		if price < 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(StockQuote{CurrentPrice: price, PreviousClose: price})
+	}))
+	server.Start()
+	useRedirectedHTTPClient(t, server.URL)
+	t.Setenv("FINNHUB_API_KEY", "test-key")
+	return &requested
+}
+
+func alertUpdate(text string, userID int64, firstName string) *models.Update {
+	update := groupTextUpdate(text)
+	update.Message.MessageThreadID = 7
+	update.Message.From = &models.User{ID: userID, FirstName: firstName}
+	return update
+}
+
+func TestParseAlertCondition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		symbol    string
+		words     []string
+		want      alertRequest
+		errSubstr string
+	}{
+		{symbol: "aapl", words: []string{">", "250"}, want: alertRequest{symbol: "AAPL", above: true, target: 250}},
+		{symbol: "AAPL", words: []string{"<200.5"}, want: alertRequest{symbol: "AAPL", target: 200.5}},
+		{symbol: "AAPL", words: []string{">=", "$250"}, want: alertRequest{symbol: "AAPL", above: true, target: 250}},
+		{symbol: "TSLA", words: []string{"-5%"}, want: alertRequest{symbol: "TSLA", percent: -5}},
+		{symbol: "TSLA", words: []string{"+10", "%"}, want: alertRequest{symbol: "TSLA", above: true, percent: 10}},
+		{symbol: "AAPL", errSubstr: "Usage"},
+		{symbol: "AAPL", words: []string{"250"}, errSubstr: "Usage"},
+		{symbol: "AAPL", words: []string{">", "abc"}, errSubstr: "invalid price"},
+		{symbol: "AAPL", words: []string{"<", "-3"}, errSubstr: "invalid price"},
+		{symbol: "AAPL", words: []string{"0%"}, errSubstr: "invalid percent"},
+		{symbol: "AAPL", words: []string{"-100%"}, errSubstr: "invalid percent"},
+		{symbol: "$$$", words: []string{">", "1"}, errSubstr: "invalid stock symbol"},
+	}
+	for _, tt := range tests {
+		got, err := parseAlertCondition(tt.symbol, tt.words)
+		if tt.errSubstr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("parseAlertCondition(%q, %q) error = %v, want %q", tt.symbol, tt.words, err, tt.errSubstr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseAlertCondition(%q, %q) = %+v, %v; want %+v", tt.symbol, tt.words, got, err, tt.want)
+		}
+	}
+}
+
+func TestAlertFromQuote(t *testing.T) {
+	t.Parallel()
+
+	drop, err := alertFromQuote(alertRequest{symbol: "TSLA", percent: -5}, 250)
+	if err != nil || drop.Above || drop.Target != 237.5 || drop.Baseline != 250 {
+		t.Fatalf("alertFromQuote(-5%%) = %+v, %v", drop, err)
+	}
+	if got := drop.condition(); got != "TSLA -5.00% from 250.00 (< 237.50)" {
+		t.Errorf("condition() = %q", got)
+	}
+	if !drop.triggeredBy(237.5) || drop.triggeredBy(240) {
+		t.Error("a -5% alert should fire at or below its target only")
+	}
+
+	if _, err := alertFromQuote(alertRequest{symbol: "AAPL", above: true, target: 250}, 260); err == nil || !strings.Contains(err.Error(), "already at 260.00") {
+		t.Fatalf("alertFromQuote() error = %v, want the already-met notice", err)
+	}
+}
+
+func TestPriceAlertStorePersistsAndTriggersOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), priceAlertsFileName)
+	store := newPriceAlertStore(path)
+
+	for i := range maxAlertsPerUser {
+		if _, err := store.add(priceAlert{ChatID: -100, UserID: 1, Symbol: "AAPL", Above: true, Target: float64(200 + i)}); err != nil {
+			t.Fatalf("add() #%d error: %v", i, err)
+		}
+	}
+	if _, err := store.add(priceAlert{ChatID: -100, UserID: 1, Symbol: "MSFT"}); !errors.Is(err, errTooManyAlerts) {
+		t.Fatalf("add() past the cap error = %v, want errTooManyAlerts", err)
+	}
+	other, err := store.add(priceAlert{ChatID: -200, UserID: 2, Symbol: "MSFT", Target: 400})
+	if err != nil || other.ID != maxAlertsPerUser+1 {
+		t.Fatalf("add() = %+v, %v", other, err)
+	}
+
+	reloaded, err := loadPriceAlertStore(path)
+	if err != nil {
+		t.Fatalf("loadPriceAlertStore() error: %v", err)
+	}
+	if got := reloaded.symbols(); strings.Join(got, ",") != "AAPL,MSFT" {
+		t.Fatalf("symbols() = %q, want AAPL,MSFT", got)
+	}
+	if len(reloaded.forChat(-100)) != maxAlertsPerUser || len(reloaded.forChat(-200)) != 1 {
+		t.Fatal("reloaded store lost alerts")
+	}
+
+	fired := reloaded.triggered("AAPL", 202)
+	if len(fired) != 3 {
+		t.Fatalf("triggered(AAPL, 202) = %d alerts, want the 200-202 targets", len(fired))
+	}
+	for _, alert := range fired {
+		if removed, err := reloaded.remove(alert.ChatID, alert.ID); err != nil || !removed {
+			t.Fatalf("remove(%d) = %v, %v", alert.ID, removed, err)
+		}
+	}
+	if again := reloaded.triggered("AAPL", 202); len(again) != 0 {
+		t.Fatalf("alerts fired twice: %+v", again)
+	}
+
+	removed, err := reloaded.remove(-100, other.ID)
+	if err != nil || removed {
+		t.Fatal("remove() must not delete another chat's alert")
+	}
+	next, _ := loadPriceAlertStore(path)
+	if len(next.forChat(-100)) != maxAlertsPerUser-3 {
+		t.Fatalf("triggered alerts not persisted as removed: %d left", len(next.forChat(-100)))
+	}
+	if added, _ := next.add(priceAlert{ChatID: -100, UserID: 3}); added.ID != maxAlertsPerUser+2 {
+		t.Fatalf("next ID = %d, want IDs never reused", added.ID)
+	}
+}
+
+func TestAlertHandlerSetListRemove(t *testing.T) {
+	store := withPriceAlertStore(t)
+	serveFinnhubQuotes(t, map[string]float64{"TSLA": 250, "AAPL": 245})
+	b, srv := newTestBot(t)
+	ctx := context.Background()
+
+	alertHandler(ctx, b, alertUpdate("!alert tsla -5%", 11, "Aye"))
+	srv.mu.Lock()
+	reply := srv.lastMessage
+	srv.mu.Unlock()
+	if !strings.Contains(reply, "Alert #1 set: TSLA -5.00% from 250.00 (< 237.50) (now 250.00)") {
+		t.Fatalf("reply = %q", reply)
+	}
+	alerts := store.forChat(-100)
+	if len(alerts) != 1 || alerts[0].UserID != 11 || alerts[0].ThreadID != 7 || alerts[0].UserName != "Aye" {
+		t.Fatalf("stored alerts = %+v, want the sender and thread recorded", alerts)
+	}
+
+	alertHandler(ctx, b, alertUpdate("!alert AAPL > 250", 12, "Bo"))
+	alertHandler(ctx, b, alertUpdate("!alerts", 13, "Cho"))
+	srv.mu.Lock()
+	reply = srv.lastMessage
+	srv.mu.Unlock()
+	want := "Price alerts in this chat\n#1 TSLA -5.00% from 250.00 (< 237.50) · Aye\n#2 AAPL > 250.00 · Bo"
+	if reply != want {
+		t.Fatalf("!alerts =\n%s\nwant\n%s", reply, want)
+	}
+
+	alertHandler(ctx, b, alertUpdate("!alert rm 2", 12, "Bo"))
+	if got := store.forChat(-100); len(got) != 1 || got[0].ID != 1 {
+		t.Fatalf("after rm alerts = %+v", got)
+	}
+	alertHandler(ctx, b, alertUpdate("!alert rm 9", 12, "Bo"))
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.lastMessage, "No alert #9") {
+		t.Fatalf("reply = %q, want the unknown-ID notice", srv.lastMessage)
+	}
+}
+
+func TestAlertHandlerRejects(t *testing.T) {
+	withPriceAlertStore(t)
+	serveFinnhubQuotes(t, map[string]float64{"AAPL": 260})
+	orig := blockedStocks
+	t.Cleanup(func() { blockedStocks = orig })
+	blockedStocks = map[string]string{"SCAM": "SCAM is not available."}
+	b, srv := newTestBot(t)
+
+	for text, want := range map[string]string{
+		"!alert AAPL > 250":  "AAPL is already at 260.00",
+		"!alert SCAM > 1":    "SCAM is not available.",
+		"!alert AAPL":        "Usage",
+		"!alert rm x":        `"x" is not an alert ID`,
+		"!alert AAPL banana": "Usage",
+	} {
+		alertHandler(context.Background(), b, alertUpdate(text, 11, "Aye"))
+		srv.mu.Lock()
+		got := srv.lastMessage
+		srv.mu.Unlock()
+		if !strings.Contains(got, want) {
+			t.Errorf("%s: reply = %q, want %q", text, got, want)
+		}
+	}
+	if got := priceAlertsInstance.symbols(); len(got) != 0 {
+		t.Fatalf("rejected alerts were stored: %q", got)
+	}
+}
+
+func TestPollPriceAlerts(t *testing.T) {
+	store := withPriceAlertStore(t)
+	requested := serveFinnhubQuotes(t, map[string]float64{"AAPL": 251, "MSFT": 400, "NVDA": 100})
+	b, srv := newTestBot(t)
+	ctx := context.Background()
+
+	for _, alert := range []priceAlert{
+		{ChatID: -100, ThreadID: 7, UserID: 11, UserName: "Aye", Symbol: "AAPL", Above: true, Target: 250},
+		{ChatID: -100, UserID: 12, UserName: "Bo", Symbol: "MSFT", Target: 300},
+		{ChatID: -100, UserID: 12, UserName: "Bo", Symbol: "NVDA", Target: 90},
+	} {
+		if _, err := store.add(alert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A batch of two quotes AAPL and MSFT, firing only the AAPL alert.
+	last := pollPriceAlerts(ctx, b, 2, "")
+	if last != "MSFT" || strings.Join(*requested, ",") != "AAPL,MSFT" {
+		t.Fatalf("last = %q, requested %q; want a batch of AAPL and MSFT", last, *requested)
+	}
+	srv.mu.Lock()
+	notice := srv.lastMessage
+	srv.mu.Unlock()
+	if notice != "Aye 🔔 AAPL is at 251.00, alert #1 hit: AAPL > 250.00" {
+		t.Fatalf("notification = %q", notice)
+	}
+	if got := store.symbols(); strings.Join(got, ",") != "MSFT,NVDA" {
+		t.Fatalf("remaining symbols = %q, want the fired alert removed", got)
+	}
+
+	// The next tick picks up after MSFT even though AAPL left the list, then
+	// wraps around.
+	*requested = nil
+	last = pollPriceAlerts(ctx, b, 1, last)
+	last = pollPriceAlerts(ctx, b, 1, last)
+	if strings.Join(*requested, ",") != "NVDA,MSFT" || last != "MSFT" {
+		t.Fatalf("requested %q, want the rotation to reach NVDA and wrap to MSFT", *requested)
+	}
+}
+
+func TestPollPriceAlertsKeepsUndeliveredAlerts(t *testing.T) {
+	store := withPriceAlertStore(t)
+	serveFinnhubQuotes(t, map[string]float64{"AAPL": 251})
+	b, srv := newTestBot(t)
+	if _, err := store.add(priceAlert{ChatID: -100, UserID: 11, UserName: "Aye", Symbol: "AAPL", Above: true, Target: 250}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	srv.failNextSend = true
+	srv.mu.Unlock()
+	pollPriceAlerts(context.Background(), b, 1, "")
+	if got := store.symbols(); strings.Join(got, ",") != "AAPL" {
+		t.Fatalf("symbols() = %q, want the alert kept after a failed send", got)
+	}
+
+	pollPriceAlerts(context.Background(), b, 1, "")
+	srv.mu.Lock()
+	notice := srv.lastMessage
+	srv.mu.Unlock()
+	if !strings.HasPrefix(notice, "Aye 🔔 AAPL is at 251.00") || len(store.symbols()) != 0 {
+		t.Fatalf("notification = %q, symbols %q; want the retry delivered and the alert removed", notice, store.symbols())
+	}
+}
+
+func TestPollPriceAlertsStopsOnRateLimit(t *testing.T) {
+	store := withPriceAlertStore(t)
+	requested := serveFinnhubQuotes(t, map[string]float64{"AAPL": -1, "MSFT": 400})
+	b, _ := newTestBot(t)
+	for _, symbol := range []string{"AAPL", "MSFT"} {
+		if _, err := store.add(priceAlert{ChatID: -100, UserID: 1, Symbol: symbol, Above: true, Target: 1000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if last := pollPriceAlerts(context.Background(), b, 10, ""); last != "" || len(*requested) != 1 {
+		t.Fatalf("last = %q after %d requests, want to stop at the 429 and retry AAPL next tick", last, len(*requested))
+	}
+}
+
+func TestFormatAlertNotificationMention(t *testing.T) {
+	t.Parallel()
+
+	text, entities := formatAlertNotification(priceAlert{ID: 3, UserID: 42, UserName: "Zaw 😀", Symbol: "AAPL", Above: true, Target: 250}, 250.5)
+	if !strings.HasPrefix(text, "Zaw 😀 🔔 AAPL is at 250.50") {
+		t.Fatalf("text = %q", text)
+	}
+	if len(entities) != 1 || entities[0].Type != models.MessageEntityTypeTextMention || entities[0].User.ID != 42 {
+		t.Fatalf("entities = %+v, want a text mention of user 42", entities)
+	}
+	// The emoji is two UTF-16 code units.
+	if entities[0].Offset != 0 || entities[0].Length != 6 {
+		t.Fatalf("mention spans %d+%d, want 0+6", entities[0].Offset, entities[0].Length)
+	}
+}
+
+func TestUSMarketOpenAndPollConfig(t *testing.T) {
+	for when, want := range map[time.Time]bool{
+		time.Date(2026, 10, 16, 13, 30, 0, 0, time.UTC): true,  // Friday 9:30 EDT
+		time.Date(2026, 10, 16, 13, 29, 0, 0, time.UTC): false, // before the open
+		time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC):  false, // 16:00 EDT close
+		time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC):  false, // Saturday
+		time.Date(2026, 12, 7, 15, 0, 0, 0, time.UTC):   true,  // Monday 10:00 EST
+	} {
+		if got := usMarketOpen(when); got != want {
+			t.Errorf("usMarketOpen(%s) = %v, want %v", when, got, want)
+		}
+	}
+
+	t.Setenv("PRICE_ALERT_POLL_SECONDS", "30")
+	t.Setenv("PRICE_ALERT_BATCH_SIZE", "500")
+	if interval, batch := alertPollConfig(); interval != 30*time.Second || batch != maxAlertBatchSize {
+		t.Fatalf("alertPollConfig() = %s, %d", interval, batch)
+	}
+	t.Setenv("PRICE_ALERT_POLL_SECONDS", "nope")
+	t.Setenv("PRICE_ALERT_BATCH_SIZE", "")
+	if interval, batch := alertPollConfig(); interval != defaultAlertPollInterval || batch != defaultAlertBatchSize {
+		t.Fatalf("alertPollConfig() defaults = %s, %d", interval, batch)
+	}
+}
+
+func TestShouldHandleAlert(t *testing.T) {
+	t.Parallel()
+
+	for text, want := range map[string]bool{
+		"!alert":            true,
+		"!alerts":           true,
+		"!alert AAPL > 250": true,
+		"!alertx":           false,
+		"!alertsx":          false,
+		"!s AAPL":           false,
+	} {
+		if got := shouldHandleAlert(groupTextUpdate(text)); got != want {
+			t.Errorf("shouldHandleAlert(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
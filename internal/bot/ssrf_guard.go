@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+// errDisallowedAddress marks a fetch refused because the host resolves to an
+// internal address.
+var errDisallowedAddress = errors.New("host resolves to a disallowed address")
+
+// disallowedPrefixes are ranges that never hold a public web page but can
+// reach this host, its network, or cloud metadata services. Loopback,
+// RFC 1918/ULA private, link-local and multicast are covered by netip.Addr's
+// own predicates in isDisallowedIP; between them, every metadata endpoint
+// (169.254.169.254, fd00:ec2::254, Alibaba's 100.100.100.200) is rejected.
+var disallowedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),   // CGNAT shared address space
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, incl. broadcast
+	netip.MustParsePrefix("100::/64"),        // IPv6 discard-only
+	netip.MustParsePrefix("2001:db8::/32"),   // IPv6 documentation
+}
+
+// Prefixes whose addresses embed an IPv4 address that the network may route
+// to; the embedded address is checked in their place.
+var (
+	nat64Prefix      = netip.MustParsePrefix("64:ff9b::/96")
+	ipv4CompatPrefix = netip.MustParsePrefix("::/96")
+	sixToFourPrefix  = netip.MustParsePrefix("2002::/16")
+)
+
+// isDisallowedIP reports whether ip is an address a server-side fetch must
+// never connect to: loopback, private, link-local, CGNAT, metadata,
+// multicast and reserved ranges, in IPv4, IPv6, and IPv4-mapped or
+// -embedding IPv6 forms.
+func isDisallowedIP(ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return true
+	}
+	ip = ip.Unmap().WithZone("")
+
+	if ip.Is6() {
+		switch {
+		case nat64Prefix.Contains(ip), ipv4CompatPrefix.Contains(ip):
+			b := ip.As16()
+			return isDisallowedIP(netip.AddrFrom4([4]byte(b[12:16])))
+		case sixToFourPrefix.Contains(ip):
+			b := ip.As16()
+			return isDisallowedIP(netip.AddrFrom4([4]byte(b[2:6])))
+		}
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return true
+	}
+	for _, prefix := range disallowedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// netIPResolver is the slice of *net.Resolver the guard needs, so tests can
+// answer lookups without real DNS.
+type netIPResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// ssrfGuardDialer resolves the host itself, refuses the connection when any
+// resolved address is internal, and dials a vetted IP directly. Because the
+// connection goes to the exact address that was checked, a DNS answer that
+// changes between the check and the dial (rebinding) cannot slip through.
+// Every redirect hop opens its connection through the same dialer, so
+// redirects are re-checked too.
+type ssrfGuardDialer struct {
+	resolver netIPResolver
+	dialer   *net.Dialer
+}
+
+func (d *ssrfGuardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("split address: %w", err)
+	}
+
+	addrs, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialErr error
+	for _, ip := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = errors.Join(dialErr, err)
+	}
+	return nil, dialErr
+}
+
+// resolve returns the host's addresses, failing when any of them is
+// disallowed. Rejecting the whole answer (rather than filtering it) keeps a
+// hostname that mixes a public and an internal record from being usable at
+// all.
+func (d *ssrfGuardDialer) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if isDisallowedIP(ip) {
+			return nil, fmt.Errorf("%w: %s", errDisallowedAddress, ip)
+		}
+		return []netip.Addr{ip}, nil
+	}
+
+	addrs, err := d.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve host: %w", err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("resolve host: no addresses for %s", host)
+	}
+	for _, ip := range addrs {
+		if isDisallowedIP(ip) {
+			return nil, fmt.Errorf("%w: %s", errDisallowedAddress, host)
+		}
+	}
+	return addrs, nil
+}
+
+// newSSRFGuardTransport returns a transport for fetching user-supplied URLs.
+// Proxies are disabled: a proxy would make the connection on our behalf and
+// bypass the address check.
+func newSSRFGuardTransport(resolver netIPResolver) *http.Transport {
+	dialer := &ssrfGuardDialer{
+		resolver: resolver,
+		dialer:   &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+func TestIsDisallowedIP(t *testing.T) {
+	t.Parallel()
+
+	disallowed := []string{
+		"127.0.0.1", "127.1.2.3", "10.0.0.1", "172.16.5.4", "192.168.1.1",
+		"169.254.169.254", "100.64.0.1", "100.100.100.200", "0.0.0.0", "0.1.2.3",
+		"255.255.255.255", "224.0.0.1", "198.18.0.1", "192.0.0.170",
+		"::", "::1", "fe80::1", "fe80::1%eth0", "fc00::1", "fd00:ec2::254", "ff02::1",
+		"::ffff:127.0.0.1", "::ffff:10.0.0.1", "::ffff:169.254.169.254",
+		"::127.0.0.1", "64:ff9b::10.0.0.1", "64:ff9b::a9fe:a9fe", "2002:7f00:1::",
+	}
+	for _, raw := range disallowed {
+		if !isDisallowedIP(netip.MustParseAddr(raw)) {
+			t.Errorf("expected %s to be disallowed", raw)
+		}
+	}
+
+	allowed := []string{
+		"1.1.1.1", "8.8.8.8", "93.184.216.34", "100.63.255.255", "100.128.0.1",
+		"2606:4700:4700::1111", "::ffff:8.8.8.8", "64:ff9b::808:808", "2002:808:808::",
+	}
+	for _, raw := range allowed {
+		if isDisallowedIP(netip.MustParseAddr(raw)) {
+			t.Errorf("expected %s to be allowed", raw)
+		}
+	}
+
+	if !isDisallowedIP(netip.Addr{}) {
+		t.Error("expected the zero Addr to be disallowed")
+	}
+}
+
+func TestIsLocalOrPrivateHost(t *testing.T) {
+	t.Parallel()
+
+	for host, want := range map[string]bool{
+		"localhost":         true,
+		"api.localhost":     true,
+		"127.0.0.1":         true,
+		"::ffff:10.1.1.1":   true,
+		"100.64.1.1":        true,
+		"example.com":       false,
+		"8.8.8.8":           false,
+		"localhost.example": false,
+	} {
+		if got := isLocalOrPrivateHost(host); got != want {
+			t.Errorf("isLocalOrPrivateHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+// fakeResolver answers every lookup with addrs.
+type fakeResolver struct {
+	addrs []netip.Addr
+	err   error
+	hosts []string
+}
+
+func (f *fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	f.hosts = append(f.hosts, host)
+	return f.addrs, f.err
+}
+
+func TestSSRFGuardDialerRejectsHostsResolvingInternally(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]netip.Addr{
+		"loopback":      {netip.MustParseAddr("127.0.0.1")},
+		"metadata":      {netip.MustParseAddr("169.254.169.254")},
+		"mapped":        {netip.MustParseAddr("::ffff:10.0.0.5")},
+		"mixed records": {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.5")},
+	}
+	for name, addrs := range tests {
+		dialer := &ssrfGuardDialer{resolver: &fakeResolver{addrs: addrs}, dialer: &net.Dialer{}}
+		_, err := dialer.DialContext(context.Background(), "tcp", "public.example:443")
+		if !errors.Is(err, errDisallowedAddress) {
+			t.Errorf("%s: expected disallowed address error, got %v", name, err)
+		}
+	}
+}
+
+func TestSSRFGuardDialerRejectsLiteralAddressWithoutLookup(t *testing.T) {
+	t.Parallel()
+
+	resolver := &fakeResolver{}
+	dialer := &ssrfGuardDialer{resolver: resolver, dialer: &net.Dialer{}}
+	if _, err := dialer.DialContext(context.Background(), "tcp", "[::ffff:127.0.0.1]:80"); !errors.Is(err, errDisallowedAddress) {
+		t.Fatalf("expected disallowed address error, got %v", err)
+	}
+	if len(resolver.hosts) != 0 {
+		t.Fatalf("expected no DNS lookup for a literal IP, got %v", resolver.hosts)
+	}
+}
+
+func TestSSRFGuardDialerPinsVettedAddress(t *testing.T) {
+	t.Parallel()
+
+	// The control hook sees the address actually dialed and aborts before any
+	// packet is sent, so no real network is needed.
+	var dialed string
+	dialer := &ssrfGuardDialer{
+		resolver: &fakeResolver{addrs: []netip.Addr{netip.MustParseAddr("93.184.216.34")}},
+		dialer: &net.Dialer{ControlContext: func(_ context.Context, _, address string, _ syscall.RawConn) error {
+			dialed = address
+			return errors.New("stop before connecting")
+		}},
+	}
+	_, err := dialer.DialContext(context.Background(), "tcp", "public.example:8443")
+	if err == nil {
+		t.Fatal("expected the control hook to abort the dial")
+	}
+	if dialed != "93.184.216.34:8443" {
+		t.Fatalf("expected dial pinned to the vetted IP, got %q", dialed)
+	}
+}
+
+func TestLocalExtractHTTPClientRefusesLoopback(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	server.Start()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	resp, err := localExtractHTTPClient.Do(req)
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("expected the guarded client to refuse a loopback server")
+	}
+	if !errors.Is(err, errDisallowedAddress) {
+		t.Fatalf("expected disallowed address error, got %v", err)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Fatalf("expected no request to reach the server, got %d", got)
+	}
+}
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -31,15 +32,15 @@ import (
 var (
 	histHTTPClient = &http.Client{Timeout: 30 * time.Second}
 	symbolRegex    = regexp.MustCompile(`^[A-Z0-9.\-]{1,10}$`)
-	rangeTokenRE   = regexp.MustCompile(`^[0-9]+d$`)
+	rangeTokenRE   = regexp.MustCompile(`^[0-9]+[dmy]$`)
 	nowFunc        = time.Now
 	blockedStocks  = map[string]string{}
 )
 
 var errDatabentoAPIKeyNotConfigured = errors.New("databento api key not configured")
 
-// stockRangeDays maps range tokens to day counts for both !s and !sa
-// commands.
+// stockRangeDays maps day-count range tokens to day counts for both !s and
+// !sa commands; see stockRangeMonths and parseStockRange for the rest.
 var stockRangeDays = map[string]int{
 	"7d":  7,
 	"30d": 30,
@@ -47,13 +48,15 @@ var stockRangeDays = map[string]int{
 	"90d": 90,
 }
 
+// httpStatusError is a non-200 response from Databento or Finnhub's quote
+// endpoint; Body is only kept for Databento, whose 422s carry details.
 type httpStatusError struct {
 	StatusCode int
 	Status     string
 	Body       string
 }
 
-// Error renders HTTP status details for Databento request failures.
+// Error renders HTTP status details for the failed request.
 func (e *httpStatusError) Error() string {
 	return fmt.Sprintf("HTTP %d %s %s", e.StatusCode, e.Status, e.Body)
 }
@@ -98,7 +101,18 @@ type databentoPayload struct {
 }
 
 func stockHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	symbol, days, err := parseStockCommand(update.Message.Text)
+	symbols, multi, err := parseStockQuotesCommand(update.Message.Text)
+	if multi && err == nil {
+		handleStockQuotes(ctx, b, update, symbols)
+		return
+	}
+
+	var symbol string
+	var rng stockRange
+	var chartOpts stockChartOptions
+	if err == nil {
+		symbol, rng, chartOpts, err = parseStockCommand(update.Message.Text)
+	}
 	if err != nil {
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          update.Message.Chat.ID,
@@ -123,8 +137,8 @@ func stockHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	}
 
 	loadingText := fmt.Sprintf("Fetching data for %s...", symbol)
-	if days > 0 {
-		loadingText = fmt.Sprintf("Fetching %d-day historical data for %s...", days, symbol)
+	if !rng.isZero() {
+		loadingText = fmt.Sprintf("Fetching %s historical data for %s...", rng.label(), symbol)
 	}
 	loadingMsg, loadingErr := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          update.Message.Chat.ID,
@@ -136,11 +150,15 @@ func stockHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		},
 	})
 	if loadingErr != nil {
-		log.Warn().Err(loadingErr).Str("symbol", symbol).Int("days", days).Msg("Failed to send stock loading state")
+		log.Warn().Err(loadingErr).Str("symbol", symbol).Str("range", rng.label()).Msg("Failed to send stock loading state")
 	}
 
-	if days > 0 {
-		handleHistoricalStock(ctx, b, update, symbol, days, loadingMsg, loadingErr)
+	if rng.isIntraday() {
+		handleIntradayStock(ctx, b, update, symbol, rng, chartOpts, loadingMsg, loadingErr)
+		return
+	}
+	if !rng.isZero() {
+		handleHistoricalStock(ctx, b, update, symbol, rng, chartOpts, loadingMsg, loadingErr)
 		return
 	}
 
@@ -162,36 +180,32 @@ func stockHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 }
 
 // handleHistoricalStock fetches historical bars, renders a chart, and replies
-// with either a photo+caption or a text fallback.
+// with either a photo+caption or a text fallback. Long ranges are charted
+// with weekly or monthly bars; the caption's price summary uses the daily
+// bars and its indicator readings the charted ones.
 func handleHistoricalStock(
 	ctx context.Context,
 	b *bot.Bot,
 	update *models.Update,
 	symbol string,
-	days int,
+	rng stockRange,
+	chartOpts stockChartOptions,
 	loadingMsg *models.Message,
 	loadingErr error,
 ) {
-	bars, adjustedNote, err := fetchHistoricalBars(ctx, symbol, days)
+	label := rng.label()
+	window := rng.dateRange(nowFunc())
+	history, adjustedNote, err := fetchHistoricalBars(ctx, symbol, rng, chartOpts.warmupDays(barIntervalFor(window)))
 	if err != nil {
-		if errors.Is(err, errDatabentoAPIKeyNotConfigured) {
-			appotel.RecordOutcome(ctx, "not_configured")
-		} else {
-			appotel.RecordOutcome(ctx, "error")
-		}
-		msg := fmt.Sprintf("Failed to fetch %d-day historical data for %s. Please try again later.", days, symbol)
-		if errors.Is(err, errDatabentoAPIKeyNotConfigured) {
-			msg = "Historical data is unavailable: DATABENTO_API_KEY is not configured."
-		}
-		log.Error().Err(err).Str("symbol", symbol).Int("days", days).Msg("Failed to fetch historical bars")
-		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, msg)
+		replyHistoricalFetchError(ctx, b, update, loadingMsg, loadingErr, symbol, label, err)
 		return
 	}
 	// All remaining paths (no data, chart-render fallback, photo or text reply)
 	// are completed operations, so they count as success.
 	appotel.RecordOutcome(ctx, "success")
+	warmup, bars := splitWarmupBars(history, window.Start)
 	if len(bars) == 0 {
-		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("No historical data returned for %s in the last %d days.", symbol, days))
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("No historical data returned for %s (%s).", symbol, label))
 		return
 	}
 
@@ -200,24 +214,75 @@ func handleHistoricalStock(
 		log.Warn().Err(profileErr).Str("symbol", symbol).Msg("Failed to fetch company profile for historical stock response")
 	}
 
-	caption := formatHistoricalSummary(symbol, days, bars, profile)
+	interval := barIntervalFor(dbn_hist.DateRange{Start: bars[0].Date, End: bars[len(bars)-1].Date})
+	chartWarmup, chartBars := downsampleBars(warmup, interval), downsampleBars(bars, interval)
+	readings := formatIndicatorReadings(chartOpts.indicators, closePrices(append(slices.Clip(chartWarmup), chartBars...)))
+	caption := formatHistoricalSummary(symbol, label, bars, profile, readings)
+	if interval != barIntervalDaily {
+		if readings != "" {
+			caption += fmt.Sprintf("\nChart and indicators: %s bars", interval)
+		} else {
+			caption += fmt.Sprintf("\nChart: %s bars", interval)
+		}
+	}
 	if adjustedNote != "" {
 		caption += "\n" + adjustedNote
 	}
-	chartPNG, err := renderHistoricalChartPNG(symbol, days, bars)
+	chartPNG, err := renderStockChartPNG(symbol, label, chartWarmup, chartBars, chartOpts)
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Int("days", days).Msg("Failed to render historical chart; sending text only")
+		log.Warn().Err(err).Str("symbol", symbol).Str("range", label).Msg("Failed to render historical chart; sending text only")
 		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, caption)
 		return
 	}
 
-	updateStockLoadingState(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Fetched %d-day historical data for %s. Sending chart...", days, symbol))
+	sendHistoricalChart(ctx, b, update, loadingMsg, loadingErr, symbol, rng, chartPNG, caption)
+}
+
+// replyHistoricalFetchError records the outcome of a failed bar fetch and
+// tells the user, naming the missing API key when that is the cause.
+func replyHistoricalFetchError(
+	ctx context.Context,
+	b *bot.Bot,
+	update *models.Update,
+	loadingMsg *models.Message,
+	loadingErr error,
+	symbol, label string,
+	err error,
+) {
+	if errors.Is(err, errDatabentoAPIKeyNotConfigured) {
+		appotel.RecordOutcome(ctx, "not_configured")
+	} else {
+		appotel.RecordOutcome(ctx, "error")
+	}
+	msg := fmt.Sprintf("Failed to fetch %s historical data for %s. Please try again later.", label, symbol)
+	if errors.Is(err, errDatabentoAPIKeyNotConfigured) {
+		msg = "Historical data is unavailable: DATABENTO_API_KEY is not configured."
+	}
+	log.Error().Err(err).Str("symbol", symbol).Str("range", label).Msg("Failed to fetch historical bars")
+	sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, msg)
+}
+
+// sendHistoricalChart replies with the chart and its caption, falling back
+// to the caption alone when the photo cannot be sent.
+func sendHistoricalChart(
+	ctx context.Context,
+	b *bot.Bot,
+	update *models.Update,
+	loadingMsg *models.Message,
+	loadingErr error,
+	symbol string,
+	rng stockRange,
+	chartPNG []byte,
+	caption string,
+) {
+	label := rng.label()
+	updateStockLoadingState(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Fetched %s historical data for %s. Sending chart...", label, symbol))
 
 	_, sendErr := b.SendPhoto(ctx, &bot.SendPhotoParams{
 		ChatID:          update.Message.Chat.ID,
 		MessageThreadID: update.Message.MessageThreadID,
 		Photo: &models.InputFileUpload{
-			Filename: fmt.Sprintf("%s-%dd.png", strings.ToLower(symbol), days),
+			Filename: historicalFileLabel(symbol, rng),
 			Data:     bytes.NewReader(chartPNG),
 		},
 		Caption: caption,
@@ -227,11 +292,11 @@ func handleHistoricalStock(
 		},
 	})
 	if sendErr == nil {
-		updateStockLoadingState(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Done. Sent %d-day chart for %s.", days, symbol))
+		updateStockLoadingState(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Done. Sent %s chart for %s.", label, symbol))
 		return
 	}
 
-	log.Warn().Err(sendErr).Str("symbol", symbol).Int("days", days).Msg("Failed to send historical chart image; sending text only")
+	log.Warn().Err(sendErr).Str("symbol", symbol).Str("range", label).Msg("Failed to send historical chart image; sending text only")
 	sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, caption)
 }
 
@@ -324,27 +389,36 @@ func extractSymbolToken(text, prefix, usageMsg string) (symbol string, parts []s
 }
 
 // parseStockCommand parses `!s` commands and validates symbol/range inputs.
-func parseStockCommand(text string) (string, int, error) {
+// A zero stockRange means a spot quote; chart options may only follow a
+// range.
+func parseStockCommand(text string) (string, stockRange, stockChartOptions, error) {
 	symbol, parts, err := extractSymbolToken(text, "!s", invalidUsageSymbol)
 	if err != nil {
-		return "", 0, err
-	}
-
-	if len(parts) > 2 {
-		return "", 0, errors.New(invalidUsageSymbol)
+		return "", stockRange{}, stockChartOptions{}, err
 	}
 
 	if len(parts) == 1 {
-		return symbol, 0, nil
+		return symbol, stockRange{}, stockChartOptions{}, nil
 	}
 
-	if days, ok := stockRangeDays[strings.ToLower(parts[1])]; ok {
-		return symbol, days, nil
+	if !isStockRangeToken(parts[1]) {
+		if isStockChartOption(parts[1]) {
+			return "", stockRange{}, stockChartOptions{}, fmt.Errorf("%s needs a range first (e.g., !s %s 30d %s)", strings.ToLower(parts[1]), symbol, strings.ToLower(parts[1]))
+		}
+		return "", stockRange{}, stockChartOptions{}, errors.New(invalidUsageSymbol)
+	}
+	rng, err := parseStockRange(parts[1], nowFunc())
+	if err != nil {
+		return "", stockRange{}, stockChartOptions{}, err
 	}
-	if rangeTokenRE.MatchString(strings.ToLower(parts[1])) {
-		return "", 0, errors.New("invalid range, use 7d, 30d, 60d or 90d (e.g., !s AAPL 7d)")
+	opts, err := parseStockChartOptions(parts[2:])
+	if err != nil {
+		return "", stockRange{}, stockChartOptions{}, err
 	}
-	return "", 0, errors.New(invalidUsageSymbol)
+	if err := opts.checkWarmup(barIntervalFor(rng.dateRange(nowFunc()))); err != nil {
+		return "", stockRange{}, stockChartOptions{}, err
+	}
+	return symbol, rng, opts, nil
 }
 
 func blockedStockResponse(symbol string) (string, bool) {
@@ -391,7 +465,8 @@ func fetchStockQuote(ctx context.Context, symbol string) (quote *StockQuote, err
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(unexpectedCodeErrMsg, resp.StatusCode)
+		// Typed so the price alert poller can spot Finnhub's 429s.
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	var decoded StockQuote
@@ -520,28 +595,70 @@ func formatExchangeCurrencyLine(profile *CompanyProfile) string {
 	return "\n" + strings.Join(parts, " · ")
 }
 
-// fetchHistoricalBars requests Databento daily OHLCV bars and normalizes them
-// into sorted, day-truncated records.
-func fetchHistoricalBars(ctx context.Context, symbol string, days int) ([]HistoricalBar, string, error) {
-	if days < 1 || days > 90 {
-		return nil, "", errors.New("historical range must be between 1 and 90 days")
+// fetchHistoricalBars requests Databento daily OHLCV bars for rng, plus
+// warmupDays of earlier history for indicators, and normalizes them into
+// sorted, day-truncated records.
+func fetchHistoricalBars(ctx context.Context, symbol string, rng stockRange, warmupDays int) ([]HistoricalBar, string, error) {
+	dateRange := rng.dateRange(nowFunc())
+	if days := rangeDays(dateRange); days < 1 || days > maxStockRangeDays {
+		return nil, "", errors.New("historical range must be between 1 day and 5 years")
+	}
+	dateRange.Start = dateRange.Start.AddDate(0, 0, -max(warmupDays, 0))
+
+	barsBySymbol, adjustedNote, err := fetchDailyBars(ctx, []string{symbol}, dateRange)
+	if err != nil {
+		return nil, "", err
+	}
+	return barsBySymbol[symbol], adjustedNote, nil
+}
+
+// fetchDailyBars requests Databento daily OHLCV bars for every symbol in a
+// single request and returns them per symbol. The note is non-empty when the
+// window had to be moved back to the dataset's available end.
+// Bars already in the daily bar cache are served from disk and only the
+// missing days are requested.
+func fetchDailyBars(ctx context.Context, symbols []string, dateRange dbn_hist.DateRange) (map[string][]HistoricalBar, string, error) {
+	if dailyBarCacheInstance != nil {
+		return dailyBarCacheInstance.bars(ctx, databentoDataset(), symbols, dateRange)
 	}
+	return fetchOHLCVBars(ctx, symbols, dbn.Schema_Ohlcv1D, dateRange)
+}
+
+// databentoDataset is DATABENTO_DATASET, defaulting to EQUS.MINI.
+func databentoDataset() string {
+	return cmp.Or(getenvTrim("DATABENTO_DATASET"), "EQUS.MINI")
+}
+
+// fetchOHLCVBars is fetchDailyBars for any OHLCV schema, without the cache.
+// Intraday bars keep their UTC start time instead of being truncated to the
+// day.
+func fetchOHLCVBars(ctx context.Context, symbols []string, schema dbn.Schema, dateRange dbn_hist.DateRange) (map[string][]HistoricalBar, string, error) {
+	barsBySymbol, _, adjustedNote, err := requestOHLCVBars(ctx, symbols, schema, dateRange)
+	return barsBySymbol, adjustedNote, err
+}
+
+// requestOHLCVBars downloads bars from Databento and also returns the window
+// actually requested, which differs from dateRange when it had to be moved
+// back to the dataset's available end.
+func requestOHLCVBars(
+	ctx context.Context,
+	symbols []string,
+	schema dbn.Schema,
+	dateRange dbn_hist.DateRange,
+) (map[string][]HistoricalBar, dbn_hist.DateRange, string, error) {
+	days := rangeDays(dateRange)
 
 	apiKey := strings.TrimSpace(os.Getenv("DATABENTO_API_KEY"))
 	if apiKey == "" {
-		return nil, "", errDatabentoAPIKeyNotConfigured
+		return nil, dateRange, "", errDatabentoAPIKeyNotConfigured
 	}
 
-	dataset := strings.TrimSpace(os.Getenv("DATABENTO_DATASET"))
-	if dataset == "" {
-		dataset = "EQUS.MINI"
-	}
+	dataset := databentoDataset()
 
-	dateRange := historicalDateRangeUTC(nowFunc(), days)
 	params := dbn_hist.SubmitJobParams{
 		Dataset:     dataset,
-		Symbols:     symbol,
-		Schema:      dbn.Schema_Ohlcv1D,
+		Symbols:     strings.Join(symbols, ","),
+		Schema:      schema,
 		DateRange:   dateRange,
 		Encoding:    dbn.Encoding_Dbn,
 		Compression: dbn.Compress_None,
@@ -554,29 +671,66 @@ func fetchHistoricalBars(ctx context.Context, symbol string, days int) ([]Histor
 		// Single-retry path: only retry once when Databento reports that the
 		// requested end date is newer than the dataset's available end date.
 		if retryParams, ok := tryAdjustRangeFromDatabento422(&params, err, days); ok {
+			endLayout := dateFormatPattern
+			if isIntradaySchema(schema) {
+				endLayout = intradayTimeFormatPattern
+			}
 			adjustedNote = fmt.Sprintf(
 				"Note: data availability lagged; used latest available window ending %s UTC.",
-				retryParams.DateRange.End.Format(dateFormatPattern),
+				retryParams.DateRange.End.Format(endLayout),
 			)
 			raw, err = getHistoricalRangeWithContext(ctx, apiKey, &retryParams)
+			params = retryParams
 		}
 	}
 	if err != nil {
-		return nil, "", err
+		return nil, dateRange, "", err
 	}
 
-	records, _, err := dbn.ReadDBNToSlice[dbn.OhlcvMsg](bytes.NewReader(raw))
+	records, metadata, err := dbn.ReadDBNToSlice[dbn.OhlcvMsg](bytes.NewReader(raw))
 	if err != nil {
-		return nil, "", err
+		return nil, dateRange, "", err
+	}
+	barsBySymbol, err := splitBarsBySymbol(symbols, schema, records, metadata)
+	if err != nil {
+		return nil, dateRange, "", err
 	}
+	return barsBySymbol, params.DateRange, adjustedNote, nil
+}
 
-	bars := make([]HistoricalBar, 0, len(records))
+// splitBarsBySymbol converts records into sorted bars per requested symbol,
+// truncating daily bars to the day. A single-symbol response needs no
+// symbology; otherwise each record's instrument ID is resolved through the
+// metadata mappings and records that resolve to none of symbols are dropped.
+func splitBarsBySymbol(symbols []string, schema dbn.Schema, records []dbn.OhlcvMsg, metadata *dbn.Metadata) (map[string][]HistoricalBar, error) {
+	var symbolMap *dbn.TsSymbolMap
+	if len(symbols) > 1 {
+		if metadata == nil {
+			return nil, errors.New("historical data has no symbol mappings")
+		}
+		symbolMap = dbn.NewTsSymbolMap()
+		if err := symbolMap.FillFromMetadata(metadata); err != nil {
+			return nil, fmt.Errorf("historical data symbol mappings: %w", err)
+		}
+	}
+
+	out := make(map[string][]HistoricalBar, len(symbols))
 	for _, rec := range records {
 		if rec.Header.TsEvent > uint64(math.MaxInt64) {
-			return nil, "", errors.New("invalid timestamp from historical data")
+			return nil, errors.New("invalid timestamp from historical data")
+		}
+		ts := time.Unix(0, int64(rec.Header.TsEvent)).UTC()
+		if !isIntradaySchema(schema) {
+			ts = ts.Truncate(24 * time.Hour)
 		}
-		ts := time.Unix(0, int64(rec.Header.TsEvent)).UTC().Truncate(24 * time.Hour)
-		bars = append(bars, HistoricalBar{
+		symbol := symbols[0]
+		if symbolMap != nil {
+			symbol = symbolMap.Get(ts, rec.Header.InstrumentID)
+			if !slices.Contains(symbols, symbol) {
+				continue
+			}
+		}
+		out[symbol] = append(out[symbol], HistoricalBar{
 			Date:   ts,
 			Open:   float64(rec.Open) / 1e9,
 			High:   float64(rec.High) / 1e9,
@@ -586,17 +740,19 @@ func fetchHistoricalBars(ctx context.Context, symbol string, days int) ([]Histor
 		})
 	}
 
-	slices.SortFunc(bars, func(a, b HistoricalBar) int {
-		return a.Date.Compare(b.Date)
-	})
-	return bars, adjustedNote, nil
+	for _, bars := range out {
+		slices.SortFunc(bars, func(a, b HistoricalBar) int {
+			return a.Date.Compare(b.Date)
+		})
+	}
+	return out, nil
 }
 
 // historicalDateRangeUTC returns a UTC midnight-aligned half-open date range.
 // The end is set to the previous day so the range stays within Databento's
 // historical (non-live) data boundary.
 func historicalDateRangeUTC(now time.Time, days int) dbn_hist.DateRange {
-	end := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	end := historicalEndUTC(now)
 	return dbn_hist.DateRange{
 		Start: end.AddDate(0, 0, -days),
 		End:   end,
@@ -661,6 +817,8 @@ func getHistoricalRangeWithContext(ctx context.Context, apiKey string, params *d
 
 // tryAdjustRangeFromDatabento422 shifts the query window into Databento's
 // available schema range for supported 422 cases, allowing one safe retry.
+// Daily windows stay midnight-aligned and days long; intraday windows keep
+// their length and end at the available end, to the minute.
 func tryAdjustRangeFromDatabento422(params *dbn_hist.SubmitJobParams, err error, days int) (dbn_hist.SubmitJobParams, bool) {
 	statusErr, ok := errors.AsType[*httpStatusError](err)
 	if !ok || statusErr.StatusCode != http.StatusUnprocessableEntity {
@@ -685,15 +843,23 @@ func tryAdjustRangeFromDatabento422(params *dbn_hist.SubmitJobParams, err error,
 	if parseErr != nil {
 		return *params, false
 	}
-	availableEnd = availableEnd.UTC().Truncate(24 * time.Hour)
+	align := 24 * time.Hour
+	if isIntradaySchema(params.Schema) {
+		align = time.Minute
+	}
+	availableEnd = availableEnd.UTC().Truncate(align)
 	adjusted := *params
 	adjusted.DateRange.End = availableEnd
-	adjusted.DateRange.Start = availableEnd.AddDate(0, 0, -days)
+	if align == time.Minute {
+		adjusted.DateRange.Start = availableEnd.Add(-params.DateRange.End.Sub(params.DateRange.Start))
+	} else {
+		adjusted.DateRange.Start = availableEnd.AddDate(0, 0, -days)
+	}
 
 	if strings.TrimSpace(payload.Detail.Payload.AvailableStart) != "" {
 		availableStart, startParseErr := time.Parse(time.RFC3339Nano, payload.Detail.Payload.AvailableStart)
 		if startParseErr == nil {
-			availableStart = availableStart.UTC().Truncate(24 * time.Hour)
+			availableStart = availableStart.UTC().Truncate(align)
 			if adjusted.DateRange.Start.Before(availableStart) {
 				adjusted.DateRange.Start = availableStart
 			}
@@ -707,9 +873,11 @@ func tryAdjustRangeFromDatabento422(params *dbn_hist.SubmitJobParams, err error,
 }
 
 // formatHistoricalSummary creates a compact caption for historical responses.
-func formatHistoricalSummary(symbol string, days int, bars []HistoricalBar, profile *CompanyProfile) string {
+// label is the range as typed (7d, 1y, YTD or a date span); indicators holds
+// the latest indicator readings, if any were asked for.
+func formatHistoricalSummary(symbol, label string, bars []HistoricalBar, profile *CompanyProfile, indicators string) string {
 	if len(bars) == 0 {
-		return fmt.Sprintf("No historical data returned for %s in the last %d days.", symbol, days)
+		return fmt.Sprintf("No historical data returned for %s (%s).", symbol, label)
 	}
 
 	first := bars[0]
@@ -741,35 +909,46 @@ func formatHistoricalSummary(symbol string, days int, bars []HistoricalBar, prof
 		}
 		exchangeCurrencyStr = formatExchangeCurrencyLine(profile)
 	}
+	spanLayout, spanZone := dateFormatPattern, ""
+	if hasIntradayBars(bars) {
+		spanLayout, spanZone = intradayTimeFormatPattern, " ET"
+	}
+	indicatorStr := ""
+	if indicators != "" {
+		indicatorStr = "\nIndicators: " + indicators
+	}
 
 	return fmt.Sprintf(
-		"%s %dd (%s to %s)%s\nClose: $%.2f\nReturn: %.2f%%\nRange: $%.2f - $%.2f%s%s",
+		"%s %s (%s to %s%s)%s\nClose: $%.2f\nReturn: %.2f%%\nRange: $%.2f - $%.2f%s%s%s",
 		title,
-		days,
-		first.Date.Format(dateFormatPattern),
-		last.Date.Format(dateFormatPattern),
+		label,
+		first.Date.Format(spanLayout),
+		last.Date.Format(spanLayout),
+		spanZone,
 		exchangeCurrencyStr,
 		last.Close,
 		change,
 		low,
 		high,
+		indicatorStr,
 		marketCapStr,
 		industryStr,
 	)
 }
 
 // renderHistoricalChartPNG renders close prices as a PNG line chart.
-func renderHistoricalChartPNG(symbol string, days int, bars []HistoricalBar) ([]byte, error) {
+func renderHistoricalChartPNG(symbol, label string, bars []HistoricalBar) ([]byte, error) {
+	layout := chartDateLayout(bars)
 	values := make([]float64, 0, len(bars))
 	labels := make([]string, 0, len(bars))
 	for _, bar := range bars {
 		values = append(values, bar.Close)
-		labels = append(labels, bar.Date.Format("01-02"))
+		labels = append(labels, bar.Date.Format(layout))
 	}
 
 	p, err := charts.LineRender(
 		[][]float64{values},
-		charts.TitleTextOptionFunc(fmt.Sprintf("%s %dD Close", symbol, days)),
+		charts.TitleTextOptionFunc(fmt.Sprintf("%s %s Close", symbol, strings.ToUpper(label))),
 		charts.LegendLabelsOptionFunc([]string{symbol}),
 		charts.XAxisLabelsOptionFunc(labels),
 		func(opt *charts.ChartOption) {
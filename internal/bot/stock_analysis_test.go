@@ -39,7 +39,7 @@ func TestParseStockAnalysisCommand(t *testing.T) {
 		{name: "historical range 30d rejected", input: "!sa AAPL 30d", wantError: true, errSubstr: "does not support historical ranges"},
 		{name: "historical range 60d rejected", input: "!sa AAPL 60d", wantError: true, errSubstr: "does not support historical ranges"},
 		{name: "historical range 90d rejected", input: "!sa AAPL 90d", wantError: true, errSubstr: "does not support historical ranges"},
-		{name: "invalid range 1d rejected", input: "!sa AAPL 1d", wantError: true, errSubstr: testErrInvalidUsage},
+		{name: "intraday range 1d rejected", input: "!sa AAPL 1d", wantError: true, errSubstr: "does not support historical ranges"},
 		{name: "invalid range 10d rejected", input: "!sa AAPL 10d", wantError: true, errSubstr: testErrInvalidUsage},
 		{name: "extra token rejected", input: "!sa AAPL foobar", wantError: true, errSubstr: testErrInvalidUsage},
 		{name: "multiple extra tokens rejected", input: "!sa AAPL x y", wantError: true, errSubstr: testErrInvalidUsage},
@@ -71,7 +71,7 @@ func TestParseStockAnalysisCommand(t *testing.T) {
 
 func TestRouting_SA_DoesNotTrigger_StockHandler(t *testing.T) {
 	// !sa AAPL should NOT be parsed by parseStockCommand.
-	_, _, err := parseStockCommand(testStockCommand)
+	_, _, _, err := parseStockCommand(testStockCommand)
 	if err == nil {
 		t.Fatal("expected !sa AAPL to fail parseStockCommand")
 	}
@@ -80,13 +80,13 @@ func TestRouting_SA_DoesNotTrigger_StockHandler(t *testing.T) {
 	}
 
 	// !sa without space should also fail.
-	_, _, err = parseStockCommand("!saAAPL")
+	_, _, _, err = parseStockCommand("!saAAPL")
 	if err == nil {
 		t.Fatal("expected !saAAPL to fail parseStockCommand")
 	}
 
 	// !sa alone should also fail.
-	_, _, err = parseStockCommand("!sa")
+	_, _, _, err = parseStockCommand("!sa")
 	if err == nil {
 		t.Fatal("expected !sa to fail parseStockCommand")
 	}
@@ -821,6 +821,8 @@ type testBotServer struct {
 	requestLog    []string // method names captured from URL paths
 	lastMessage   string   // text captured from last sendMessage/editMessageText
 	lastParseMode string   // parse_mode captured from last sendMessage/editMessageText
+	lastMarkup    string   // reply_markup captured from last sendMessage/editMessageText
+	lastCaption   string   // caption captured from last sendPhoto
 	failNextEdit  bool     // return error on next editMessageText call
 	failNextSend  bool     // return error on next sendMessage call
 }
@@ -851,6 +853,10 @@ func (s *testBotServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseMultipartForm(maxFormSize); err == nil { //nolint:gosec,nolintlint
 		if txt := r.FormValue("text"); txt != "" {
 			s.lastMessage = txt
+			s.lastMarkup = r.FormValue("reply_markup")
+		}
+		if caption := r.FormValue("caption"); caption != "" {
+			s.lastCaption = caption
 		}
 		s.lastParseMode = r.FormValue("parse_mode")
 	}
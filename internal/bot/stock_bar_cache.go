@@ -0,0 +1,275 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	dbn "github.com/NimbleMarkets/dbn-go"
+	dbn_hist "github.com/NimbleMarkets/dbn-go/hist"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	barCacheDirName = "bars"
+	barCacheName    = "databento_bars"
+	barCacheSchema  = dbn.Schema_Ohlcv1D
+)
+
+// barCacheFile is the on-disk cache of one symbol's daily bars. Covered lists
+// the sorted, non-overlapping windows already downloaded, so holidays and
+// other days without bars are not requested again.
+type barCacheFile struct {
+	Covered []dbn_hist.DateRange `json:"covered"`
+	Bars    []HistoricalBar      `json:"bars"`
+}
+
+// dailyBarCache keeps Databento daily bars on disk, one file per dataset,
+// schema and symbol, and downloads only the days a request is missing.
+type dailyBarCache struct {
+	dir   string
+	fetch func(context.Context, []string, dbn.Schema, dbn_hist.DateRange) (map[string][]HistoricalBar, dbn_hist.DateRange, string, error)
+
+	// mu serialises the read-merge-write of cache files; downloads run
+	// outside it.
+	mu sync.Mutex
+}
+
+// dailyBarCacheInstance is nil until initDailyBarCache runs, which leaves
+// fetchDailyBars uncached.
+var dailyBarCacheInstance *dailyBarCache
+
+func newDailyBarCache(dir string) *dailyBarCache {
+	return &dailyBarCache{dir: dir, fetch: requestOHLCVBars}
+}
+
+// initDailyBarCache enables the cache under DATABENTO_CACHE_DIR (default
+// BOT_DATA_DIR/bars). Setting it to "off" disables caching.
+func initDailyBarCache() {
+	dir := cmp.Or(getenvTrim("DATABENTO_CACHE_DIR"), botDataPath(barCacheDirName))
+	if strings.EqualFold(dir, "off") {
+		dailyBarCacheInstance = nil
+		log.Info().Msg("Databento bar cache disabled")
+		return
+	}
+	dailyBarCacheInstance = newDailyBarCache(dir)
+	log.Info().Str("dir", dir).Msg("Databento bar cache enabled")
+}
+
+// bars returns the daily bars of symbols within dateRange, requesting only
+// the days not yet cached. Symbols missing the same days share one batched
+// request. The note is non-empty when a request had to be moved back to the
+// dataset's available end; the earlier bars it returned are included.
+func (c *dailyBarCache) bars(ctx context.Context, dataset string, symbols []string, dateRange dbn_hist.DateRange) (map[string][]HistoricalBar, string, error) {
+	files := make(map[string]barCacheFile, len(symbols))
+	groups := make(map[string][]string)
+	var groupOrder []string
+	var bytesSaved int64
+	for _, symbol := range symbols {
+		file := c.load(dataset, symbol)
+		files[symbol] = file
+
+		gaps := slices.DeleteFunc(missingRanges(file.Covered, dateRange), weekendOnly)
+		recordCacheLookup(ctx, barCacheName, len(gaps) == 0)
+		bytesSaved += int64(len(barsInRange(file.Bars, dateRange)) * dbn.OhlcvMsg_Size)
+		if len(gaps) == 0 {
+			continue
+		}
+		key := formatDateRanges(gaps)
+		if _, ok := groups[key]; !ok {
+			groupOrder = append(groupOrder, key)
+		}
+		groups[key] = append(groups[key], symbol)
+	}
+	if bytesSaved > 0 {
+		appotel.Instruments().CacheBytesSavedTotal.Add(ctx, bytesSaved, metric.WithAttributes(
+			attribute.String("cache", barCacheName),
+		))
+	}
+
+	from := dateRange.Start
+	adjustedNote := ""
+	for _, key := range groupOrder {
+		group := groups[key]
+		for _, gap := range slices.DeleteFunc(missingRanges(files[group[0]].Covered, dateRange), weekendOnly) {
+			fetched, fetchedRange, note, err := c.fetch(ctx, group, barCacheSchema, gap)
+			if err != nil {
+				return nil, "", err
+			}
+			if note != "" && adjustedNote == "" {
+				adjustedNote = note
+			}
+			if fetchedRange.Start.Before(from) {
+				from = fetchedRange.Start
+			}
+			for _, symbol := range group {
+				files[symbol] = c.merge(dataset, symbol, fetchedRange, fetched[symbol])
+			}
+		}
+	}
+
+	window := dbn_hist.DateRange{Start: from, End: dateRange.End}
+	out := make(map[string][]HistoricalBar, len(symbols))
+	for _, symbol := range symbols {
+		if bars := barsInRange(files[symbol].Bars, window); len(bars) > 0 {
+			out[symbol] = bars
+		}
+	}
+	return out, adjustedNote, nil
+}
+
+func (c *dailyBarCache) path(dataset, symbol string) string {
+	return filepath.Join(c.dir, cacheFileName(dataset), barCacheSchema.String(), cacheFileName(symbol)+".json")
+}
+
+// load reads a symbol's cache file. A missing or unreadable file is an empty
+// cache: the bars are downloaded again and the file rewritten.
+func (c *dailyBarCache) load(dataset, symbol string) barCacheFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadLocked(dataset, symbol)
+}
+
+func (c *dailyBarCache) loadLocked(dataset, symbol string) barCacheFile {
+	var file barCacheFile
+	if _, err := readJSONFile(c.path(dataset, symbol), &file); err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Ignoring unreadable bar cache file")
+		return barCacheFile{}
+	}
+	return file
+}
+
+// merge adds bars downloaded for fetched to the symbol's cache file and
+// returns the result. The file is re-read under the lock so concurrent
+// requests for the same symbol do not drop each other's bars. Only windows
+// ending before historicalEndUTC count as covered, since later bars may still
+// change.
+func (c *dailyBarCache) merge(dataset, symbol string, fetched dbn_hist.DateRange, bars []HistoricalBar) barCacheFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file := c.loadLocked(dataset, symbol)
+	if settled := historicalEndUTC(nowFunc()); fetched.End.After(settled) {
+		fetched.End = settled
+	}
+	if fetched.Start.Before(fetched.End) {
+		file.Covered = addDateRange(file.Covered, fetched)
+	}
+	file.Bars = mergeBars(file.Bars, bars)
+
+	if err := writeJSONFileAtomic(c.path(dataset, symbol), file); err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to write bar cache file")
+	}
+	return file
+}
+
+// missingRanges returns the parts of want not inside the sorted,
+// non-overlapping covered windows.
+func missingRanges(covered []dbn_hist.DateRange, want dbn_hist.DateRange) []dbn_hist.DateRange {
+	var gaps []dbn_hist.DateRange
+	cursor := want.Start
+	for _, span := range covered {
+		if !span.End.After(cursor) {
+			continue
+		}
+		if !span.Start.Before(want.End) {
+			break
+		}
+		if span.Start.After(cursor) {
+			gaps = append(gaps, dbn_hist.DateRange{Start: cursor, End: span.Start})
+		}
+		cursor = span.End
+	}
+	if cursor.Before(want.End) {
+		gaps = append(gaps, dbn_hist.DateRange{Start: cursor, End: want.End})
+	}
+	return gaps
+}
+
+// addDateRange inserts span into covered, merging overlapping and adjacent
+// windows.
+func addDateRange(covered []dbn_hist.DateRange, span dbn_hist.DateRange) []dbn_hist.DateRange {
+	all := append(slices.Clone(covered), span)
+	slices.SortFunc(all, func(a, b dbn_hist.DateRange) int { return a.Start.Compare(b.Start) })
+
+	merged := all[:1]
+	for _, next := range all[1:] {
+		last := &merged[len(merged)-1]
+		if next.Start.After(last.End) {
+			merged = append(merged, next)
+			continue
+		}
+		if next.End.After(last.End) {
+			last.End = next.End
+		}
+	}
+	return merged
+}
+
+// weekendOnly reports whether every day in a daily window is a Saturday or
+// Sunday, which have no bars and need no request.
+func weekendOnly(span dbn_hist.DateRange) bool {
+	for day := span.Start; day.Before(span.End); day = day.AddDate(0, 0, 1) {
+		if weekday := day.Weekday(); weekday != time.Saturday && weekday != time.Sunday {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeBars combines two bar lists by date, preferring added, and returns
+// them sorted.
+func mergeBars(existing, added []HistoricalBar) []HistoricalBar {
+	byDate := make(map[int64]HistoricalBar, len(existing)+len(added))
+	for _, bar := range existing {
+		byDate[bar.Date.Unix()] = bar
+	}
+	for _, bar := range added {
+		byDate[bar.Date.Unix()] = bar
+	}
+	merged := make([]HistoricalBar, 0, len(byDate))
+	for _, bar := range byDate {
+		merged = append(merged, bar)
+	}
+	slices.SortFunc(merged, func(a, b HistoricalBar) int { return a.Date.Compare(b.Date) })
+	return merged
+}
+
+// barsInRange returns the sorted bars dated within span.
+func barsInRange(bars []HistoricalBar, span dbn_hist.DateRange) []HistoricalBar {
+	start, _ := slices.BinarySearchFunc(bars, span.Start, func(bar HistoricalBar, t time.Time) int { return bar.Date.Compare(t) })
+	end, _ := slices.BinarySearchFunc(bars, span.End, func(bar HistoricalBar, t time.Time) int { return bar.Date.Compare(t) })
+	if start >= end {
+		return nil
+	}
+	return slices.Clone(bars[start:end])
+}
+
+func formatDateRanges(spans []dbn_hist.DateRange) string {
+	parts := make([]string, len(spans))
+	for i, span := range spans {
+		parts[i] = fmt.Sprintf("%s/%s", span.Start.Format(time.RFC3339), span.End.Format(time.RFC3339))
+	}
+	return strings.Join(parts, ",")
+}
+
+// cacheFileName keeps a symbol or dataset name safe to use as a path element.
+func cacheFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
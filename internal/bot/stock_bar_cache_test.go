@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	dbn "github.com/NimbleMarkets/dbn-go"
+	dbn_hist "github.com/NimbleMarkets/dbn-go/hist"
+)
+
+type barCacheFetch struct {
+	symbols []string
+	span    dbn_hist.DateRange
+}
+
+// newFakeBarCache returns a cache in a temp dir whose downloads return one
+// weekday bar per day of the requested window and are recorded in calls.
+func newFakeBarCache(t *testing.T, calls *[]barCacheFetch) *dailyBarCache {
+	t.Helper()
+	cache := newDailyBarCache(t.TempDir())
+	cache.fetch = func(_ context.Context, symbols []string, schema dbn.Schema, span dbn_hist.DateRange) (map[string][]HistoricalBar, dbn_hist.DateRange, string, error) {
+		if schema != dbn.Schema_Ohlcv1D {
+			t.Fatalf("fetch schema = %v, want daily bars", schema)
+		}
+		*calls = append(*calls, barCacheFetch{symbols: slices.Clone(symbols), span: span})
+		out := make(map[string][]HistoricalBar, len(symbols))
+		for _, symbol := range symbols {
+			for day := span.Start; day.Before(span.End); day = day.AddDate(0, 0, 1) {
+				if !weekendOnly(dbn_hist.DateRange{Start: day, End: day.AddDate(0, 0, 1)}) {
+					out[symbol] = append(out[symbol], HistoricalBar{Date: day, Close: float64(day.Day())})
+				}
+			}
+		}
+		return out, span, "", nil
+	}
+	return cache
+}
+
+func dayRange(start time.Time, days int) dbn_hist.DateRange {
+	return dbn_hist.DateRange{Start: start, End: start.AddDate(0, 0, days)}
+}
+
+func TestDailyBarCacheRequestsOnlyMissingDays(t *testing.T) {
+	var calls []barCacheFetch
+	cache := newFakeBarCache(t, &calls)
+	ctx := context.Background()
+	monday := time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)
+
+	got, _, err := cache.bars(ctx, "EQUS.MINI", []string{"AAPL"}, dayRange(monday, 14))
+	if err != nil || len(got["AAPL"]) != 10 || len(calls) != 1 {
+		t.Fatalf("first bars() = %d bars, %d fetches, %v; want 10 bars from one fetch", len(got["AAPL"]), len(calls), err)
+	}
+
+	if _, _, err := cache.bars(ctx, "EQUS.MINI", []string{"AAPL"}, dayRange(monday.AddDate(0, 0, 2), 7)); err != nil || len(calls) != 1 {
+		t.Fatalf("cached window made %d fetches (%v), want none", len(calls)-1, err)
+	}
+
+	// Widening the window on both sides fetches just the two new edges.
+	got, _, err = cache.bars(ctx, "EQUS.MINI", []string{"AAPL"}, dayRange(monday.AddDate(0, 0, -7), 28))
+	if err != nil {
+		t.Fatalf("wider bars() error: %v", err)
+	}
+	if len(calls) != 3 || !calls[1].span.End.Equal(monday) || !calls[2].span.Start.Equal(monday.AddDate(0, 0, 14)) {
+		t.Fatalf("fetches = %+v, want only the week before and the week after the cached window", calls)
+	}
+	if len(got["AAPL"]) != 20 {
+		t.Fatalf("wider bars() = %d bars, want 20 weekdays", len(got["AAPL"]))
+	}
+	for i := 1; i < len(got["AAPL"]); i++ {
+		if !got["AAPL"][i-1].Date.Before(got["AAPL"][i].Date) {
+			t.Fatal("merged bars are not sorted by date")
+		}
+	}
+}
+
+func TestDailyBarCacheBatchesSymbolsWithSameGaps(t *testing.T) {
+	var calls []barCacheFetch
+	cache := newFakeBarCache(t, &calls)
+	ctx := context.Background()
+	window := dayRange(time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC), 14)
+
+	if _, _, err := cache.bars(ctx, "EQUS.MINI", []string{"AAPL"}, window); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := cache.bars(ctx, "EQUS.MINI", []string{"AAPL", "MSFT", "QQQ"}, window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || strings.Join(calls[1].symbols, ",") != "MSFT,QQQ" {
+		t.Fatalf("fetches = %+v, want one batched request for MSFT and QQQ", calls)
+	}
+	if len(got) != 3 || len(got["QQQ"]) != 10 {
+		t.Fatalf("bars() = %v, want 10 bars for each symbol", got)
+	}
+
+	// Another dataset is a separate cache.
+	if _, _, err := cache.bars(ctx, "XNAS.ITCH", []string{"AAPL"}, window); err != nil || len(calls) != 3 {
+		t.Fatalf("other dataset made %d fetches (%v), want a new request", len(calls), err)
+	}
+}
+
+func TestDailyBarCacheSkipsWeekendGaps(t *testing.T) {
+	var calls []barCacheFetch
+	cache := newFakeBarCache(t, &calls)
+	ctx := context.Background()
+	monday := time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)
+
+	if _, _, err := cache.bars(ctx, "EQUS.MINI", []string{"AAPL"}, dayRange(monday, 5)); err != nil {
+		t.Fatal(err)
+	}
+	// Monday through the following Monday only adds a weekend.
+	got, _, err := cache.bars(ctx, "EQUS.MINI", []string{"AAPL"}, dayRange(monday, 7))
+	if err != nil || len(calls) != 1 || len(got["AAPL"]) != 5 {
+		t.Fatalf("bars() = %d bars, %d fetches, %v; want the 5 cached bars and no fetch", len(got["AAPL"]), len(calls), err)
+	}
+}
+
+func TestDailyBarCacheKeepsAdjustedWindowAndErrors(t *testing.T) {
+	cache := newDailyBarCache(t.TempDir())
+	monday := time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)
+	want := dayRange(monday, 14)
+	adjusted := dayRange(monday.AddDate(0, 0, -3), 14)
+	cache.fetch = func(context.Context, []string, dbn.Schema, dbn_hist.DateRange) (map[string][]HistoricalBar, dbn_hist.DateRange, string, error) {
+		bars := []HistoricalBar{{Date: adjusted.Start.AddDate(0, 0, 1), Close: 1}, {Date: monday, Close: 2}}
+		return map[string][]HistoricalBar{"AAPL": bars}, adjusted, "Note: lagged", nil
+	}
+
+	got, note, err := cache.bars(context.Background(), "EQUS.MINI", []string{"AAPL"}, want)
+	if err != nil || note != "Note: lagged" || len(got["AAPL"]) != 2 {
+		t.Fatalf("bars() = %v, %q, %v; want both bars of the adjusted window and its note", got, note, err)
+	}
+	// Only the adjusted window counts as covered; the days after it are
+	// requested again.
+	file := cache.load("EQUS.MINI", "AAPL")
+	if len(file.Covered) != 1 || file.Covered[0] != adjusted {
+		t.Fatalf("covered = %v, want %v", file.Covered, adjusted)
+	}
+
+	errDown := errors.New("databento down")
+	cache.fetch = func(context.Context, []string, dbn.Schema, dbn_hist.DateRange) (map[string][]HistoricalBar, dbn_hist.DateRange, string, error) {
+		return nil, dbn_hist.DateRange{}, "", errDown
+	}
+	if _, _, err := cache.bars(context.Background(), "EQUS.MINI", []string{"AAPL"}, want); !errors.Is(err, errDown) {
+		t.Fatalf("bars() error = %v, want the fetch error", err)
+	}
+}
+
+func TestDailyBarCacheIgnoresCorruptFile(t *testing.T) {
+	var calls []barCacheFetch
+	cache := newFakeBarCache(t, &calls)
+	window := dayRange(time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC), 7)
+	path := cache.path("EQUS.MINI", "BRK.B")
+	if err := writeJSONFileAtomic(path, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _, err := cache.bars(context.Background(), "EQUS.MINI", []string{"BRK.B"}, window)
+	if err != nil || len(calls) != 1 || len(got["BRK.B"]) != 5 {
+		t.Fatalf("bars() = %v, %v; want a fresh download", got, err)
+	}
+	if file := cache.load("EQUS.MINI", "BRK.B"); len(file.Bars) != 5 {
+		t.Fatalf("rewritten cache has %d bars, want 5", len(file.Bars))
+	}
+}
+
+func TestMissingAndAddDateRanges(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2026, 9, d, 0, 0, 0, 0, time.UTC) }
+	span := func(a, b int) dbn_hist.DateRange { return dbn_hist.DateRange{Start: day(a), End: day(b)} }
+
+	covered := addDateRange(nil, span(10, 12))
+	covered = addDateRange(covered, span(3, 5))
+	covered = addDateRange(covered, span(5, 7)) // adjacent to 3-5
+	if len(covered) != 2 || covered[0] != span(3, 7) || covered[1] != span(10, 12) {
+		t.Fatalf("addDateRange() = %v, want [3-7 10-12]", covered)
+	}
+
+	gaps := missingRanges(covered, span(1, 15))
+	if len(gaps) != 3 || gaps[0] != span(1, 3) || gaps[1] != span(7, 10) || gaps[2] != span(12, 15) {
+		t.Fatalf("missingRanges() = %v, want [1-3 7-10 12-15]", gaps)
+	}
+	if gaps := missingRanges(covered, span(4, 6)); len(gaps) != 0 {
+		t.Fatalf("missingRanges() inside coverage = %v, want none", gaps)
+	}
+	if gaps := missingRanges(nil, span(4, 6)); len(gaps) != 1 || gaps[0] != span(4, 6) {
+		t.Fatalf("missingRanges() with no coverage = %v", gaps)
+	}
+}
+
+func TestFetchDailyBarsUsesCache(t *testing.T) {
+	var calls []barCacheFetch
+	cache := newFakeBarCache(t, &calls)
+	orig := dailyBarCacheInstance
+	dailyBarCacheInstance = cache
+	t.Cleanup(func() { dailyBarCacheInstance = orig })
+	t.Setenv("DATABENTO_DATASET", "")
+
+	window := dayRange(time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC), 7)
+	for range 2 {
+		if _, _, err := fetchDailyBars(context.Background(), []string{testSymbolAAPL}, window); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(calls) != 1 {
+		t.Fatalf("fetchDailyBars() made %d downloads, want 1", len(calls))
+	}
+	if _, err := os.Stat(cache.path("EQUS.MINI", testSymbolAAPL)); err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+}
+
+func TestInitDailyBarCache(t *testing.T) {
+	orig := dailyBarCacheInstance
+	t.Cleanup(func() { dailyBarCacheInstance = orig })
+
+	t.Setenv("BOT_DATA_DIR", "/var/lib/bot")
+	t.Setenv("DATABENTO_CACHE_DIR", "")
+	initDailyBarCache()
+	if dailyBarCacheInstance == nil || dailyBarCacheInstance.dir != "/var/lib/bot/bars" {
+		t.Fatalf("cache = %+v, want it under BOT_DATA_DIR", dailyBarCacheInstance)
+	}
+	if got := dailyBarCacheInstance.path("EQUS.MINI", "BRK/B"); got != "/var/lib/bot/bars/EQUS.MINI/ohlcv-1d/BRK_B.json" {
+		t.Errorf("path = %q", got)
+	}
+
+	t.Setenv("DATABENTO_CACHE_DIR", "off")
+	initDailyBarCache()
+	if dailyBarCacheInstance != nil {
+		t.Fatal("DATABENTO_CACHE_DIR=off should disable the cache")
+	}
+}
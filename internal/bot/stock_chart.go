@@ -0,0 +1,309 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/go-analyze/charts"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	chartStyleCandle = "candle"
+
+	// Composite charts are drawn on a fixed-width canvas: the price panel on
+	// top and one shorter sub-panel per extra series (volume, RSI, MACD)
+	// below it, all sharing the same date labels.
+	stockChartWidth       = 800
+	stockPricePanelHeight = 440
+	stockSubPanelHeight   = 160
+
+	// maxIndicatorWarmupDays caps the extra history fetched for indicators.
+	maxIndicatorWarmupDays = 2 * 366
+)
+
+// overlayColors keeps SMA/EMA lines apart from the green/red candles.
+var overlayColors = []charts.Color{charts.ColorOrange, charts.ColorPurple, charts.ColorTeal, charts.ColorChocolate, charts.ColorFuchsia}
+
+// stockChartOptions are the modifiers that may follow a `!s` range, e.g.
+// `!s AAPL 30d candle sma20 rsi`. The zero value draws the default
+// close-price line.
+type stockChartOptions struct {
+	candle     bool
+	indicators []indicatorSpec
+}
+
+func (o stockChartOptions) isZero() bool {
+	return !o.candle && len(o.indicators) == 0
+}
+
+// warmupDays is how many calendar days before the visible window to fetch
+// so the indicators are already settled on the first visible bar of a chart
+// drawn with interval bars.
+func (o stockChartOptions) warmupDays(interval barInterval) int {
+	bars := 0
+	for _, spec := range o.indicators {
+		bars = max(bars, spec.lookback())
+	}
+	return min(indicatorWarmupDays(bars, interval), maxIndicatorWarmupDays)
+}
+
+// checkWarmup rejects indicators whose warm-up on interval bars would need
+// more than maxIndicatorWarmupDays of history, since they would never
+// settle on the chart.
+func (o stockChartOptions) checkWarmup(interval barInterval) error {
+	for _, spec := range o.indicators {
+		if indicatorWarmupDays(spec.lookback(), interval) > maxIndicatorWarmupDays {
+			return fmt.Errorf("%s needs more history than a chart of %s bars can load, use a shorter range or period", strings.ToLower(spec.name()), interval)
+		}
+	}
+	return nil
+}
+
+// indicatorWarmupDays converts a lookback in bars of interval to calendar
+// days.
+func indicatorWarmupDays(bars int, interval barInterval) int {
+	if bars == 0 {
+		return 0
+	}
+	switch interval {
+	case barIntervalWeekly:
+		return (bars + 1) * 7
+	case barIntervalMonthly:
+		return (bars + 1) * 31
+	default:
+		// Trading days to calendar days, with slack for holidays.
+		return bars*7/5 + 10
+	}
+}
+
+// isStockChartOption reports whether token is a chart modifier word.
+func isStockChartOption(token string) bool {
+	return strings.EqualFold(token, chartStyleCandle) || isIndicatorToken(token)
+}
+
+// parseStockChartOptions parses the tokens after a range. Unknown tokens are
+// rejected so a typo does not silently draw the default chart.
+func parseStockChartOptions(tokens []string) (stockChartOptions, error) {
+	var opts stockChartOptions
+	for _, token := range tokens {
+		if strings.EqualFold(token, chartStyleCandle) {
+			opts.candle = true
+			continue
+		}
+		if !isIndicatorToken(token) {
+			return stockChartOptions{}, fmt.Errorf("unknown chart option %q, use candle, sma, ema, rsi, macd or bb (e.g., !s AAPL 90d candle sma20 rsi)", token)
+		}
+		spec, err := parseIndicatorToken(token)
+		if err != nil {
+			return stockChartOptions{}, err
+		}
+		if !slices.Contains(opts.indicators, spec) {
+			opts.indicators = append(opts.indicators, spec)
+		}
+	}
+	if len(opts.indicators) > maxChartIndicators {
+		return stockChartOptions{}, fmt.Errorf("too many indicators, use up to %d", maxChartIndicators)
+	}
+	return opts, nil
+}
+
+// renderStockChartPNG draws bars in the requested style. warmup holds the
+// bars before the visible window; they only feed the indicators. A
+// composite chart that fails to render falls back to the plain close-price
+// line so the user still gets a picture.
+func renderStockChartPNG(symbol, label string, warmup, bars []HistoricalBar, opts stockChartOptions) ([]byte, error) {
+	if !opts.isZero() {
+		chart, err := renderCompositeChartPNG(symbol, label, warmup, bars, opts)
+		if err == nil {
+			return chart, nil
+		}
+		log.Warn().Err(err).Str("symbol", symbol).Str("range", label).Msg("Failed to render composite chart; falling back to line chart")
+	}
+	return renderHistoricalChartPNG(symbol, label, bars)
+}
+
+// renderCompositeChartPNG renders the price panel (candles or a close line,
+// plus SMA/EMA/Bollinger overlays) and the volume, RSI and MACD sub-panels.
+// Candles and volume bars use the theme's up/down colors.
+func renderCompositeChartPNG(symbol, label string, warmup, bars []HistoricalBar, opts stockChartOptions) ([]byte, error) {
+	if len(bars) == 0 {
+		return nil, errors.New("no bars to chart")
+	}
+
+	layout := chartDateLayout(bars)
+	labels := make([]string, 0, len(bars))
+	for _, bar := range bars {
+		labels = append(labels, bar.Date.Format(layout))
+	}
+
+	closes := make([]float64, 0, len(warmup)+len(bars))
+	for _, bar := range warmup {
+		closes = append(closes, bar.Close)
+	}
+	for _, bar := range bars {
+		closes = append(closes, bar.Close)
+	}
+	var overlays, rsiSeries, macdSeries []indicatorSeries
+	for _, series := range computeIndicatorSeries(opts.indicators, closes) {
+		series.values = series.values[len(warmup):]
+		switch series.panel {
+		case panelRSI:
+			rsiSeries = append(rsiSeries, series)
+		case panelMACD:
+			macdSeries = append(macdSeries, series)
+		default:
+			overlays = append(overlays, series)
+		}
+	}
+
+	var panels []charts.ChartOption
+	if opts.candle {
+		panels = append(panels, volumePanelOption(bars, labels))
+	}
+	if len(rsiSeries) > 0 {
+		panels = append(panels, indicatorPanelOption(rsiSeries, labels, charts.Ptr(0.0), charts.Ptr(100.0)))
+	}
+	if len(macdSeries) > 0 {
+		panels = append(panels, indicatorPanelOption(macdSeries, labels, nil, nil))
+	}
+
+	height := stockPricePanelHeight + len(panels)*stockSubPanelHeight
+	top := stockPricePanelHeight
+	for i := range panels {
+		panels[i].Box = charts.NewBox(0, top, stockChartWidth, top+stockSubPanelHeight)
+		if i < len(panels)-1 {
+			panels[i].XAxis.Show = charts.Ptr(false)
+		}
+		top += stockSubPanelHeight
+	}
+
+	names := []string{symbol}
+	price := charts.GenericSeries{Type: charts.ChartTypeLine, Name: symbol}
+	if opts.candle {
+		price.Type = charts.ChartTypeCandlestick
+		for _, bar := range bars {
+			price.Values = append(price.Values, bar.Open, bar.High, bar.Low, bar.Close)
+		}
+	} else {
+		price.Values = closes[len(warmup):]
+	}
+	seriesList := charts.GenericSeriesList{price}
+	colors := []charts.Color{charts.ColorNavy}
+	for i, series := range overlays {
+		names = append(names, series.name)
+		seriesList = append(seriesList, charts.GenericSeries{Type: charts.ChartTypeLine, Name: series.name, Values: chartValues(series.values)})
+		if series.band {
+			colors = append(colors, charts.ColorGray)
+		} else {
+			colors = append(colors, overlayColors[i%len(overlayColors)])
+		}
+	}
+
+	opt := charts.ChartOption{
+		OutputFormat:    charts.ChartOutputPNG,
+		Width:           stockChartWidth,
+		Height:          height,
+		Theme:           charts.GetDefaultTheme().WithSeriesColors(colors),
+		Box:             charts.NewBox(0, 0, stockChartWidth, stockPricePanelHeight),
+		Title:           charts.TitleOption{Text: fmt.Sprintf("%s %s", symbol, strings.ToUpper(label))},
+		Legend:          charts.LegendOption{SeriesNames: names, Show: charts.Ptr(len(names) > 1)},
+		XAxis:           charts.XAxisOption{Labels: labels, Show: charts.Ptr(len(panels) == 0)},
+		SeriesList:      seriesList,
+		Symbol:          charts.Symbol{Shape: charts.SymbolNone},
+		LineStrokeWidth: 1.2,
+		BarSize:         0.8,
+		Children:        panels,
+		ValueFormatter: func(f float64) string {
+			return fmt.Sprintf("%.2f", f)
+		},
+	}
+	p, err := charts.Render(opt)
+	if err != nil {
+		return nil, err
+	}
+	return p.Bytes()
+}
+
+// volumePanelOption draws volume as two stacked bar series, one per candle
+// direction, so each bar takes the color of its candle.
+func volumePanelOption(bars []HistoricalBar, labels []string) charts.ChartOption {
+	upVolume := make([]float64, 0, len(bars))
+	downVolume := make([]float64, 0, len(bars))
+	for _, bar := range bars {
+		if bar.Close >= bar.Open {
+			upVolume = append(upVolume, float64(bar.Volume))
+			downVolume = append(downVolume, 0)
+		} else {
+			upVolume = append(upVolume, 0)
+			downVolume = append(downVolume, float64(bar.Volume))
+		}
+	}
+	theme := charts.GetDefaultTheme()
+	up, down := theme.GetSeriesUpDownColors(0)
+	return charts.ChartOption{
+		Theme:          theme.WithSeriesColors([]charts.Color{up, down}),
+		SeriesList:     charts.NewSeriesListGeneric([][]float64{upVolume, downVolume}, charts.ChartTypeBar),
+		StackSeries:    charts.Ptr(true),
+		Legend:         charts.LegendOption{Show: charts.Ptr(false)},
+		XAxis:          charts.XAxisOption{Labels: labels},
+		ValueFormatter: formatCompactVolume,
+	}
+}
+
+// indicatorPanelOption draws RSI or MACD series in their own panel with an
+// optional fixed value range (0-100 for RSI).
+func indicatorPanelOption(series []indicatorSeries, labels []string, minValue, maxValue *float64) charts.ChartOption {
+	names := make([]string, 0, len(series))
+	list := make(charts.GenericSeriesList, 0, len(series))
+	for _, s := range series {
+		chartType := charts.ChartTypeLine
+		if s.histogram {
+			chartType = charts.ChartTypeBar
+		}
+		names = append(names, s.name)
+		list = append(list, charts.GenericSeries{Type: chartType, Name: s.name, Values: chartValues(s.values)})
+	}
+	return charts.ChartOption{
+		Theme:           charts.GetDefaultTheme(),
+		SeriesList:      list,
+		Legend:          charts.LegendOption{SeriesNames: names, Show: charts.Ptr(true)},
+		XAxis:           charts.XAxisOption{Labels: labels},
+		YAxis:           []charts.YAxisOption{{Min: minValue, Max: maxValue}},
+		Symbol:          charts.Symbol{Shape: charts.SymbolNone},
+		LineStrokeWidth: 1.2,
+		ValueFormatter: func(f float64) string {
+			return fmt.Sprintf("%.1f", f)
+		},
+	}
+}
+
+// chartValues maps NaN (an indicator's undefined head) to the charts
+// library's null value so the line simply starts later.
+func chartValues(values []float64) []float64 {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		if math.IsNaN(v) {
+			v = charts.GetNullValue()
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// formatCompactVolume keeps volume axis labels short (e.g. 12.3M).
+func formatCompactVolume(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.1fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.0fK", v/1e3)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func candleTestBars(n int) []HistoricalBar {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	bars := make([]HistoricalBar, 0, n)
+	price := 100.0
+	for i := range n {
+		open := price
+		if i%3 == 0 {
+			price -= 2
+		} else {
+			price += 3
+		}
+		bars = append(bars, HistoricalBar{
+			Date:   start.AddDate(0, 0, i),
+			Open:   open,
+			High:   max(open, price) + 1,
+			Low:    min(open, price) - 1,
+			Close:  price,
+			Volume: uint64(1_000_000 + i*250_000),
+		})
+	}
+	return bars
+}
+
+var pngSignature = []byte{137, 80, 78, 71, 13, 10, 26, 10}
+
+func TestRenderCompositeChartPNG(t *testing.T) {
+	t.Parallel()
+
+	bars := candleTestBars(60)
+	for name, opts := range map[string]stockChartOptions{
+		"candle":            {candle: true},
+		"line with overlay": {indicators: []indicatorSpec{{kind: indicatorSMA, period: 5}, {kind: indicatorBollinger, period: bollingerPeriod}}},
+		"candle with panels": {candle: true, indicators: []indicatorSpec{
+			{kind: indicatorEMA, period: 10}, {kind: indicatorRSI, period: 14}, {kind: indicatorMACD},
+		}},
+	} {
+		buf, err := renderCompositeChartPNG(testSymbolAAPL, "30d", bars[:30], bars[30:], opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !bytes.HasPrefix(buf, pngSignature) {
+			t.Fatalf("%s: expected PNG output", name)
+		}
+	}
+}
+
+func TestRenderStockChartPNGDefaultsToLine(t *testing.T) {
+	t.Parallel()
+
+	if _, err := renderCompositeChartPNG(testSymbolAAPL, "30d", nil, nil, stockChartOptions{candle: true}); err == nil {
+		t.Fatal("expected an error for an empty candle chart")
+	}
+	bars := candleTestBars(10)
+	want, err := renderHistoricalChartPNG(testSymbolAAPL, "30d", bars)
+	if err != nil {
+		t.Fatalf("line chart: %v", err)
+	}
+	got, err := renderStockChartPNG(testSymbolAAPL, "30d", nil, bars, stockChartOptions{})
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("expected the default style to be the line chart, err=%v", err)
+	}
+}
+
+func TestParseStockChartOptions(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseStockChartOptions([]string{"Candle", "sma50", "SMA50", "rsi", "macd", "bb"})
+	if err != nil || !opts.candle {
+		t.Fatalf("parseStockChartOptions = %+v, %v", opts, err)
+	}
+	names := make([]string, 0, len(opts.indicators))
+	for _, spec := range opts.indicators {
+		names = append(names, spec.name())
+	}
+	if got := strings.Join(names, ","); got != "SMA50,RSI14,MACD(12,26,9),BB(20,2)" {
+		t.Fatalf("indicators = %s", got)
+	}
+
+	for tokens, errSubstr := range map[string]string{
+		"candle heikin":                   "unknown chart option",
+		"sma1":                            "invalid indicator period",
+		"ema500":                          "invalid indicator period",
+		"sma5 sma10 sma20 ema5 ema10 rsi": "too many indicators",
+	} {
+		if _, err := parseStockChartOptions(strings.Fields(tokens)); err == nil || !strings.Contains(err.Error(), errSubstr) {
+			t.Errorf("parseStockChartOptions(%q) error = %v, want %q", tokens, err, errSubstr)
+		}
+	}
+}
+
+func TestStockChartOptionsWarmupDays(t *testing.T) {
+	t.Parallel()
+
+	if got := (stockChartOptions{candle: true}).warmupDays(barIntervalDaily); got != 0 {
+		t.Fatalf("expected no warm-up without indicators, got %d", got)
+	}
+	sma50 := stockChartOptions{indicators: []indicatorSpec{{kind: indicatorSMA, period: 50}}}
+	if got := sma50.warmupDays(barIntervalDaily); got != 80 {
+		t.Fatalf("SMA50 daily warm-up = %d, want 80", got)
+	}
+	if got := sma50.warmupDays(barIntervalMonthly); got != maxIndicatorWarmupDays {
+		t.Fatalf("SMA50 monthly warm-up = %d, want the cap", got)
+	}
+}
+
+func TestStockChartOptionsCheckWarmup(t *testing.T) {
+	t.Parallel()
+
+	sma50 := stockChartOptions{indicators: []indicatorSpec{{kind: indicatorSMA, period: 20}, {kind: indicatorSMA, period: 50}}}
+	if err := sma50.checkWarmup(barIntervalWeekly); err != nil {
+		t.Fatalf("SMA50 on weekly bars: %v", err)
+	}
+	err := sma50.checkWarmup(barIntervalMonthly)
+	if err == nil || !strings.Contains(err.Error(), "sma50 needs more history") {
+		t.Fatalf("SMA50 on monthly bars error = %v, want it rejected", err)
+	}
+}
+
+func TestFormatCompactVolume(t *testing.T) {
+	t.Parallel()
+
+	for v, want := range map[float64]string{
+		950:           "950",
+		12_400:        "12K",
+		3_450_000:     "3.5M",
+		1_200_000_000: "1.2B",
+	} {
+		if got := formatCompactVolume(v); got != want {
+			t.Errorf("formatCompactVolume(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package bot
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	dbn_hist "github.com/NimbleMarkets/dbn-go/hist"
+	"github.com/go-analyze/charts"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	compareCommand = "!cmp"
+
+	// maxCompareSymbols keeps the legend and the caption readable.
+	maxCompareSymbols   = 6
+	defaultCompareRange = "90d"
+
+	invalidUsageCompare = "invalid usage, use !cmp SYMBOL SYMBOL... [RANGE] (e.g., !cmp AAPL MSFT QQQ 90d)"
+)
+
+// symbolPerformance is one symbol's closes rebased to 100 on the first
+// common date, with its total return and max drawdown in percent.
+type symbolPerformance struct {
+	symbol      string
+	rebased     []float64
+	totalReturn float64
+	maxDrawdown float64
+}
+
+// performanceComparison lines several symbols up on the dates they all
+// traded. missing lists the requested symbols that returned no bars.
+type performanceComparison struct {
+	dates   []time.Time
+	series  []symbolPerformance
+	missing []string
+}
+
+// parseCompareCommand parses `!cmp AAPL MSFT QQQ 90d`: two or more symbols
+// and an optional trailing range (90d by default). Symbols are uppercased
+// and deduplicated.
+func parseCompareCommand(text string) ([]string, stockRange, error) {
+	rest, ok := strings.CutPrefix(text, compareCommand)
+	if !ok || (rest != "" && !startsWithSpace(rest)) {
+		return nil, stockRange{}, errors.New(invalidUsageCompare)
+	}
+	parts := strings.Fields(rest)
+
+	rangeToken := defaultCompareRange
+	if len(parts) > 0 && isStockRangeToken(parts[len(parts)-1]) {
+		rangeToken = parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+	}
+
+	var symbols []string
+	for _, part := range parts {
+		if isStockRangeToken(part) {
+			return nil, stockRange{}, errors.New(invalidUsageCompare)
+		}
+		symbol := strings.ToUpper(part)
+		if !symbolRegex.MatchString(symbol) {
+			return nil, stockRange{}, fmt.Errorf("invalid stock symbol %q, use 1-10 characters: letters, numbers, dots (.) or dashes (-)", part)
+		}
+		if !slices.Contains(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) < 2 {
+		return nil, stockRange{}, errors.New(invalidUsageCompare)
+	}
+	if len(symbols) > maxCompareSymbols {
+		return nil, stockRange{}, fmt.Errorf("too many symbols, use up to %d (e.g., !cmp AAPL MSFT QQQ 90d)", maxCompareSymbols)
+	}
+
+	rng, err := parseStockRange(rangeToken, nowFunc())
+	if err != nil {
+		return nil, stockRange{}, err
+	}
+	if rng.isIntraday() {
+		return nil, stockRange{}, errors.New("comparisons use daily bars, use 7d or longer (e.g., !cmp AAPL MSFT 30d)")
+	}
+	return symbols, rng, nil
+}
+
+func compareHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	symbols, rng, err := parseCompareCommand(update.Message.Text)
+	if err != nil {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          update.Message.Chat.ID,
+			MessageThreadID: update.Message.MessageThreadID,
+			Text:            err.Error(),
+		})
+		return
+	}
+
+	var allowed, blocked []string
+	for _, symbol := range symbols {
+		if _, isBlocked := blockedStockResponse(symbol); isBlocked {
+			blocked = append(blocked, symbol)
+		} else {
+			allowed = append(allowed, symbol)
+		}
+	}
+	if len(allowed) == 0 {
+		appotel.RecordOutcome(ctx, "blocked")
+		msg, _ := blockedStockResponse(blocked[0])
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          update.Message.Chat.ID,
+			MessageThreadID: update.Message.MessageThreadID,
+			Text:            msg,
+			ReplyParameters: &models.ReplyParameters{
+				MessageID:                update.Message.ID,
+				AllowSendingWithoutReply: true,
+			},
+		})
+		return
+	}
+
+	label := rng.label()
+	loadingMsg, loadingErr := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: update.Message.MessageThreadID,
+		Text:            fmt.Sprintf("Fetching %s performance for %s...", label, strings.Join(allowed, ", ")),
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                update.Message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+	if loadingErr != nil {
+		log.Warn().Err(loadingErr).Strs("symbols", allowed).Str("range", label).Msg("Failed to send compare loading state")
+	}
+
+	barsBySymbol, adjustedNote, err := fetchCompareBars(ctx, allowed, rng)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to fetch %s historical data for %s. Please try again later.", label, strings.Join(allowed, ", "))
+		if errors.Is(err, errDatabentoAPIKeyNotConfigured) {
+			appotel.RecordOutcome(ctx, "not_configured")
+			msg = "Historical data is unavailable: DATABENTO_API_KEY is not configured."
+		} else {
+			appotel.RecordOutcome(ctx, "error")
+		}
+		log.Error().Err(err).Strs("symbols", allowed).Str("range", label).Msg("Failed to fetch comparison bars")
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, msg)
+		return
+	}
+	appotel.RecordOutcome(ctx, "success")
+
+	comparison := comparePerformance(allowed, barsBySymbol)
+	caption := formatComparisonSummary(label, comparison, blocked)
+	if adjustedNote != "" {
+		caption += "\n" + adjustedNote
+	}
+	if len(comparison.series) == 0 {
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, caption)
+		return
+	}
+
+	chartPNG, err := renderComparisonChartPNG(label, comparison)
+	if err != nil {
+		log.Warn().Err(err).Strs("symbols", allowed).Str("range", label).Msg("Failed to render comparison chart; sending text only")
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, caption)
+		return
+	}
+
+	updateStockLoadingState(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Fetched %s performance for %s. Sending chart...", label, strings.Join(allowed, ", ")))
+	_, sendErr := b.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: update.Message.MessageThreadID,
+		Photo: &models.InputFileUpload{
+			Filename: historicalFileLabel("cmp-"+strings.Join(allowed, "-"), rng),
+			Data:     bytes.NewReader(chartPNG),
+		},
+		Caption: caption,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                update.Message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+	if sendErr == nil {
+		updateStockLoadingState(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("Done. Sent %s comparison for %s.", label, strings.Join(allowed, ", ")))
+		return
+	}
+
+	log.Warn().Err(sendErr).Strs("symbols", allowed).Str("range", label).Msg("Failed to send comparison chart image; sending text only")
+	sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, caption)
+}
+
+// fetchCompareBars asks Databento for all symbols in one request. If that
+// request fails for any reason other than missing configuration, each symbol
+// is fetched on its own so one bad symbol does not sink the comparison.
+func fetchCompareBars(ctx context.Context, symbols []string, rng stockRange) (map[string][]HistoricalBar, string, error) {
+	dateRange := rng.dateRange(nowFunc())
+	if days := rangeDays(dateRange); days < 1 || days > maxStockRangeDays {
+		return nil, "", errors.New("historical range must be between 1 day and 5 years")
+	}
+
+	barsBySymbol, adjustedNote, err := fetchDailyBars(ctx, symbols, dateRange)
+	if err == nil || errors.Is(err, errDatabentoAPIKeyNotConfigured) {
+		return barsBySymbol, adjustedNote, err
+	}
+	log.Warn().Err(err).Strs("symbols", symbols).Msg("Batched comparison request failed; fetching symbols one by one")
+	return fetchDailyBarsEach(ctx, symbols, dateRange)
+}
+
+// fetchDailyBarsEach fetches every symbol concurrently and fails only when
+// all of them do.
+func fetchDailyBarsEach(ctx context.Context, symbols []string, dateRange dbn_hist.DateRange) (map[string][]HistoricalBar, string, error) {
+	var (
+		mu           sync.Mutex
+		wg           sync.WaitGroup
+		barsBySymbol = make(map[string][]HistoricalBar, len(symbols))
+		adjustedNote string
+		errs         []error
+	)
+	for _, symbol := range symbols {
+		wg.Go(func() {
+			bars, note, err := fetchDailyBars(ctx, []string{symbol}, dateRange)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to fetch comparison bars")
+				errs = append(errs, err)
+				return
+			}
+			barsBySymbol[symbol] = bars[symbol]
+			adjustedNote = cmp.Or(adjustedNote, note)
+		})
+	}
+	wg.Wait()
+	if len(errs) == len(symbols) {
+		return nil, "", errors.Join(errs...)
+	}
+	return barsBySymbol, adjustedNote, nil
+}
+
+// comparePerformance aligns the symbols on the dates every one of them has
+// a bar for, starting at their first common date, and rebases each close
+// series to 100 there. Symbols without usable bars are reported as missing.
+func comparePerformance(symbols []string, barsBySymbol map[string][]HistoricalBar) performanceComparison {
+	var result performanceComparison
+	var present []string
+	closes := make(map[string]map[time.Time]float64, len(symbols))
+	dateCounts := map[time.Time]int{}
+	for _, symbol := range symbols {
+		byDate := map[time.Time]float64{}
+		for _, bar := range barsBySymbol[symbol] {
+			if bar.Close > 0 {
+				byDate[bar.Date] = bar.Close
+			}
+		}
+		if len(byDate) == 0 {
+			result.missing = append(result.missing, symbol)
+			continue
+		}
+		present = append(present, symbol)
+		closes[symbol] = byDate
+		for date := range byDate {
+			dateCounts[date]++
+		}
+	}
+
+	for date, count := range dateCounts {
+		if count == len(present) {
+			result.dates = append(result.dates, date)
+		}
+	}
+	if len(result.dates) == 0 {
+		result.missing = append(result.missing, present...)
+		return result
+	}
+	slices.SortFunc(result.dates, func(a, b time.Time) int { return a.Compare(b) })
+
+	for _, symbol := range present {
+		base := closes[symbol][result.dates[0]]
+		perf := symbolPerformance{symbol: symbol, rebased: make([]float64, len(result.dates))}
+		peak := 0.0
+		for i, date := range result.dates {
+			value := closes[symbol][date] / base * 100
+			perf.rebased[i] = value
+			peak = max(peak, value)
+			perf.maxDrawdown = min(perf.maxDrawdown, (value/peak-1)*100)
+		}
+		perf.totalReturn = perf.rebased[len(perf.rebased)-1] - 100
+		result.series = append(result.series, perf)
+	}
+	return result
+}
+
+// formatComparisonSummary renders the caption: one line per symbol, best
+// total return first, followed by the symbols that could not be compared.
+func formatComparisonSummary(label string, comparison performanceComparison, blocked []string) string {
+	var sb strings.Builder
+	if len(comparison.series) == 0 {
+		fmt.Fprintf(&sb, "No overlapping historical data returned (%s).", label)
+	} else {
+		fmt.Fprintf(&sb, "Performance %s (%s to %s), rebased to 100",
+			label,
+			comparison.dates[0].Format(dateFormatPattern),
+			comparison.dates[len(comparison.dates)-1].Format(dateFormatPattern),
+		)
+		ranked := slices.Clone(comparison.series)
+		slices.SortStableFunc(ranked, func(a, b symbolPerformance) int {
+			return cmp.Compare(b.totalReturn, a.totalReturn)
+		})
+		for _, perf := range ranked {
+			fmt.Fprintf(&sb, "\n%s: %+.2f%% · max drawdown %.2f%%", perf.symbol, perf.totalReturn, perf.maxDrawdown)
+		}
+	}
+	if len(comparison.missing) > 0 {
+		sb.WriteString("\nNo data: " + strings.Join(comparison.missing, ", "))
+	}
+	if len(blocked) > 0 {
+		sb.WriteString("\nUnavailable: " + strings.Join(blocked, ", "))
+	}
+	return sb.String()
+}
+
+// renderComparisonChartPNG draws every rebased series on one line chart.
+// Long windows use the same weekly/monthly sampling as `!s` charts.
+func renderComparisonChartPNG(label string, comparison performanceComparison) ([]byte, error) {
+	if len(comparison.series) == 0 {
+		return nil, errors.New("no series to chart")
+	}
+	interval := barIntervalFor(dbn_hist.DateRange{Start: comparison.dates[0], End: comparison.dates[len(comparison.dates)-1]})
+
+	names := make([]string, 0, len(comparison.series))
+	values := make([][]float64, 0, len(comparison.series))
+	var sampled []HistoricalBar
+	for _, perf := range comparison.series {
+		bars := make([]HistoricalBar, len(comparison.dates))
+		for i, date := range comparison.dates {
+			bars[i] = HistoricalBar{Date: date, Close: perf.rebased[i]}
+		}
+		sampled = downsampleBars(bars, interval)
+		names = append(names, perf.symbol)
+		values = append(values, closePrices(sampled))
+	}
+	layout := chartDateLayout(sampled)
+	labels := make([]string, 0, len(sampled))
+	for _, bar := range sampled {
+		labels = append(labels, bar.Date.Format(layout))
+	}
+
+	p, err := charts.LineRender(
+		values,
+		charts.TitleTextOptionFunc(fmt.Sprintf("%s %s (rebased to 100)", strings.Join(names, " vs "), strings.ToUpper(label))),
+		charts.LegendLabelsOptionFunc(names),
+		charts.XAxisLabelsOptionFunc(labels),
+		func(opt *charts.ChartOption) {
+			opt.Width = stockChartWidth
+			opt.Height = stockPricePanelHeight
+			opt.Symbol.Shape = charts.SymbolNone
+			opt.LineStrokeWidth = 1.6
+			opt.ValueFormatter = func(f float64) string {
+				return fmt.Sprintf("%.0f", f)
+			}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p.Bytes()
+}
@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	dbn "github.com/NimbleMarkets/dbn-go"
+)
+
+func TestParseCompareCommand(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      []string
+		wantRange string
+		errSubstr string
+	}{
+		{input: "!cmp AAPL MSFT QQQ 90d", want: []string{"AAPL", "MSFT", "QQQ"}, wantRange: "90d"},
+		{input: "!cmp aapl msft", want: []string{"AAPL", "MSFT"}, wantRange: "90d"},
+		{input: "!cmp AAPL aapl MSFT 1y", want: []string{"AAPL", "MSFT"}, wantRange: "1y"},
+		{input: "!cmp AAPL MSFT ytd", want: []string{"AAPL", "MSFT"}, wantRange: "YTD"},
+		{input: "!cmp", errSubstr: "invalid usage"},
+		{input: "!cmp AAPL 90d", errSubstr: "invalid usage"},
+		{input: "!cmp AAPL AAPL", errSubstr: "invalid usage"},
+		{input: "!cmp AAPL 90d MSFT", errSubstr: "invalid usage"},
+		{input: "!cmpAAPL MSFT", errSubstr: "invalid usage"},
+		{input: "!cmp AAPL $$$", errSubstr: `invalid stock symbol "$$$"`},
+		{input: "!cmp AAPL MSFT 10d", errSubstr: "invalid range"},
+		{input: "!cmp AAPL MSFT 1d", errSubstr: "use 7d or longer"},
+		{input: "!cmp AAPL MSFT 5D", errSubstr: "use 7d or longer"},
+		{input: "!cmp A B C D E F G", errSubstr: "too many symbols"},
+	}
+	for _, tt := range tests {
+		got, rng, err := parseCompareCommand(tt.input)
+		if tt.errSubstr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("parseCompareCommand(%q) error = %v, want %q", tt.input, err, tt.errSubstr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCompareCommand(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") || rng.label() != tt.wantRange {
+			t.Errorf("parseCompareCommand(%q) = %q, %q; want %q, %q", tt.input, got, rng.label(), tt.want, tt.wantRange)
+		}
+	}
+}
+
+func closeBars(start time.Time, closes ...float64) []HistoricalBar {
+	bars := make([]HistoricalBar, len(closes))
+	for i, c := range closes {
+		bars[i] = HistoricalBar{Date: start.AddDate(0, 0, i), Close: c}
+	}
+	return bars
+}
+
+func TestComparePerformance(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	barsBySymbol := map[string][]HistoricalBar{
+		// AAPL starts a day earlier; the first common date is day+1.
+		"AAPL": closeBars(day, 90, 100, 120, 90, 110),
+		"MSFT": closeBars(day.AddDate(0, 0, 1), 50, 55, 60, 40),
+	}
+
+	got := comparePerformance([]string{"AAPL", "MSFT", "NOPE"}, barsBySymbol)
+
+	if len(got.dates) != 4 || !got.dates[0].Equal(day.AddDate(0, 0, 1)) {
+		t.Fatalf("dates = %v, want 4 dates from %s", got.dates, day.AddDate(0, 0, 1))
+	}
+	if strings.Join(got.missing, ",") != "NOPE" {
+		t.Fatalf("missing = %q, want NOPE", got.missing)
+	}
+	want := map[string]struct {
+		rebased            []float64
+		totalReturn, maxDD float64
+	}{
+		"AAPL": {rebased: []float64{100, 120, 90, 110}, totalReturn: 10, maxDD: -25},
+		"MSFT": {rebased: []float64{100, 110, 120, 80}, totalReturn: -20, maxDD: -100.0 / 3},
+	}
+	if len(got.series) != len(want) {
+		t.Fatalf("series = %d, want %d", len(got.series), len(want))
+	}
+	for _, perf := range got.series {
+		w := want[perf.symbol]
+		for i := range w.rebased {
+			if math.Abs(perf.rebased[i]-w.rebased[i]) > 1e-9 {
+				t.Errorf("%s rebased = %v, want %v", perf.symbol, perf.rebased, w.rebased)
+				break
+			}
+		}
+		if math.Abs(perf.totalReturn-w.totalReturn) > 1e-9 || math.Abs(perf.maxDrawdown-w.maxDD) > 1e-9 {
+			t.Errorf("%s return/drawdown = %v/%v, want %v/%v", perf.symbol, perf.totalReturn, perf.maxDrawdown, w.totalReturn, w.maxDD)
+		}
+	}
+}
+
+func TestComparePerformanceNoOverlap(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	got := comparePerformance([]string{"AAPL", "MSFT"}, map[string][]HistoricalBar{
+		"AAPL": closeBars(day, 100, 101),
+		"MSFT": closeBars(day.AddDate(0, 0, 5), 50, 51),
+	})
+	if len(got.series) != 0 || strings.Join(got.missing, ",") != "AAPL,MSFT" {
+		t.Fatalf("comparePerformance() = %+v, want no series and both missing", got)
+	}
+	if summary := formatComparisonSummary("30d", got, nil); !strings.HasPrefix(summary, "No overlapping historical data") {
+		t.Fatalf("summary = %q", summary)
+	}
+}
+
+func TestFormatComparisonSummary(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	comparison := performanceComparison{
+		dates: []time.Time{day, day.AddDate(0, 0, 30)},
+		series: []symbolPerformance{
+			{symbol: "AAPL", totalReturn: -3.5, maxDrawdown: -8.25},
+			{symbol: "QQQ", totalReturn: 4.126, maxDrawdown: -2},
+		},
+		missing: []string{"ZZZZ"},
+	}
+	got := formatComparisonSummary("30d", comparison, []string{"BLOCKED"})
+	want := strings.Join([]string{
+		"Performance 30d (2026-03-02 to 2026-04-01), rebased to 100",
+		"QQQ: +4.13% · max drawdown -2.00%",
+		"AAPL: -3.50% · max drawdown -8.25%",
+		"No data: ZZZZ",
+		"Unavailable: BLOCKED",
+	}, "\n")
+	if got != want {
+		t.Fatalf("formatComparisonSummary() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderComparisonChartPNG(t *testing.T) {
+	t.Parallel()
+
+	bars := candleTestBars(200)
+	comparison := comparePerformance([]string{"AAPL", "MSFT"}, map[string][]HistoricalBar{
+		"AAPL": bars,
+		"MSFT": closeBars(bars[0].Date, closePrices(bars[10:])...),
+	})
+	png, err := renderComparisonChartPNG("6m", comparison)
+	if err != nil {
+		t.Fatalf("renderComparisonChartPNG() error: %v", err)
+	}
+	if !bytes.HasPrefix(png, pngSignature) {
+		t.Fatal("renderComparisonChartPNG() did not return a PNG")
+	}
+
+	if _, err := renderComparisonChartPNG("6m", performanceComparison{}); err == nil {
+		t.Fatal("renderComparisonChartPNG() with no series should fail")
+	}
+}
+
+func TestSplitBarsBySymbol(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	record := func(instrumentID uint32, date time.Time, closePrice int64) dbn.OhlcvMsg {
+		var rec dbn.OhlcvMsg
+		rec.Header.InstrumentID = instrumentID
+		rec.Header.TsEvent = uint64(date.UnixNano())
+		rec.Close = closePrice * 1e9
+		return rec
+	}
+	records := []dbn.OhlcvMsg{
+		record(202, day.AddDate(0, 0, 1), 51),
+		record(101, day.AddDate(0, 0, 1), 101),
+		record(101, day, 100),
+		record(202, day, 50),
+		record(999, day, 1),
+	}
+	metadata := &dbn.Metadata{
+		StypeIn:  dbn.SType_RawSymbol,
+		StypeOut: dbn.SType_InstrumentId,
+		Mappings: []dbn.SymbolMapping{
+			{RawSymbol: "AAPL", Intervals: []dbn.MappingInterval{{StartDate: 20260301, EndDate: 20260401, Symbol: "101"}}},
+			{RawSymbol: "MSFT", Intervals: []dbn.MappingInterval{{StartDate: 20260301, EndDate: 20260401, Symbol: "202"}}},
+		},
+	}
+
+	got, err := splitBarsBySymbol([]string{"AAPL", "MSFT"}, dbn.Schema_Ohlcv1D, records, metadata)
+	if err != nil {
+		t.Fatalf("splitBarsBySymbol() error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("splitBarsBySymbol() = %d symbols, want 2", len(got))
+	}
+	for symbol, want := range map[string][]float64{"AAPL": {100, 101}, "MSFT": {50, 51}} {
+		if closes := closePrices(got[symbol]); len(closes) != 2 || closes[0] != want[0] || closes[1] != want[1] {
+			t.Errorf("%s closes = %v, want %v", symbol, closes, want)
+		}
+	}
+
+	single, err := splitBarsBySymbol([]string{"AAPL"}, dbn.Schema_Ohlcv1D, records[1:3], nil)
+	if err != nil || len(single["AAPL"]) != 2 || single["AAPL"][0].Close != 100 {
+		t.Fatalf("single-symbol splitBarsBySymbol() = %v, %v", single, err)
+	}
+}
+
+func TestCompareHandlerWithoutDatabentoKey(t *testing.T) {
+	t.Setenv("DATABENTO_API_KEY", "")
+	b, srv := newTestBot(t)
+
+	compareHandler(context.Background(), b, groupTextUpdate("!cmp AAPL MSFT 30d"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.lastMessage, "DATABENTO_API_KEY is not configured") {
+		t.Fatalf("last message = %q, want the not-configured notice", srv.lastMessage)
+	}
+}
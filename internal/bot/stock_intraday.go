@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+	_ "time/tzdata" // the runtime image ships without a zoneinfo database
+
+	dbn "github.com/NimbleMarkets/dbn-go"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	intradayTimeFormatPattern = "2006-01-02 15:04"
+
+	// The US equity regular session, in minutes after midnight Eastern.
+	marketOpenMinute  = 9*60 + 30
+	marketCloseMinute = 16 * 60
+)
+
+// usEastern is the exchange time zone intraday bars are labelled in.
+var usEastern = loadUSEastern()
+
+func loadUSEastern() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		// Unreachable with the embedded tzdata; EST keeps labels close.
+		return time.FixedZone("EST", -5*60*60)
+	}
+	return loc
+}
+
+func isIntradaySchema(schema dbn.Schema) bool {
+	return schema == dbn.Schema_Ohlcv1H || schema == dbn.Schema_Ohlcv1M
+}
+
+// intradaySchema picks the Databento schema and chart bar size for an
+// intraday range: minute bars merged into 5-minute bars for one session,
+// hourly bars for several.
+func intradaySchema(rng stockRange) (dbn.Schema, barInterval) {
+	if rng.sessions == 1 {
+		return dbn.Schema_Ohlcv1M, barIntervalFiveMinute
+	}
+	return dbn.Schema_Ohlcv1H, barIntervalHourly
+}
+
+// hasIntradayBars reports whether bars came from fetchIntradayBars, which
+// stores them in US Eastern time; daily bars are at UTC midnight.
+func hasIntradayBars(bars []HistoricalBar) bool {
+	return len(bars) > 0 && bars[0].Date.Location() == usEastern
+}
+
+// handleIntradayStock answers `!s AAPL 1d` and `!s AAPL 5d` with the
+// regular-hours bars of the latest sessions. Indicators are computed on the
+// charted bars alone, without warm-up history.
+func handleIntradayStock(
+	ctx context.Context,
+	b *bot.Bot,
+	update *models.Update,
+	symbol string,
+	rng stockRange,
+	chartOpts stockChartOptions,
+	loadingMsg *models.Message,
+	loadingErr error,
+) {
+	label := rng.label()
+	bars, adjustedNote, err := fetchIntradayBars(ctx, symbol, rng)
+	if err != nil {
+		replyHistoricalFetchError(ctx, b, update, loadingMsg, loadingErr, symbol, label, err)
+		return
+	}
+	appotel.RecordOutcome(ctx, "success")
+	if len(bars) == 0 {
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, fmt.Sprintf("No intraday data returned for %s (%s).", symbol, label))
+		return
+	}
+
+	profile, profileErr := fetchCompanyProfile(ctx, symbol)
+	if profileErr != nil {
+		log.Warn().Err(profileErr).Str("symbol", symbol).Msg("Failed to fetch company profile for intraday stock response")
+	}
+
+	_, interval := intradaySchema(rng)
+	chartBars := downsampleBars(bars, interval)
+	caption := formatHistoricalSummary(symbol, label, bars, profile, formatIndicatorReadings(chartOpts.indicators, closePrices(chartBars)))
+	caption += fmt.Sprintf("\nChart: %s bars, regular hours (US Eastern)", interval)
+	if adjustedNote != "" {
+		caption += "\n" + adjustedNote
+	}
+	chartPNG, err := renderStockChartPNG(symbol, label, nil, chartBars, chartOpts)
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Str("range", label).Msg("Failed to render intraday chart; sending text only")
+		sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, caption)
+		return
+	}
+
+	sendHistoricalChart(ctx, b, update, loadingMsg, loadingErr, symbol, rng, chartPNG, caption)
+}
+
+// fetchIntradayBars returns the regular-hours bars of rng's latest sessions,
+// in US Eastern time.
+func fetchIntradayBars(ctx context.Context, symbol string, rng stockRange) ([]HistoricalBar, string, error) {
+	schema, _ := intradaySchema(rng)
+	barsBySymbol, adjustedNote, err := fetchOHLCVBars(ctx, []string{symbol}, schema, rng.dateRange(nowFunc()))
+	if err != nil {
+		return nil, "", err
+	}
+	barLength := time.Minute
+	if schema == dbn.Schema_Ohlcv1H {
+		barLength = time.Hour
+	}
+	return latestSessions(regularSessionBars(barsBySymbol[symbol], barLength), rng.sessions), adjustedNote, nil
+}
+
+// regularSessionBars converts bars to US Eastern time and keeps those that
+// overlap the 9:30-16:00 weekday session, so the hourly bar starting at 9:00
+// stays as the session's first hour.
+func regularSessionBars(bars []HistoricalBar, barLength time.Duration) []HistoricalBar {
+	out := make([]HistoricalBar, 0, len(bars))
+	for _, bar := range bars {
+		bar.Date = bar.Date.In(usEastern)
+		if weekday := bar.Date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			continue
+		}
+		start := bar.Date.Hour()*60 + bar.Date.Minute()
+		if start < marketCloseMinute && start+int(barLength/time.Minute) > marketOpenMinute {
+			out = append(out, bar)
+		}
+	}
+	return out
+}
+
+// latestSessions keeps the bars of the last n trading days in sorted bars.
+func latestSessions(bars []HistoricalBar, n int) []HistoricalBar {
+	sessions := 0
+	for i := len(bars) - 1; i >= 0; i-- {
+		if i < len(bars)-1 && sameCalendarDay(bars[i].Date, bars[i+1].Date) {
+			continue
+		}
+		sessions++
+		if sessions > n {
+			return bars[i+1:]
+		}
+	}
+	return bars
+}
+
+func sameCalendarDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dbn "github.com/NimbleMarkets/dbn-go"
+	dbn_hist "github.com/NimbleMarkets/dbn-go/hist"
+)
+
+// utcBars returns one bar per step starting at start, as Databento returns
+// them before session filtering.
+func utcBars(start time.Time, step time.Duration, n int) []HistoricalBar {
+	bars := make([]HistoricalBar, n)
+	for i := range bars {
+		bars[i] = HistoricalBar{Date: start.Add(time.Duration(i) * step), Open: 100, High: 101, Low: 99, Close: 100 + float64(i%7), Volume: 1000}
+	}
+	return bars
+}
+
+func TestIntradayStockRange(t *testing.T) {
+	now := time.Date(2026, 10, 16, 18, 30, 45, 0, time.UTC)
+	for token, wantSessions := range map[string]int{"1d": 1, "5D": 5} {
+		rng, err := parseStockRange(token, now)
+		if err != nil {
+			t.Fatalf("parseStockRange(%q): %v", token, err)
+		}
+		if !rng.isIntraday() || rng.sessions != wantSessions {
+			t.Fatalf("parseStockRange(%q) = %+v, want %d intraday sessions", token, rng, wantSessions)
+		}
+		dr := rng.dateRange(now)
+		if !dr.End.Equal(time.Date(2026, 10, 16, 18, 30, 0, 0, time.UTC)) {
+			t.Errorf("%s window ends %s, want now truncated to the minute", token, dr.End)
+		}
+		if days := rangeDays(dr); days < wantSessions+2 || days > maxStockRangeDays {
+			t.Errorf("%s window spans %d days, want room for a weekend", token, days)
+		}
+	}
+
+	if schema, interval := intradaySchema(stockRange{sessions: 1}); schema != dbn.Schema_Ohlcv1M || interval != barIntervalFiveMinute {
+		t.Errorf("1d schema = %v/%v, want minute bars charted as 5-minute bars", schema, interval)
+	}
+	if schema, interval := intradaySchema(stockRange{sessions: 5}); schema != dbn.Schema_Ohlcv1H || interval != barIntervalHourly {
+		t.Errorf("5d schema = %v/%v, want hourly bars", schema, interval)
+	}
+}
+
+func TestRegularSessionBars(t *testing.T) {
+	t.Parallel()
+
+	// Thursday 2026-10-15, EDT (UTC-4): 9:30 ET is 13:30 UTC.
+	minutes := regularSessionBars(utcBars(time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC), time.Minute, 16*60), time.Minute)
+	if len(minutes) != 390 {
+		t.Fatalf("kept %d minute bars, want the 390 of the regular session", len(minutes))
+	}
+	if got := minutes[0].Date.Format("15:04 MST"); got != "09:30 EDT" {
+		t.Errorf("first minute bar = %s, want 09:30 EDT", got)
+	}
+	if got := minutes[len(minutes)-1].Date.Format("15:04"); got != "15:59" {
+		t.Errorf("last minute bar = %s, want 15:59", got)
+	}
+
+	hours := regularSessionBars(utcBars(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), time.Hour, 24), time.Hour)
+	if len(hours) != 7 || hours[0].Date.Hour() != 9 || hours[len(hours)-1].Date.Hour() != 15 {
+		t.Fatalf("kept hourly bars %v, want 09:00 through 15:00 ET", hours)
+	}
+
+	// Saturday bars are dropped even inside session hours.
+	if weekend := regularSessionBars(utcBars(time.Date(2026, 10, 17, 14, 0, 0, 0, time.UTC), time.Hour, 2), time.Hour); len(weekend) != 0 {
+		t.Errorf("kept %d weekend bars, want none", len(weekend))
+	}
+}
+
+func TestLatestSessions(t *testing.T) {
+	t.Parallel()
+
+	var bars []HistoricalBar
+	for _, day := range []int{12, 13, 14, 15, 16} {
+		bars = append(bars, regularSessionBars(utcBars(time.Date(2026, 10, day, 13, 0, 0, 0, time.UTC), time.Hour, 8), time.Hour)...)
+	}
+
+	last := latestSessions(bars, 1)
+	if len(last) == 0 || !sameCalendarDay(last[0].Date, last[len(last)-1].Date) || last[0].Date.Day() != 16 {
+		t.Fatalf("latestSessions(1) = %d bars from %v, want only 2026-10-16", len(last), last[0].Date)
+	}
+	if got := latestSessions(bars, 3); got[0].Date.Day() != 14 {
+		t.Errorf("latestSessions(3) starts %s, want 2026-10-14", got[0].Date)
+	}
+	if got := latestSessions(bars, 10); len(got) != len(bars) {
+		t.Errorf("latestSessions(10) = %d bars, want all %d", len(got), len(bars))
+	}
+}
+
+func TestIntradayChartLabelsAndSummary(t *testing.T) {
+	t.Parallel()
+
+	session := regularSessionBars(utcBars(time.Date(2026, 10, 16, 13, 30, 0, 0, time.UTC), time.Minute, 390), time.Minute)
+	if !hasIntradayBars(session) || hasIntradayBars(candleTestBars(3)) {
+		t.Fatal("hasIntradayBars should only match bars in US Eastern time")
+	}
+	if got := chartDateLayout(session); got != "15:04" {
+		t.Errorf("one-session layout = %q, want time of day", got)
+	}
+	twoDays := regularSessionBars(utcBars(time.Date(2026, 10, 15, 13, 0, 0, 0, time.UTC), time.Hour, 30), time.Hour)
+	if got := chartDateLayout(twoDays); got != "01-02 15:04" {
+		t.Errorf("multi-session layout = %q, want date and time", got)
+	}
+
+	fiveMinute := downsampleBars(session, barIntervalFiveMinute)
+	if len(fiveMinute) != 78 || fiveMinute[1].Date.Format("15:04") != "09:35" {
+		t.Fatalf("downsampled to %d bars, want 78 five-minute bars", len(fiveMinute))
+	}
+
+	summary := formatHistoricalSummary(testSymbolAAPL, "1d", session, nil, "")
+	if !strings.HasPrefix(summary, "AAPL 1d (2026-10-16 09:30 to 2026-10-16 15:59 ET)") {
+		t.Errorf("summary = %q, want an Eastern time span", summary)
+	}
+
+	png, err := renderStockChartPNG(testSymbolAAPL, "1d", nil, fiveMinute, stockChartOptions{candle: true})
+	if err != nil || !bytes.HasPrefix(png, pngSignature) {
+		t.Fatalf("renderStockChartPNG() = %d bytes, %v", len(png), err)
+	}
+}
+
+func TestTryAdjustRangeFromDatabento422_Intraday(t *testing.T) {
+	orig := dbn_hist.SubmitJobParams{
+		Schema: dbn.Schema_Ohlcv1M,
+		DateRange: dbn_hist.DateRange{
+			Start: time.Date(2026, 10, 11, 18, 30, 0, 0, time.UTC),
+			End:   time.Date(2026, 10, 16, 18, 30, 0, 0, time.UTC),
+		},
+	}
+	err := &httpStatusError{
+		StatusCode: http.StatusUnprocessableEntity,
+		Status:     "422 Unprocessable Entity",
+		Body:       `{"detail":{"case":"data_end_after_available_end","payload":{"available_end":"2026-10-16T18:10:27.000000000Z"}}}`,
+	}
+
+	adjusted, ok := tryAdjustRangeFromDatabento422(&orig, err, rangeDays(orig.DateRange))
+	if !ok {
+		t.Fatal("expected adjustment for an intraday window")
+	}
+	wantEnd := time.Date(2026, 10, 16, 18, 10, 0, 0, time.UTC)
+	if !adjusted.DateRange.End.Equal(wantEnd) {
+		t.Fatalf("end = %s, want %s (minute-aligned, not midnight)", adjusted.DateRange.End, wantEnd)
+	}
+	if got := adjusted.DateRange.End.Sub(adjusted.DateRange.Start); got != orig.DateRange.End.Sub(orig.DateRange.Start) {
+		t.Fatalf("window length = %s, want it unchanged", got)
+	}
+}
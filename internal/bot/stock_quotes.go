@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+// maxQuoteSymbols caps `!s AAPL MSFT ...` so one command stays well inside
+// Finnhub's 60 calls/minute free tier.
+const maxQuoteSymbols = 8
+
+// quoteRow is one line of the multi-symbol table: either a quote or the
+// reason the symbol has none.
+type quoteRow struct {
+	symbol  string
+	quote   *StockQuote
+	failure string
+}
+
+// parseStockQuotesCommand recognizes `!s SYM1 SYM2 ...`: two or more
+// symbols and nothing else. Chart words such as candle, rsi or bb only
+// count as options after a range, so a list without a range token is all
+// symbols (BB and RSI are tickers too); one with a range is left to
+// parseStockCommand, whose error text explains the range syntax. Symbols are
+// uppercased and deduplicated.
+func parseStockQuotesCommand(text string) ([]string, bool, error) {
+	_, parts, err := extractSymbolToken(text, "!s", invalidUsageSymbol)
+	if err != nil || len(parts) < 2 {
+		return nil, false, nil //nolint:nilerr // parseStockCommand reports it with the usual wording
+	}
+
+	if slices.ContainsFunc(parts[1:], isStockRangeToken) {
+		return nil, false, nil
+	}
+
+	var symbols []string
+	for _, part := range parts {
+		symbol := strings.ToUpper(part)
+		if !symbolRegex.MatchString(symbol) {
+			return nil, true, fmt.Errorf("invalid stock symbol %q, use 1-10 characters: letters, numbers, dots (.) or dashes (-)", part)
+		}
+		if !slices.Contains(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) > maxQuoteSymbols {
+		return nil, true, fmt.Errorf("too many symbols, use up to %d (e.g., !s AAPL MSFT NVDA)", maxQuoteSymbols)
+	}
+	if len(symbols) < 2 {
+		return nil, false, nil
+	}
+	return symbols, true, nil
+}
+
+// handleStockQuotes answers `!s AAPL MSFT NVDA` with one table. Quotes are
+// fetched concurrently; a symbol that fails or is blocked gets its own row
+// instead of failing the whole reply.
+func handleStockQuotes(ctx context.Context, b *bot.Bot, update *models.Update, symbols []string) {
+	loadingMsg, loadingErr := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: update.Message.MessageThreadID,
+		Text:            fmt.Sprintf("Fetching quotes for %s...", strings.Join(symbols, ", ")),
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                update.Message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+	if loadingErr != nil {
+		log.Warn().Err(loadingErr).Strs("symbols", symbols).Msg("Failed to send stock loading state")
+	}
+
+	rows := fetchQuoteRows(ctx, symbols)
+	if !slices.ContainsFunc(rows, func(row quoteRow) bool { return row.quote != nil }) {
+		appotel.RecordOutcome(ctx, "error")
+	} else {
+		appotel.RecordOutcome(ctx, "success")
+	}
+	sendOrEditStockTable(ctx, b, update, loadingMsg, loadingErr, formatQuoteTable(rows))
+}
+
+func fetchQuoteRows(ctx context.Context, symbols []string) []quoteRow {
+	rows := make([]quoteRow, len(symbols))
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		rows[i].symbol = symbol
+		if _, blocked := blockedStockResponse(symbol); blocked {
+			rows[i].failure = "unavailable"
+			continue
+		}
+		wg.Go(func() {
+			quote, err := fetchStockQuote(ctx, symbol)
+			if err != nil {
+				log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to fetch stock quote for table")
+				rows[i].failure = quoteFailureText(err)
+				return
+			}
+			rows[i].quote = quote
+		})
+	}
+	wg.Wait()
+	return rows
+}
+
+// quoteFailureText keeps table rows short and free of transport details.
+func quoteFailureText(err error) string {
+	if strings.Contains(err.Error(), "symbol not found") {
+		return "not found"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timed out"
+	}
+	return "failed"
+}
+
+// formatQuoteTable renders quotes as an aligned plain-text table sorted by
+// percent change (best first), with failed symbols listed last in the order
+// they were asked for.
+func formatQuoteTable(rows []quoteRow) string {
+	var quoted, failed []quoteRow
+	for _, row := range rows {
+		if row.quote != nil {
+			quoted = append(quoted, row)
+		} else {
+			failed = append(failed, row)
+		}
+	}
+	slices.SortStableFunc(quoted, func(a, b quoteRow) int {
+		return cmp.Compare(b.quote.PercentChange, a.quote.PercentChange)
+	})
+
+	table := [][]string{{"SYM", "PRICE", "CHG", "CHG%", "DAY RANGE"}}
+	for _, row := range quoted {
+		q := row.quote
+		table = append(table, []string{
+			row.symbol,
+			fmt.Sprintf("%.2f", q.CurrentPrice),
+			fmt.Sprintf("%+.2f", q.Change),
+			fmt.Sprintf("%+.2f%%", q.PercentChange),
+			fmt.Sprintf("%.2f-%.2f", q.Low, q.High),
+		})
+	}
+
+	widths := make([]int, len(table[0]))
+	for _, cells := range table {
+		for i, cell := range cells {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	for _, row := range failed {
+		widths[0] = max(widths[0], utf8.RuneCountInString(row.symbol))
+	}
+
+	var sb strings.Builder
+	for _, cells := range table {
+		var line strings.Builder
+		for i, cell := range cells {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i == 0 || i == len(cells)-1 {
+				line.WriteString(cell + pad)
+			} else {
+				line.WriteString(pad + cell)
+			}
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	for _, row := range failed {
+		fmt.Fprintf(&sb, "%-*s  %s\n", widths[0], row.symbol, row.failure)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// sendOrEditStockTable shows text in a monospace block, falling back to the
+// plain sendOrEditStockResult path when Telegram rejects the formatting.
+func sendOrEditStockTable(
+	ctx context.Context,
+	b *bot.Bot,
+	update *models.Update,
+	loadingMsg *models.Message,
+	loadingErr error,
+	table string,
+) {
+	formatted := "```\n" + escapeCodeMarkdownV2(table) + "\n```"
+	if loadingErr == nil && loadingMsg != nil {
+		_, editErr := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    update.Message.Chat.ID,
+			MessageID: loadingMsg.ID,
+			Text:      formatted,
+			ParseMode: models.ParseModeMarkdown,
+		})
+		if editErr == nil {
+			return
+		}
+		log.Warn().Err(editErr).Int64("chat_id", update.Message.Chat.ID).Msg("Failed to edit stock table; sending plain text")
+	}
+	sendOrEditStockResult(ctx, b, update, loadingMsg, loadingErr, table)
+}
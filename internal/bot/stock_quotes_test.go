@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseStockQuotesCommand(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      []string
+		wantMulti bool
+		errSubstr string
+	}{
+		{input: "!s aapl msft NVDA", want: []string{"AAPL", "MSFT", "NVDA"}, wantMulti: true},
+		{input: "!s AAPL aapl MSFT", want: []string{"AAPL", "MSFT"}, wantMulti: true},
+		{input: "!s AAPL", wantMulti: false},
+		{input: "!s AAPL 30d", wantMulti: false},
+		{input: "!s AAPL 10d", wantMulti: false},
+		{input: "!s AAPL 30d candle", wantMulti: false},
+		{input: "!s AAPL candle 30d", wantMulti: false},
+		{input: "!s AAPL BB", want: []string{"AAPL", "BB"}, wantMulti: true},
+		{input: "!s BB AAPL", want: []string{"BB", "AAPL"}, wantMulti: true},
+		{input: "!s AAPL RSI MSFT", want: []string{"AAPL", "RSI", "MSFT"}, wantMulti: true},
+		{input: "!s AAPL candle", want: []string{"AAPL", "CANDLE"}, wantMulti: true},
+		{input: "!s AAPL AAPL", wantMulti: false},
+		{input: "!sAAPL MSFT", wantMulti: false},
+		{input: "!s AAPL $$$", wantMulti: true, errSubstr: `invalid stock symbol "$$$"`},
+		{input: "!s A B C D E F G H I", wantMulti: true, errSubstr: "too many symbols"},
+	}
+	for _, tt := range tests {
+		got, multi, err := parseStockQuotesCommand(tt.input)
+		if multi != tt.wantMulti || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("parseStockQuotesCommand(%q) = %q, %v", tt.input, got, multi)
+		}
+		if tt.errSubstr == "" && err != nil || tt.errSubstr != "" && (err == nil || !strings.Contains(err.Error(), tt.errSubstr)) {
+			t.Errorf("parseStockQuotesCommand(%q) error = %v, want %q", tt.input, err, tt.errSubstr)
+		}
+	}
+}
+
+func TestFormatQuoteTable(t *testing.T) {
+	t.Parallel()
+
+	got := formatQuoteTable([]quoteRow{
+		{symbol: "AAPL", quote: &StockQuote{CurrentPrice: 230.1, Change: -1.2, PercentChange: -0.52, Low: 229, High: 232.5}},
+		{symbol: "ZZZZZ", failure: "not found"},
+		{symbol: "NVDA", quote: &StockQuote{CurrentPrice: 128.4, Change: 3.2, PercentChange: 2.56, Low: 124.1, High: 129}},
+	})
+	want := strings.Join([]string{
+		"SYM     PRICE    CHG    CHG%  DAY RANGE",
+		"NVDA   128.40  +3.20  +2.56%  124.10-129.00",
+		"AAPL   230.10  -1.20  -0.52%  229.00-232.50",
+		"ZZZZZ  not found",
+	}, "\n")
+	if got != want {
+		t.Fatalf("formatQuoteTable() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStockHandlerMultiSymbolTable(t *testing.T) {
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("symbol") {
+		case "AAPL":
+			_ = json.NewEncoder(w).Encode(StockQuote{CurrentPrice: 230.1, PercentChange: -0.5, Low: 229, High: 232})
+		case "MSFT":
+			_ = json.NewEncoder(w).Encode(StockQuote{CurrentPrice: 410, PercentChange: 1.5, Low: 400, High: 412})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	server.Start()
+	useRedirectedHTTPClient(t, server.URL)
+	t.Setenv("FINNHUB_API_KEY", "test-key")
+	b, srv := newTestBot(t)
+
+	stockHandler(context.Background(), b, groupTextUpdate("!s AAPL BAD MSFT"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	reply := srv.lastMessage
+	if !strings.HasPrefix(reply, "```\nSYM") || srv.lastParseMode != "MarkdownV2" {
+		t.Fatalf("expected a monospace table, got %q (%q)", reply, srv.lastParseMode)
+	}
+	msft, aapl, bad := strings.Index(reply, "MSFT"), strings.Index(reply, "AAPL"), strings.Index(reply, "BAD   failed")
+	if msft < 0 || aapl < 0 || bad < 0 || msft > aapl || aapl > bad {
+		t.Fatalf("expected rows sorted by percent change with the failure last, got %q", reply)
+	}
+}
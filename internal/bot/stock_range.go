@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dbn_hist "github.com/NimbleMarkets/dbn-go/hist"
+)
+
+const (
+	stockRangeYTD     = "ytd"
+	stockRangeSpanSep = ".."
+
+	// maxStockRangeDays bounds every range, explicit spans included, to the
+	// longest preset (5y) so one command cannot pull decades of bars.
+	maxStockRangeDays = 5*366 + 1
+
+	invalidRangeMsg = "invalid range, use 1d, 5d, 7d, 30d, 60d, 90d, 6m, 1y, 5y, ytd or a date span like 2024-01-01..2024-06-30 (e.g., !s AAPL 1y)"
+)
+
+// stockRangeSessions maps the intraday range tokens to the number of latest
+// trading sessions they chart.
+var stockRangeSessions = map[string]int{
+	"1d": 1,
+	"5d": 5,
+}
+
+// stockRangeMonths maps the calendar-based range tokens to month counts.
+var stockRangeMonths = map[string]int{
+	"6m": 6,
+	"1y": 12,
+	"5y": 60,
+}
+
+// barInterval is the bar size a chart is drawn with. Long ranges are
+// downsampled so a 5y chart is not 1,250 unreadable daily points.
+type barInterval int
+
+const (
+	barIntervalDaily barInterval = iota
+	barIntervalWeekly
+	barIntervalMonthly
+	barIntervalFiveMinute
+	barIntervalHourly
+)
+
+func (i barInterval) String() string {
+	switch i {
+	case barIntervalWeekly:
+		return "weekly"
+	case barIntervalMonthly:
+		return "monthly"
+	case barIntervalFiveMinute:
+		return "5-minute"
+	case barIntervalHourly:
+		return "hourly"
+	default:
+		return "daily"
+	}
+}
+
+// stockRange is a parsed `!s` history range. The zero value means "no range"
+// (a spot quote). Relative ranges are resolved against the clock when the
+// bars are fetched, so a cached command never goes stale.
+type stockRange struct {
+	token    string // as typed, lowercased
+	sessions int    // 1d, 5d: intraday bars of the latest sessions
+	days     int    // 7d, 30d, ...
+	months   int    // 6m, 1y, 5y
+	ytd      bool
+	// start and end bound an explicit span; end is inclusive.
+	start, end time.Time
+}
+
+func (r stockRange) isZero() bool {
+	return r.token == ""
+}
+
+// isIntraday reports whether r is charted from hourly or minute bars.
+func (r stockRange) isIntraday() bool {
+	return r.sessions > 0
+}
+
+// isSpan reports whether r is an explicit date span rather than a range
+// relative to today.
+func (r stockRange) isSpan() bool {
+	return !r.start.IsZero()
+}
+
+// label is the range as shown in captions and chart titles.
+func (r stockRange) label() string {
+	if r.ytd {
+		return "YTD"
+	}
+	return r.token
+}
+
+// isStockRangeToken reports whether token is written like a range, valid or
+// not, so callers can tell "bad range" apart from "not a range at all".
+func isStockRangeToken(token string) bool {
+	token = strings.ToLower(token)
+	return token == stockRangeYTD || rangeTokenRE.MatchString(token) || strings.Contains(token, stockRangeSpanSep)
+}
+
+// parseStockRange parses one range token. now is only used to reject
+// explicit spans that lie entirely in the future.
+func parseStockRange(token string, now time.Time) (stockRange, error) {
+	token = strings.ToLower(strings.TrimSpace(token))
+	if sessions, ok := stockRangeSessions[token]; ok {
+		return stockRange{token: token, sessions: sessions}, nil
+	}
+	if days, ok := stockRangeDays[token]; ok {
+		return stockRange{token: token, days: days}, nil
+	}
+	if months, ok := stockRangeMonths[token]; ok {
+		return stockRange{token: token, months: months}, nil
+	}
+	if token == stockRangeYTD {
+		return stockRange{token: token, ytd: true}, nil
+	}
+	if rawStart, rawEnd, ok := strings.Cut(token, stockRangeSpanSep); ok {
+		return parseStockDateSpan(token, rawStart, rawEnd, now)
+	}
+	return stockRange{}, errors.New(invalidRangeMsg)
+}
+
+func parseStockDateSpan(token, rawStart, rawEnd string, now time.Time) (stockRange, error) {
+	start, startErr := time.Parse(dateFormatPattern, rawStart)
+	end, endErr := time.Parse(dateFormatPattern, rawEnd)
+	if startErr != nil || endErr != nil {
+		return stockRange{}, errors.New("invalid date span, use YYYY-MM-DD..YYYY-MM-DD (e.g., !s AAPL 2024-01-01..2024-06-30)")
+	}
+	if !start.Before(end) {
+		return stockRange{}, errors.New("invalid date span, the start date must be before the end date")
+	}
+	// dateRange fetches through the end date, so the window it sends is a
+	// day longer than end-start.
+	if end.AddDate(0, 0, 1).Sub(start) > maxStockRangeDays*24*time.Hour {
+		return stockRange{}, errors.New("invalid date span, a span can cover at most 5 years")
+	}
+	if !start.Before(historicalEndUTC(now)) {
+		return stockRange{}, errors.New("invalid date span, it must start before yesterday")
+	}
+	return stockRange{token: token, start: start, end: end}, nil
+}
+
+// dateRange resolves r into the half-open UTC window sent to Databento. Like
+// historicalDateRangeUTC, daily windows never end after yesterday. Intraday
+// windows end now and reach back far enough to span weekends and holidays;
+// the latest sessions are picked from the bars that come back.
+func (r stockRange) dateRange(now time.Time) dbn_hist.DateRange {
+	end := historicalEndUTC(now)
+	switch {
+	case r.sessions > 0:
+		intradayEnd := now.UTC().Truncate(time.Minute)
+		return dbn_hist.DateRange{Start: intradayEnd.AddDate(0, 0, -(r.sessions*7/5 + 4)), End: intradayEnd}
+	case r.days > 0:
+		return historicalDateRangeUTC(now, r.days)
+	case r.months > 0:
+		return dbn_hist.DateRange{Start: end.AddDate(0, -r.months, 0), End: end}
+	case r.ytd:
+		start := time.Date(end.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		if !start.Before(end) {
+			start = end.AddDate(0, 0, -1)
+		}
+		return dbn_hist.DateRange{Start: start, End: end}
+	default:
+		spanEnd := r.end.AddDate(0, 0, 1)
+		if spanEnd.After(end) {
+			spanEnd = end
+		}
+		return dbn_hist.DateRange{Start: r.start, End: spanEnd}
+	}
+}
+
+// historicalEndUTC is the exclusive end of every history window: UTC
+// midnight of the previous day.
+func historicalEndUTC(now time.Time) time.Time {
+	return now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+}
+
+// rangeDays is the length of a resolved window in whole days.
+func rangeDays(dr dbn_hist.DateRange) int {
+	return int(dr.End.Sub(dr.Start).Hours() / 24)
+}
+
+// barIntervalFor picks the chart bar size for a window: daily up to about
+// four months, weekly up to two years, monthly beyond.
+func barIntervalFor(dr dbn_hist.DateRange) barInterval {
+	switch days := rangeDays(dr); {
+	case days <= 120:
+		return barIntervalDaily
+	case days <= 2*366:
+		return barIntervalWeekly
+	default:
+		return barIntervalMonthly
+	}
+}
+
+// downsampleBars merges sorted bars into 5-minute, ISO-week or
+// calendar-month bars: first open, highest high, lowest low, last close,
+// summed volume, dated by the bucket's first bar.
+func downsampleBars(bars []HistoricalBar, interval barInterval) []HistoricalBar {
+	if interval == barIntervalDaily || interval == barIntervalHourly || len(bars) == 0 {
+		return bars
+	}
+
+	bucketOf := func(t time.Time) int64 {
+		switch interval {
+		case barIntervalFiveMinute:
+			return t.Truncate(5 * time.Minute).Unix()
+		case barIntervalWeekly:
+			year, week := t.ISOWeek()
+			return int64(year*100 + week)
+		default:
+			return int64(t.Year()*100 + int(t.Month()))
+		}
+	}
+
+	out := make([]HistoricalBar, 0, len(bars)/4+1)
+	current := bars[0]
+	currentBucket := bucketOf(current.Date)
+	for _, bar := range bars[1:] {
+		if bucket := bucketOf(bar.Date); bucket != currentBucket {
+			out = append(out, current)
+			current, currentBucket = bar, bucket
+			continue
+		}
+		current.High = max(current.High, bar.High)
+		current.Low = min(current.Low, bar.Low)
+		current.Close = bar.Close
+		current.Volume += bar.Volume
+	}
+	return append(out, current)
+}
+
+// chartDateLayout keeps x-axis labels short: month-day within a year,
+// year-month beyond. Intraday bars are labelled with their US Eastern time
+// of day, prefixed by the date when they span several sessions.
+func chartDateLayout(bars []HistoricalBar) string {
+	if hasIntradayBars(bars) {
+		if sameCalendarDay(bars[0].Date, bars[len(bars)-1].Date) {
+			return "15:04"
+		}
+		return "01-02 15:04"
+	}
+	if len(bars) > 1 && bars[len(bars)-1].Date.Sub(bars[0].Date) > 366*24*time.Hour {
+		return "2006-01"
+	}
+	return "01-02"
+}
+
+// historicalFileLabel makes a range label safe for an upload file name.
+func historicalFileLabel(symbol string, rng stockRange) string {
+	return fmt.Sprintf("%s-%s.png", strings.ToLower(symbol), strings.ReplaceAll(strings.ToLower(rng.label()), stockRangeSpanSep, "_"))
+}
+
+// splitWarmupBars separates the indicator warm-up bars fetched before start
+// from the bars in the requested window.
+func splitWarmupBars(bars []HistoricalBar, start time.Time) (warmup, window []HistoricalBar) {
+	i, _ := slices.BinarySearchFunc(bars, start, func(bar HistoricalBar, t time.Time) int {
+		return bar.Date.Compare(t)
+	})
+	return bars[:i], bars[i:]
+}
+
+func closePrices(bars []HistoricalBar) []float64 {
+	closes := make([]float64, len(bars))
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+	return closes
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	dbn_hist "github.com/NimbleMarkets/dbn-go/hist"
+)
+
+func TestParseStockRange(t *testing.T) {
+	now := time.Date(2026, 3, 8, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		token     string
+		wantLabel string
+		errSubstr string
+	}{
+		{token: "30d", wantLabel: "30d"},
+		{token: "6M", wantLabel: "6m"},
+		{token: "5y", wantLabel: "5y"},
+		{token: "YTD", wantLabel: "YTD"},
+		{token: "2024-01-01..2024-06-30", wantLabel: "2024-01-01..2024-06-30"},
+		{token: "2y", errSubstr: "invalid range, use 1d, 5d, 7d"},
+		{token: "2024-1-1..2024-06-30", errSubstr: "use YYYY-MM-DD..YYYY-MM-DD"},
+		{token: "2024-06-30..2024-06-30", errSubstr: "start date must be before"},
+		{token: "2015-01-01..2024-06-30", errSubstr: "at most 5 years"},
+		{token: "2020-01-01..2025-01-04", wantLabel: "2020-01-01..2025-01-04"},
+		{token: "2020-01-01..2025-01-05", errSubstr: "at most 5 years"},
+		{token: "2026-03-07..2026-04-01", errSubstr: "must start before yesterday"},
+	}
+	for _, tt := range tests {
+		got, err := parseStockRange(tt.token, now)
+		if tt.errSubstr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("parseStockRange(%q) error = %v, want %q", tt.token, err, tt.errSubstr)
+			}
+			continue
+		}
+		if err != nil || got.label() != tt.wantLabel {
+			t.Errorf("parseStockRange(%q) = %q, %v; want %q", tt.token, got.label(), err, tt.wantLabel)
+		}
+	}
+}
+
+func TestStockRangeDateRange(t *testing.T) {
+	now := time.Date(2026, 3, 8, 15, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		token     string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{token: "7d", wantStart: day(2026, 2, 28), wantEnd: day(2026, 3, 7)},
+		{token: "1y", wantStart: day(2025, 3, 7), wantEnd: day(2026, 3, 7)},
+		{token: "ytd", wantStart: day(2026, 1, 1), wantEnd: day(2026, 3, 7)},
+		{token: "2024-01-01..2024-06-30", wantStart: day(2024, 1, 1), wantEnd: day(2024, 7, 1)},
+		{token: "2026-01-01..2026-12-31", wantStart: day(2026, 1, 1), wantEnd: day(2026, 3, 7)},
+	}
+	for _, tt := range tests {
+		rng, err := parseStockRange(tt.token, now)
+		if err != nil {
+			t.Fatalf("parseStockRange(%q): %v", tt.token, err)
+		}
+		got := rng.dateRange(now)
+		if !got.Start.Equal(tt.wantStart) || !got.End.Equal(tt.wantEnd) {
+			t.Errorf("%s dateRange = %s..%s, want %s..%s", tt.token, got.Start, got.End, tt.wantStart, tt.wantEnd)
+		}
+	}
+
+	// On January 1st YTD still covers one day rather than an empty window.
+	ytd, _ := parseStockRange("ytd", now)
+	got := ytd.dateRange(time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC))
+	if rangeDays(got) != 1 {
+		t.Errorf("early-January YTD covers %d days, want 1", rangeDays(got))
+	}
+
+	// The longest span the parser accepts still fits the fetch limit once its
+	// end date is included.
+	longest, err := parseStockRange("2020-01-01..2025-01-04", now)
+	if err != nil {
+		t.Fatalf("parseStockRange(longest span): %v", err)
+	}
+	if days := rangeDays(longest.dateRange(now)); days != maxStockRangeDays {
+		t.Errorf("longest span covers %d days, want %d", days, maxStockRangeDays)
+	}
+}
+
+func TestBarIntervalFor(t *testing.T) {
+	end := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+	for days, want := range map[int]barInterval{
+		90:   barIntervalDaily,
+		183:  barIntervalWeekly,
+		365:  barIntervalWeekly,
+		1826: barIntervalMonthly,
+	} {
+		if got := barIntervalFor(dbn_hist.DateRange{Start: end.AddDate(0, 0, -days), End: end}); got != want {
+			t.Errorf("barIntervalFor(%d days) = %s, want %s", days, got, want)
+		}
+	}
+}
+
+func TestDownsampleBars(t *testing.T) {
+	bar := func(d int, open, high, low, closePrice float64, volume uint64) HistoricalBar {
+		return HistoricalBar{
+			Date: time.Date(2026, 2, d, 0, 0, 0, 0, time.UTC), Open: open, High: high, Low: low, Close: closePrice, Volume: volume,
+		}
+	}
+	// Feb 2026: Mon 23 - Fri 27 is one ISO week, Mon Mar 2 starts the next.
+	bars := []HistoricalBar{
+		bar(23, 10, 12, 9, 11, 100),
+		bar(24, 11, 15, 10, 14, 200),
+		bar(27, 14, 14, 8, 9, 300),
+	}
+	bars = append(bars, HistoricalBar{Date: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Open: 9, High: 10, Low: 9, Close: 10, Volume: 50})
+
+	weekly := downsampleBars(bars, barIntervalWeekly)
+	if len(weekly) != 2 {
+		t.Fatalf("expected 2 weekly bars, got %+v", weekly)
+	}
+	want := HistoricalBar{Date: bars[0].Date, Open: 10, High: 15, Low: 8, Close: 9, Volume: 600}
+	if weekly[0] != want {
+		t.Fatalf("weekly[0] = %+v, want %+v", weekly[0], want)
+	}
+
+	monthly := downsampleBars(bars, barIntervalMonthly)
+	if len(monthly) != 2 || monthly[1].Close != 10 {
+		t.Fatalf("unexpected monthly bars %+v", monthly)
+	}
+	if got := downsampleBars(bars, barIntervalDaily); len(got) != len(bars) {
+		t.Fatalf("daily downsampling should be a no-op, got %d bars", len(got))
+	}
+}
+
+func TestHistoricalFileLabel(t *testing.T) {
+	rng, err := parseStockRange("2024-01-01..2024-06-30", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("parseStockRange: %v", err)
+	}
+	if got := historicalFileLabel("AAPL", rng); got != "aapl-2024-01-01_2024-06-30.png" {
+		t.Fatalf("historicalFileLabel = %q", got)
+	}
+}
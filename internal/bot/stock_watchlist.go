@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+const (
+	watchlistCommand = "!wl"
+
+	// maxWatchlistSymbols matches the `!s` table cap, since `!wl` quotes
+	// every symbol at once.
+	maxWatchlistSymbols = maxQuoteSymbols
+	watchlistChartRange = "30d"
+
+	watchlistUsageMsg      = "Usage: !wl shows this chat's watchlist, !wl chart adds a 30-day performance chart, !wl add SYMBOL... and !wl rm SYMBOL... edit it."
+	watchlistEmptyMsg      = "This chat's watchlist is empty. Add symbols with !wl add AAPL NVDA"
+	watchlistSaveFailedMsg = "Could not save the watchlist. Please try again."
+)
+
+func shouldHandleWatchlist(update *models.Update) bool {
+	if update == nil || update.Message == nil {
+		return false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(update.Message.Text), watchlistCommand)
+	return ok && (rest == "" || startsWithSpace(rest))
+}
+
+// watchlistHandler serves `!wl`, `!wl chart`, `!wl add SYMBOL...` and `!wl
+// rm SYMBOL...` for the chat's shared watchlist.
+func watchlistHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	message := update.Message
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(message.Text), watchlistCommand))
+
+	if len(fields) == 0 || (len(fields) == 1 && strings.EqualFold(fields[0], "chart")) {
+		symbols := chatSettingsInstance.get(message.Chat.ID).Watchlist
+		if len(symbols) == 0 {
+			appotel.RecordOutcome(ctx, "success")
+			sendSettingsReply(ctx, b, message, watchlistEmptyMsg)
+			return
+		}
+		handleWatchlistOverview(ctx, b, update, symbols, len(fields) == 1)
+		return
+	}
+
+	action := strings.ToLower(fields[0])
+	if (action != "add" && action != "rm") || len(fields) < 2 {
+		sendSettingsReply(ctx, b, message, watchlistUsageMsg)
+		return
+	}
+	var symbols []string
+	for _, raw := range fields[1:] {
+		symbol := strings.ToUpper(raw)
+		if !symbolRegex.MatchString(symbol) {
+			sendSettingsReply(ctx, b, message, fmt.Sprintf("invalid stock symbol %q, use 1-10 characters: letters, numbers, dots (.) or dashes (-)", raw))
+			return
+		}
+		if !slices.Contains(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	var blocked, dropped []string
+	if action == "add" {
+		symbols = slices.DeleteFunc(symbols, func(symbol string) bool {
+			_, isBlocked := blockedStockResponse(symbol)
+			if isBlocked {
+				blocked = append(blocked, symbol)
+			}
+			return isBlocked
+		})
+	}
+	err := chatSettingsInstance.update(message.Chat.ID, func(settings *chatSettings) {
+		if action == "add" {
+			settings.Watchlist, dropped = addWatchlistSymbols(settings.Watchlist, symbols)
+		} else {
+			// Clone first: readers may hold the stored slice.
+			settings.Watchlist = slices.DeleteFunc(slices.Clone(settings.Watchlist), func(symbol string) bool {
+				return slices.Contains(symbols, symbol)
+			})
+		}
+	})
+	if err != nil {
+		appotel.RecordOutcome(ctx, "error")
+		log.Error().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to persist chat watchlist")
+		sendSettingsReply(ctx, b, message, watchlistSaveFailedMsg)
+		return
+	}
+
+	log.Info().Int64("chat_id", message.Chat.ID).Str("action", action).Strs("symbols", symbols).Msg("Chat watchlist changed")
+	appotel.RecordOutcome(ctx, "success")
+	reply := formatWatchlist(chatSettingsInstance.get(message.Chat.ID).Watchlist)
+	if len(dropped) > 0 {
+		reply += fmt.Sprintf("\nThe watchlist holds at most %d symbols; not added: %s", maxWatchlistSymbols, strings.Join(dropped, ", "))
+	}
+	if len(blocked) > 0 {
+		reply += "\nUnavailable: " + strings.Join(blocked, ", ")
+	}
+	sendSettingsReply(ctx, b, message, reply)
+}
+
+// addWatchlistSymbols returns list plus the symbols not yet in it, up to
+// maxWatchlistSymbols, and the symbols left out for the cap.
+func addWatchlistSymbols(list, symbols []string) ([]string, []string) {
+	list = slices.Clone(list)
+	var dropped []string
+	for _, symbol := range symbols {
+		switch {
+		case slices.Contains(list, symbol):
+		case len(list) >= maxWatchlistSymbols:
+			dropped = append(dropped, symbol)
+		default:
+			list = append(list, symbol)
+		}
+	}
+	return list, dropped
+}
+
+func formatWatchlist(symbols []string) string {
+	if len(symbols) == 0 {
+		return watchlistEmptyMsg
+	}
+	return "Watchlist: " + strings.Join(symbols, ", ")
+}
+
+// handleWatchlistOverview replies with one quote table for the watchlist and,
+// with withChart, a 30-day chart of the symbols rebased to 100. Blocked
+// symbols keep their list entry but show as unavailable.
+func handleWatchlistOverview(ctx context.Context, b *bot.Bot, update *models.Update, symbols []string, withChart bool) {
+	loadingMsg, loadingErr := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: update.Message.MessageThreadID,
+		Text:            fmt.Sprintf("Fetching watchlist quotes for %s...", strings.Join(symbols, ", ")),
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                update.Message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+	if loadingErr != nil {
+		log.Warn().Err(loadingErr).Strs("symbols", symbols).Msg("Failed to send watchlist loading state")
+	}
+
+	rows := fetchQuoteRows(ctx, symbols)
+	if !slices.ContainsFunc(rows, func(row quoteRow) bool { return row.quote != nil }) {
+		appotel.RecordOutcome(ctx, "error")
+	} else {
+		appotel.RecordOutcome(ctx, "success")
+	}
+	sendOrEditStockTable(ctx, b, update, loadingMsg, loadingErr, formatQuoteTable(rows))
+
+	if withChart {
+		sendWatchlistChart(ctx, b, update, symbols)
+	}
+}
+
+// sendWatchlistChart posts the normalized chart as its own reply, so a
+// Databento failure still leaves the quote table in place.
+func sendWatchlistChart(ctx context.Context, b *bot.Bot, update *models.Update, symbols []string) {
+	var allowed, blocked []string
+	for _, symbol := range symbols {
+		if _, isBlocked := blockedStockResponse(symbol); isBlocked {
+			blocked = append(blocked, symbol)
+		} else {
+			allowed = append(allowed, symbol)
+		}
+	}
+	if len(allowed) == 0 {
+		return
+	}
+
+	rng, err := parseStockRange(watchlistChartRange, nowFunc())
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid watchlist chart range")
+		return
+	}
+	label := rng.label()
+	barsBySymbol, adjustedNote, err := fetchCompareBars(ctx, allowed, rng)
+	if err != nil {
+		msg := "Could not fetch history for the watchlist chart. Please try again later."
+		if errors.Is(err, errDatabentoAPIKeyNotConfigured) {
+			msg = "Watchlist chart is unavailable: DATABENTO_API_KEY is not configured."
+		}
+		log.Error().Err(err).Strs("symbols", allowed).Msg("Failed to fetch watchlist chart bars")
+		sendSettingsReply(ctx, b, update.Message, msg)
+		return
+	}
+
+	comparison := comparePerformance(allowed, barsBySymbol)
+	caption := formatComparisonSummary(label, comparison, blocked)
+	if adjustedNote != "" {
+		caption += "\n" + adjustedNote
+	}
+	if len(comparison.series) == 0 {
+		sendSettingsReply(ctx, b, update.Message, caption)
+		return
+	}
+	chartPNG, err := renderComparisonChartPNG(label, comparison)
+	if err != nil {
+		log.Warn().Err(err).Strs("symbols", allowed).Msg("Failed to render watchlist chart; sending text only")
+		sendSettingsReply(ctx, b, update.Message, caption)
+		return
+	}
+
+	_, sendErr := b.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: update.Message.MessageThreadID,
+		Photo: &models.InputFileUpload{
+			Filename: historicalFileLabel("watchlist", rng),
+			Data:     bytes.NewReader(chartPNG),
+		},
+		Caption: caption,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                update.Message.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+	if sendErr != nil {
+		log.Warn().Err(sendErr).Strs("symbols", allowed).Msg("Failed to send watchlist chart image; sending text only")
+		sendSettingsReply(ctx, b, update.Message, caption)
+	}
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShouldHandleWatchlist(t *testing.T) {
+	t.Parallel()
+
+	for text, want := range map[string]bool{
+		"!wl":              true,
+		"!wl chart":        true,
+		"!wl add AAPL":     true,
+		"!wlx":             false,
+		"!s AAPL":          false,
+		"  !wl rm NVDA  ":  true,
+		"!wl\tadd AAPL  ":  true,
+		"hello !wl add AA": false,
+	} {
+		if got := shouldHandleWatchlist(groupTextUpdate(text)); got != want {
+			t.Errorf("shouldHandleWatchlist(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestAddWatchlistSymbols(t *testing.T) {
+	t.Parallel()
+
+	stored := []string{"AAPL"}
+	got, dropped := addWatchlistSymbols(stored, []string{"AAPL", "MSFT", "A", "B", "C", "D", "E", "F", "G", "H"})
+	if strings.Join(got, ",") != "AAPL,MSFT,A,B,C,D,E,F" || strings.Join(dropped, ",") != "G,H" {
+		t.Fatalf("addWatchlistSymbols() = %q, dropped %q", got, dropped)
+	}
+	if len(stored) != 1 {
+		t.Fatal("addWatchlistSymbols() modified the stored slice")
+	}
+}
+
+func TestWatchlistHandlerAddRemovePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), chatSettingsFileName)
+	store := newChatSettingsStore(path)
+	withChatSettingsStore(t, store)
+	orig := blockedStocks
+	t.Cleanup(func() { blockedStocks = orig })
+	blockedStocks = map[string]string{"SCAM": "SCAM is not available."}
+	b, srv := newTestBot(t)
+	ctx := context.Background()
+
+	watchlistHandler(ctx, b, groupTextUpdate("!wl add aapl NVDA scam aapl"))
+	if srv.lastMessage != "Watchlist: AAPL, NVDA\nUnavailable: SCAM" {
+		t.Fatalf("add reply = %q", srv.lastMessage)
+	}
+
+	watchlistHandler(ctx, b, groupTextUpdate("!wl rm nvda"))
+	if srv.lastMessage != "Watchlist: AAPL" {
+		t.Fatalf("rm reply = %q", srv.lastMessage)
+	}
+
+	reloaded, err := loadChatSettingsStore(path)
+	if err != nil {
+		t.Fatalf("loadChatSettingsStore() error: %v", err)
+	}
+	if got := reloaded.get(-100).Watchlist; strings.Join(got, ",") != "AAPL" {
+		t.Fatalf("persisted watchlist = %q, want AAPL", got)
+	}
+
+	watchlistHandler(ctx, b, groupTextUpdate("!wl rm AAPL"))
+	if srv.lastMessage != watchlistEmptyMsg {
+		t.Fatalf("emptied reply = %q", srv.lastMessage)
+	}
+	if !store.get(-100).isZero() {
+		t.Fatal("an empty watchlist should leave no chat settings behind")
+	}
+}
+
+func TestWatchlistHandlerRejectsBadInput(t *testing.T) {
+	withChatSettingsStore(t, newChatSettingsStore(""))
+	b, srv := newTestBot(t)
+
+	for text, want := range map[string]string{
+		"!wl add":        "Usage",
+		"!wl sort":       "Usage",
+		"!wl add $$$":    `invalid stock symbol "$$$"`,
+		"!wl":            watchlistEmptyMsg,
+		"!wl chart":      watchlistEmptyMsg,
+		"!wl chart AAPL": "Usage",
+	} {
+		watchlistHandler(context.Background(), b, groupTextUpdate(text))
+		if !strings.Contains(srv.lastMessage, want) {
+			t.Errorf("%s: reply = %q, want %q", text, srv.lastMessage, want)
+		}
+	}
+}
+
+func TestWatchlistHandlerShowsQuoteTable(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	if err := store.update(-100, func(s *chatSettings) { s.Watchlist = []string{"AAPL", "SCAM", "MSFT"} }); err != nil {
+		t.Fatal(err)
+	}
+	orig := blockedStocks
+	t.Cleanup(func() { blockedStocks = orig })
+	blockedStocks = map[string]string{"SCAM": "SCAM is not available."}
+	requested := serveFinnhubQuotes(t, map[string]float64{"AAPL": 250, "MSFT": 410})
+	b, srv := newTestBot(t)
+
+	watchlistHandler(context.Background(), b, groupTextUpdate("!wl"))
+
+	if strings.Contains(strings.Join(*requested, ","), "SCAM") || len(*requested) != 2 {
+		t.Fatalf("requested quotes %q, want AAPL and MSFT only", *requested)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, want := range []string{"SYM", "AAPL", "250", "MSFT", "410", "SCAM", "unavailable"} {
+		if !strings.Contains(srv.lastMessage, want) {
+			t.Errorf("table missing %q:\n%s", want, srv.lastMessage)
+		}
+	}
+}
+
+func TestWatchlistChartWithoutDatabentoKey(t *testing.T) {
+	store := newChatSettingsStore("")
+	withChatSettingsStore(t, store)
+	if err := store.update(-100, func(s *chatSettings) { s.Watchlist = []string{"AAPL", "MSFT"} }); err != nil {
+		t.Fatal(err)
+	}
+	serveFinnhubQuotes(t, map[string]float64{"AAPL": 250, "MSFT": 410})
+	t.Setenv("DATABENTO_API_KEY", "")
+	b, srv := newTestBot(t)
+
+	watchlistHandler(context.Background(), b, groupTextUpdate("!wl chart"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.lastMessage, "DATABENTO_API_KEY is not configured") {
+		t.Fatalf("last message = %q, want the chart's not-configured notice after the table", srv.lastMessage)
+	}
+}
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	appotel "gitlab.com/yelinaung/csy-helper-bot/internal/otel"
+)
+
+// ttlCache is a bounded cache whose entries expire after ttl. When full it
+// evicts the least recently used entry in O(1), unlike the linear
+// oldest-entry scan in exaCache. A nil *ttlCache is a
+// valid, always-missing cache so callers can disable caching by leaving it
+// unset.
+type ttlCache[V any] struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // front = most recently used
+	entries map[string]*list.Element
+}
+
+type ttlCacheEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// newTTLCache returns nil (caching disabled) when ttl or maxEntries is not
+// positive. name labels the hit/miss metrics.
+func newTTLCache[V any](name string, ttl time.Duration, maxEntries int) *ttlCache[V] {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil
+	}
+	return &ttlCache[V]{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the live value for key and records a hit or miss.
+func (c *ttlCache[V]) get(ctx context.Context, key string) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	value, ok := c.lookupLocked(key)
+	c.mu.Unlock()
+
+	recordCacheLookup(ctx, c.name, ok)
+	if !ok {
+		return zero, false
+	}
+	return value, true
+}
+
+func (c *ttlCache[V]) lookupLocked(key string) (V, bool) {
+	var zero V
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*ttlCacheEntry[V])
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set stores value under key, evicting the least recently used entry when
+// the cache is full.
+func (c *ttlCache[V]) set(key string, value V) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*ttlCacheEntry[V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	for c.order.Len() >= c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*ttlCacheEntry[V]).key)
+	}
+	c.entries[key] = c.order.PushFront(&ttlCacheEntry[V]{key: key, value: value, expiresAt: expiresAt})
+}
+
+func (c *ttlCache[V]) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// recordCacheLookup increments bot.cache.lookups.total for the named cache.
+func recordCacheLookup(ctx context.Context, cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	appotel.Instruments().CacheLookupsTotal.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("cache", cache),
+		attribute.String("result", result),
+	))
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestTTLCache(ttl time.Duration, maxEntries int) (*ttlCache[string], *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newTTLCache[string]("test", ttl, maxEntries)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestTTLCacheExpiresEntries(t *testing.T) {
+	cache, now := newTestTTLCache(time.Minute, 10)
+	ctx := context.Background()
+
+	cache.set("a", "1")
+	if got, ok := cache.get(ctx, "a"); !ok || got != "1" {
+		t.Fatalf("expected hit, got %q %v", got, ok)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, ok := cache.get(ctx, "a"); ok {
+		t.Fatal("expected entry to expire at its TTL")
+	}
+	if cache.len() != 0 {
+		t.Fatalf("expected expired entry to be removed, len=%d", cache.len())
+	}
+}
+
+func TestTTLCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, _ := newTestTTLCache(time.Hour, 2)
+	ctx := context.Background()
+
+	cache.set("a", "1")
+	cache.set("b", "2")
+	// Touch "a" so "b" becomes the least recently used entry.
+	if _, ok := cache.get(ctx, "a"); !ok {
+		t.Fatal("expected hit for a")
+	}
+	cache.set("c", "3")
+
+	if _, ok := cache.get(ctx, "b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(ctx, key); !ok {
+			t.Fatalf("expected %s to survive eviction", key)
+		}
+	}
+	if cache.len() != 2 {
+		t.Fatalf("expected cache bounded at 2, got %d", cache.len())
+	}
+}
+
+func TestTTLCacheSetRefreshesExistingEntry(t *testing.T) {
+	cache, now := newTestTTLCache(time.Minute, 2)
+	ctx := context.Background()
+
+	cache.set("a", "1")
+	*now = now.Add(50 * time.Second)
+	cache.set("a", "2")
+	*now = now.Add(50 * time.Second)
+
+	if got, ok := cache.get(ctx, "a"); !ok || got != "2" {
+		t.Fatalf("expected refreshed value, got %q %v", got, ok)
+	}
+	if cache.len() != 1 {
+		t.Fatalf("expected a single entry, got %d", cache.len())
+	}
+}
+
+func TestTTLCacheDisabled(t *testing.T) {
+	if newTTLCache[string]("test", 0, 10) != nil {
+		t.Fatal("expected zero TTL to disable the cache")
+	}
+	if newTTLCache[string]("test", time.Minute, 0) != nil {
+		t.Fatal("expected zero size to disable the cache")
+	}
+
+	var cache *ttlCache[string]
+	cache.set("a", "1")
+	if _, ok := cache.get(context.Background(), "a"); ok {
+		t.Fatal("expected nil cache to always miss")
+	}
+	if cache.len() != 0 {
+		t.Fatal("expected nil cache to be empty")
+	}
+}
@@ -1,8 +1,10 @@
 package bot
 
 import (
-	"net"
+	"net/netip"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/go-telegram/bot/models"
@@ -22,7 +24,9 @@ const maxExtractRawURLLen = 2048
 // those in the quoted/replied-to text. It returns up to maxURLs normalized,
 // deduplicated URLs (question-side first) plus the question with every
 // literal URL token it found removed, for use as the extract objective.
-func extractQuestionURLs(message *models.Message, question, quoted string, maxURLs int) (urls []string, strippedQuestion string) {
+// URLs the chat's domain policy (see domainPolicyFor) rules out are returned
+// separately in denied and do not count towards maxURLs.
+func extractQuestionURLs(message *models.Message, question, quoted string, maxURLs int) (urls []string, denied []deniedURL, strippedQuestion string) {
 	if maxURLs <= 0 {
 		maxURLs = defaultExtractMaxURLs
 	}
@@ -65,6 +69,7 @@ func extractQuestionURLs(message *models.Message, question, quoted string, maxUR
 		candidates = append(candidates, scanTextURLs(quoted)...)
 	}
 
+	policy := domainPolicyFor(message)
 	seen := make(map[string]struct{}, len(candidates))
 	for _, raw := range candidates {
 		normalized, ok := normalizeExtractURL(raw)
@@ -75,13 +80,17 @@ func extractQuestionURLs(message *models.Message, question, quoted string, maxUR
 			continue
 		}
 		seen[normalized] = struct{}{}
+		if host, reason := policy.checkURL(normalized); reason != "" {
+			denied = append(denied, deniedURL{URL: normalized, Host: host, Reason: reason})
+			continue
+		}
 		urls = append(urls, normalized)
 		if len(urls) >= maxURLs {
 			break
 		}
 	}
 
-	return urls, stripKnownURLTokens(question, questionURLTokens)
+	return urls, denied, stripKnownURLTokens(question, questionURLTokens)
 }
 
 // extractObjectiveFor turns a stripped question into a Parallel Extract
@@ -328,19 +337,138 @@ func normalizeExtractURL(raw string) (string, bool) {
 	return candidate, true
 }
 
-// isLocalOrPrivateHost reports whether host resolves to localhost or a
-// private/link-local address. Extraction is fetched by Parallel's own
-// infrastructure, not this process, so this is not an SSRF guard; it just
-// avoids paying for and confusingly failing on URLs that could never
-// resolve to a real public page.
+// isLocalOrPrivateHost reports whether host is localhost or a literal
+// internal address (see isDisallowedIP). It judges only the literal string:
+// it avoids paying Parallel for URLs that could never be a public page, while
+// the DNS-aware check for pages this process fetches itself lives in the
+// local extractor's transport (ssrfGuardDialer).
 func isLocalOrPrivateHost(host string) bool {
-	if strings.EqualFold(host, "localhost") {
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
 		return true
 	}
-	ip := net.ParseIP(host)
-	if ip == nil {
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
 		return false
 	}
-	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
-		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+	return isDisallowedIP(ip)
+}
+
+// githubLinkKind is the kind of GitHub page a link points at.
+type githubLinkKind string
+
+const (
+	githubLinkRepo  githubLinkKind = "repo"
+	githubLinkIssue githubLinkKind = "issue"
+	githubLinkPull  githubLinkKind = "pull"
+	githubLinkBlob  githubLinkKind = "blob"
+)
+
+// githubLink is a github.com URL resolved to what the REST API needs.
+// number is set for issues and pull requests; ref and path for blobs, with
+// startLine/endLine from a "#L10-L20" fragment (0 when absent).
+type githubLink struct {
+	kind      githubLinkKind
+	owner     string
+	repo      string
+	number    int
+	ref       string
+	path      string
+	startLine int
+	endLine   int
+}
+
+// githubReservedOwners are top-level github.com paths that are site pages,
+// not user or organization names.
+var githubReservedOwners = map[string]bool{
+	"about": true, "apps": true, "collections": true, "customer-stories": true,
+	"enterprise": true, "explore": true, "features": true, "login": true,
+	"marketplace": true, "notifications": true, "orgs": true, "pricing": true,
+	"search": true, "settings": true, "sponsors": true, "topics": true,
+	"trending": true, "users": true,
+}
+
+var githubLineFragmentRegexp = regexp.MustCompile(`^L(\d+)(?:C\d+)?(?:-L(\d+)(?:C\d+)?)?$`)
+
+// parseGitHubLink recognizes github.com links the GitHub REST API can answer
+// precisely: repositories (including /tree/ views), issues, pull requests
+// (including their /files and /commits tabs) and file blobs. Other GitHub
+// pages (discussions, wikis, profiles) go through generic extraction.
+func parseGitHubLink(rawURL string) (githubLink, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return githubLink{}, false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "github.com" && host != "www.github.com" {
+		return githubLink{}, false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] == "" || githubReservedOwners[strings.ToLower(segments[0])] {
+		return githubLink{}, false
+	}
+	link := githubLink{owner: segments[0], repo: strings.TrimSuffix(segments[1], ".git")}
+	if link.repo == "" {
+		return githubLink{}, false
+	}
+
+	if len(segments) == 2 {
+		link.kind = githubLinkRepo
+		return link, true
+	}
+
+	switch segments[2] {
+	case "tree":
+		link.kind = githubLinkRepo
+		return link, true
+	case "issues", "pull":
+		if len(segments) < 4 {
+			return githubLink{}, false
+		}
+		number, err := strconv.Atoi(segments[3])
+		if err != nil || number <= 0 {
+			return githubLink{}, false
+		}
+		link.kind = githubLinkIssue
+		if segments[2] == "pull" {
+			link.kind = githubLinkPull
+		}
+		link.number = number
+		return link, true
+	case "blob":
+		if len(segments) < 5 {
+			return githubLink{}, false
+		}
+		// A ref containing "/" (e.g. "release/1.2") is indistinguishable from
+		// a path here; the first segment is taken as the ref, which covers
+		// branch names without slashes, tags and commit SHAs.
+		link.kind = githubLinkBlob
+		link.ref = segments[3]
+		link.path = strings.Join(segments[4:], "/")
+		if m := githubLineFragmentRegexp.FindStringSubmatch(u.Fragment); m != nil {
+			link.startLine, _ = strconv.Atoi(m[1])
+			link.endLine = link.startLine
+			if m[2] != "" {
+				link.endLine, _ = strconv.Atoi(m[2])
+			}
+			if link.endLine < link.startLine {
+				link.startLine, link.endLine = link.endLine, link.startLine
+			}
+		}
+		return link, true
+	}
+	return githubLink{}, false
+}
+
+// splitGitHubURLs separates links the GitHub API can resolve from ordinary
+// pages, keeping each group's order.
+func splitGitHubURLs(urls []string) (github []string, pages []string) {
+	for _, u := range urls {
+		if _, ok := parseGitHubLink(u); ok {
+			github = append(github, u)
+		} else {
+			pages = append(pages, u)
+		}
+	}
+	return github, pages
 }
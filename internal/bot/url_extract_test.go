@@ -23,7 +23,7 @@ func TestExtractQuestionURLs_EntityInQuestion(t *testing.T) {
 	}
 	question := link + " what are the plan prices?"
 
-	urls, stripped := extractQuestionURLs(message, question, "", 3)
+	urls, _, stripped := extractQuestionURLs(message, question, "", 3)
 
 	if len(urls) != 1 || urls[0] != link {
 		t.Fatalf("urls = %v, want [%s]", urls, link)
@@ -49,7 +49,7 @@ func TestExtractQuestionURLs_TextLinkEntityInQuestion(t *testing.T) {
 	}
 	question := anchor + " what does it say?"
 
-	urls, stripped := extractQuestionURLs(message, question, "", 3)
+	urls, _, stripped := extractQuestionURLs(message, question, "", 3)
 
 	if len(urls) != 1 || urls[0] != "https://textlink.example.net/target" {
 		t.Fatalf("urls = %v, want [https://textlink.example.net/target]", urls)
@@ -82,7 +82,7 @@ func TestExtractQuestionURLs_EntitiesBeforeMentionIgnored(t *testing.T) {
 	// As askHandler would produce it: only the text after the mention.
 	question := "explain this"
 
-	urls, stripped := extractQuestionURLs(message, question, "", 3)
+	urls, _, stripped := extractQuestionURLs(message, question, "", 3)
 
 	if len(urls) != 0 {
 		t.Fatalf("urls = %v, want none (entity lives before the mention)", urls)
@@ -113,7 +113,7 @@ func TestExtractQuestionURLs_BareDomainEntityStrippedFromObjective(t *testing.T)
 	}
 	question := domain
 
-	urls, stripped := extractQuestionURLs(message, question, "", 3)
+	urls, _, stripped := extractQuestionURLs(message, question, "", 3)
 
 	if len(urls) != 1 || urls[0] != "https://"+domain {
 		t.Fatalf("urls = %v, want [https://%s]", urls, domain)
@@ -136,7 +136,7 @@ func TestExtractQuestionURLs_WrappedTokenStrippedFromObjective(t *testing.T) {
 
 	question := "(https://wrapped.example.net/a) what is this"
 
-	urls, stripped := extractQuestionURLs(nil, question, "", 3)
+	urls, _, stripped := extractQuestionURLs(nil, question, "", 3)
 
 	if len(urls) != 1 || urls[0] != "https://wrapped.example.net/a" {
 		t.Fatalf("urls = %v, want [https://wrapped.example.net/a]", urls)
@@ -151,7 +151,7 @@ func TestExtractQuestionURLs_FallbackScanNoEntities(t *testing.T) {
 
 	question := "https://fallback.example.net summarize this"
 
-	urls, stripped := extractQuestionURLs(nil, question, "", 3)
+	urls, _, stripped := extractQuestionURLs(nil, question, "", 3)
 
 	if len(urls) != 1 || urls[0] != "https://fallback.example.net" {
 		t.Fatalf("urls = %v, want [https://fallback.example.net]", urls)
@@ -183,7 +183,7 @@ func TestExtractQuestionURLs_QuoteEntitiesOnlySelectedSource(t *testing.T) {
 		},
 	}
 
-	urls, _ := extractQuestionURLs(message, "summarize", quoteText, 3)
+	urls, _, _ := extractQuestionURLs(message, "summarize", quoteText, 3)
 
 	if len(urls) != 1 || urls[0] != "https://quote.example.net/a" {
 		t.Fatalf("urls = %v, want only the quoted URL [https://quote.example.net/a]", urls)
@@ -204,7 +204,7 @@ func TestExtractQuestionURLs_ReplyTextEntitiesWhenNoQuote(t *testing.T) {
 		},
 	}
 
-	urls, _ := extractQuestionURLs(message, "summarize", replyText, 3)
+	urls, _, _ := extractQuestionURLs(message, "summarize", replyText, 3)
 
 	if len(urls) != 1 || urls[0] != "https://reply.example.net/b" {
 		t.Fatalf("urls = %v, want [https://reply.example.net/b]", urls)
@@ -226,7 +226,7 @@ func TestExtractQuestionURLs_ReplyCaptionEntitiesForNonPhotoMedia(t *testing.T)
 		},
 	}
 
-	urls, _ := extractQuestionURLs(message, "summarize", caption, 3)
+	urls, _, _ := extractQuestionURLs(message, "summarize", caption, 3)
 
 	if len(urls) != 1 || urls[0] != "https://caption.example.net/c" {
 		t.Fatalf("urls = %v, want [https://caption.example.net/c]", urls)
@@ -250,7 +250,7 @@ func TestExtractQuestionURLs_BotOwnedQuoteIgnored(t *testing.T) {
 		},
 	}
 
-	urls, _ := extractQuestionURLs(message, "explain this", replyText, 3)
+	urls, _, _ := extractQuestionURLs(message, "explain this", replyText, 3)
 
 	if len(urls) != 0 {
 		t.Fatalf("urls = %v, want none (quoted message is from the bot)", urls)
@@ -262,7 +262,7 @@ func TestExtractQuestionURLs_DedupAndCap(t *testing.T) {
 
 	question := "https://dedup.example.net/a https://dedup.example.net/a https://dedup.example.net/b https://dedup.example.net/c summarize"
 
-	urls, _ := extractQuestionURLs(nil, question, "", 2)
+	urls, _, _ := extractQuestionURLs(nil, question, "", 2)
 
 	want := []string{"https://dedup.example.net/a", "https://dedup.example.net/b"}
 	if len(urls) != len(want) {
@@ -284,7 +284,7 @@ func TestExtractQuestionURLs_DedupIgnoresHostCase(t *testing.T) {
 
 	question := "https://Dedup-Case.example.net/a https://dedup-case.EXAMPLE.NET/a summarize"
 
-	urls, _ := extractQuestionURLs(nil, question, "", 3)
+	urls, _, _ := extractQuestionURLs(nil, question, "", 3)
 
 	if len(urls) != 1 || urls[0] != "https://dedup-case.example.net/a" {
 		t.Fatalf("urls = %v, want a single lowercased URL", urls)
@@ -303,7 +303,7 @@ func TestExtractQuestionURLs_QuestionURLsBeforeQuotedURLs(t *testing.T) {
 		},
 	}
 
-	urls, _ := extractQuestionURLs(message, question, quoted, 3)
+	urls, _, _ := extractQuestionURLs(message, question, quoted, 3)
 
 	if len(urls) != 2 || urls[0] != "https://order.example.net/question-link" || urls[1] != "https://order.example.net/quoted-link" {
 		t.Fatalf("urls = %v, want question link first", urls)
@@ -315,7 +315,7 @@ func TestExtractQuestionURLs_BareLinkQuestionStripsToEmpty(t *testing.T) {
 
 	question := "https://bare.example.net/article"
 
-	urls, stripped := extractQuestionURLs(nil, question, "", 3)
+	urls, _, stripped := extractQuestionURLs(nil, question, "", 3)
 
 	if len(urls) != 1 {
 		t.Fatalf("urls = %v, want 1", urls)
@@ -494,3 +494,46 @@ func TestUrlsFromEntities_EmptyTextSourceWithEntities(t *testing.T) {
 		t.Fatalf("urlsFromEntities() = %v", got)
 	}
 }
+
+func TestParseGitHubLink(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url  string
+		want githubLink
+		ok   bool
+	}{
+		{url: "https://github.com/golang/go", want: githubLink{kind: githubLinkRepo, owner: "golang", repo: "go"}, ok: true},
+		{url: "https://github.com/golang/go.git", want: githubLink{kind: githubLinkRepo, owner: "golang", repo: "go"}, ok: true},
+		{url: "https://www.github.com/golang/go/tree/master/src", want: githubLink{kind: githubLinkRepo, owner: "golang", repo: "go"}, ok: true},
+		{url: "https://github.com/golang/go/issues/123", want: githubLink{kind: githubLinkIssue, owner: "golang", repo: "go", number: 123}, ok: true},
+		{url: "https://github.com/golang/go/pull/456/files", want: githubLink{kind: githubLinkPull, owner: "golang", repo: "go", number: 456}, ok: true},
+		{
+			url:  "https://github.com/golang/go/blob/master/src/fmt/print.go#L10-L20",
+			want: githubLink{kind: githubLinkBlob, owner: "golang", repo: "go", ref: "master", path: "src/fmt/print.go", startLine: 10, endLine: 20},
+			ok:   true,
+		},
+		{
+			url:  "https://github.com/golang/go/blob/v1.22.0/README.md#L7",
+			want: githubLink{kind: githubLinkBlob, owner: "golang", repo: "go", ref: "v1.22.0", path: "README.md", startLine: 7, endLine: 7},
+			ok:   true,
+		},
+		{url: "https://github.com/golang/go/issues", ok: false},
+		{url: "https://github.com/golang/go/pull/abc", ok: false},
+		{url: "https://github.com/golang/go/discussions/1", ok: false},
+		{url: "https://github.com/golang", ok: false},
+		{url: "https://github.com/settings/profile", ok: false},
+		{url: "https://gist.github.com/user/abc", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := parseGitHubLink(tt.url)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseGitHubLink(%q) = %+v, %v; want %+v, %v", tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	github, pages := splitGitHubURLs([]string{"https://example.com", "https://github.com/golang/go/pull/1"})
+	if len(github) != 1 || len(pages) != 1 || pages[0] != "https://example.com" {
+		t.Fatalf("splitGitHubURLs() = %v, %v", github, pages)
+	}
+}
@@ -0,0 +1,262 @@
+package bot
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultExaSearchBaseURL = "https://api.exa.ai/search"
+	defaultExaWebTimeout    = 10 * time.Second
+
+	webSearchProviderParallel = "parallel"
+	webSearchProviderExa      = "exa"
+
+	// defaultWebSearchProviders is the order ask grounding tries providers
+	// in; unconfigured providers are skipped.
+	defaultWebSearchProviders = webSearchProviderParallel + "," + webSearchProviderExa
+
+	exaHighlightSentences = 3
+)
+
+// webSearchProvider is one vendor in the ask-grounding search chain. Every
+// provider maps its results onto promptWebResult so the grounded explainer
+// does not care which one answered.
+type webSearchProvider interface {
+	providerName() string
+	searchWeb(ctx context.Context, objective string, queries []string) ([]promptWebResult, error)
+}
+
+func (p *parallelSearcher) providerName() string { return webSearchProviderParallel }
+
+func (p *parallelSearcher) searchWeb(ctx context.Context, objective string, queries []string) ([]promptWebResult, error) {
+	results, err := p.search(ctx, objective, queries)
+	if err != nil {
+		return nil, err
+	}
+	return toPromptWebResults(results), nil
+}
+
+type exaWebSearchRequest struct {
+	Query      string            `json:"query"`
+	Type       string            `json:"type"`
+	NumResults int               `json:"numResults"` //nolint:tagliatelle // Exa API uses camelCase.
+	Contents   exaWebContentsReq `json:"contents"`
+}
+
+type exaWebContentsReq struct {
+	Highlights exaHighlightsReq `json:"highlights"`
+}
+
+type exaHighlightsReq struct {
+	Query            string `json:"query,omitempty"`
+	NumSentences     int    `json:"numSentences"`     //nolint:tagliatelle // Exa API uses camelCase.
+	HighlightsPerURL int    `json:"highlightsPerUrl"` //nolint:tagliatelle // Exa API uses camelCase.
+}
+
+// exaWebSearcher is a general-purpose Exa web search client for ask
+// grounding, unlike searchStockNews which is tuned for !sa (news category,
+// last 30 days, stock query template). Configuration is captured at
+// construction so tests can inject a local server.
+type exaWebSearcher struct {
+	baseURL    string
+	apiKey     string
+	timeout    time.Duration
+	numResults int
+	cache      *ttlCache[[]promptWebResult]
+}
+
+// newExaWebSearcher returns nil when EXA_API_KEY is not configured.
+func newExaWebSearcher() *exaWebSearcher {
+	apiKey := getenvTrim("EXA_API_KEY")
+	if apiKey == "" {
+		return nil
+	}
+	return &exaWebSearcher{
+		baseURL:    defaultExaSearchBaseURL,
+		apiKey:     apiKey,
+		timeout:    defaultExaWebTimeout,
+		numResults: loadExaNumResults(),
+		cache:      exaWebSearchCache,
+	}
+}
+
+func (e *exaWebSearcher) providerName() string { return webSearchProviderExa }
+
+// searchWeb sends the objective as Exa's natural-language query and uses the
+// keyword queries to steer which highlights come back.
+func (e *exaWebSearcher) searchWeb(ctx context.Context, objective string, queries []string) (results []promptWebResult, err error) {
+	if e == nil {
+		return nil, errors.New("exa web searcher not configured")
+	}
+
+	ctx, span := tracer().Start(
+		ctx, "exa.web_search",
+		trace.WithAttributes(
+			// Length only: the objective can be the user's own question.
+			attribute.Int("exa.objective_len", len(strings.TrimSpace(objective))),
+			attribute.Int("exa.queries_count", len(queries)),
+			attribute.Int("exa.num_results", e.numResults),
+		),
+	)
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	objective = strings.TrimSpace(objective)
+	if objective == "" {
+		return nil, errors.New("search objective is required")
+	}
+
+	cacheKey := parallelSearchCacheKey(objective, queries)
+	if cached, ok := e.cache.get(ctx, cacheKey); ok {
+		span.SetAttributes(attribute.Bool("exa.cache_hit", true))
+		return slices.Clone(cached), nil
+	}
+
+	reqBody := exaWebSearchRequest{
+		Query:      objective,
+		Type:       "auto",
+		NumResults: e.numResults,
+		Contents: exaWebContentsReq{Highlights: exaHighlightsReq{
+			Query:            strings.Join(queries, " "),
+			NumSentences:     exaHighlightSentences,
+			HighlightsPerURL: maxParallelExcerptsPerItem,
+		}},
+	}
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal exa web search request: %w", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, cmp.Or(e.timeout, defaultExaWebTimeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(timeoutCtx, http.MethodPost, e.baseURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create exa web search request: %w", err)
+	}
+	req.Header.Set("x-api-key", e.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("exa web search request failed: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exa web search returned status %d", resp.StatusCode)
+	}
+
+	var searchResp exaSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("decode exa web search response: %w", err)
+	}
+
+	log.Info().
+		Str("request_id", searchResp.RequestID).
+		Float64("cost_dollars", searchResp.CostDollars.Total).
+		Int("result_count", len(searchResp.Results)).
+		Msg("Exa web search completed")
+
+	results = exaResultsToPromptWebResults(sanitizeExaResults(searchResp.Results))
+	if len(results) > 0 {
+		e.cache.set(cacheKey, slices.Clone(results))
+	}
+	return results, nil
+}
+
+// exaResultsToPromptWebResults maps sanitized Exa results onto the prompt
+// shape. Exa's publishedDate is a full timestamp; only the date is kept, as
+// Parallel reports it.
+func exaResultsToPromptWebResults(results []exaSearchResult) []promptWebResult {
+	webResults := make([]promptWebResult, 0, len(results))
+	for _, r := range results {
+		webResults = append(webResults, promptWebResult{
+			Title:       r.Title,
+			URL:         r.URL,
+			PublishDate: dateOnly(r.PublishedDate),
+			Excerpts:    r.Highlights[:min(len(r.Highlights), maxParallelExcerptsPerItem)],
+		})
+	}
+	return webResults
+}
+
+// loadWebSearchProviders builds the ask-grounding search chain from
+// WEB_SEARCH_PROVIDERS (comma-separated, default "parallel,exa"), skipping
+// providers whose API key is not configured.
+func loadWebSearchProviders() []webSearchProvider {
+	raw := cmp.Or(getenvTrim("WEB_SEARCH_PROVIDERS"), defaultWebSearchProviders)
+
+	var providers []webSearchProvider
+	var seen []string
+	for name := range strings.SplitSeq(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(seen, name) {
+			continue
+		}
+		seen = append(seen, name)
+
+		switch name {
+		case webSearchProviderParallel:
+			if searcher := newParallelSearcher(); searcher != nil {
+				providers = append(providers, searcher)
+			}
+		case webSearchProviderExa:
+			if searcher := newExaWebSearcher(); searcher != nil {
+				providers = append(providers, searcher)
+			}
+		default:
+			log.Warn().Str("provider", name).Msg("Unknown WEB_SEARCH_PROVIDERS entry; skipping")
+		}
+	}
+	return providers
+}
+
+// searchWebChain tries each provider in order and returns the first
+// non-empty result set, so a freshness question stays grounded when one
+// vendor is down. It returns nil when every provider failed or came back
+// empty.
+func searchWebChain(ctx context.Context, providers []webSearchProvider, objective string, queries []string) (results []promptWebResult) {
+	ctx, span := tracer().Start(
+		ctx, "web_search",
+		trace.WithAttributes(attribute.Int("web_search.providers_count", len(providers))),
+	)
+	defer span.End()
+
+	for i, provider := range providers {
+		results, err := provider.searchWeb(ctx, objective, queries)
+		switch {
+		case err != nil:
+			log.Warn().Err(err).Str("provider", provider.providerName()).Msg("Web search provider failed; trying next provider")
+		case len(results) == 0:
+			log.Warn().Str("provider", provider.providerName()).Msg("Web search provider returned no results; trying next provider")
+		default:
+			span.SetAttributes(
+				attribute.String("web_search.provider", provider.providerName()),
+				attribute.Int("web_search.attempts", i+1),
+			)
+			return results
+		}
+	}
+	span.SetAttributes(attribute.Int("web_search.attempts", len(providers)))
+	return nil
+}
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestExaWebSearcher(t *testing.T, handler http.HandlerFunc) *exaWebSearcher {
+	t.Helper()
+	server := httptest.NewTestServer(t, handler)
+	server.Start()
+	return &exaWebSearcher{
+		baseURL:    server.URL,
+		apiKey:     "exa-key",
+		timeout:    5 * time.Second,
+		numResults: 3,
+	}
+}
+
+func TestExaWebSearcher_Success(t *testing.T) {
+	t.Parallel()
+
+	var gotRequest exaWebSearchRequest
+	searcher := newTestExaWebSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-api-key"); got != "exa-key" {
+			t.Errorf("expected x-api-key header, got %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotRequest); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(exaSearchResponse{Results: []exaSearchResult{
+			{
+				Title:         "Go 1.27 released",
+				URL:           "https://go.dev/blog/go1.27",
+				PublishedDate: "2026-08-12T00:00:00.000Z",
+				Highlights:    []string{"one", "two", "three", "four", "five"},
+			},
+			{Title: "", Highlights: nil},
+		}})
+	})
+
+	results, err := searcher.searchWeb(context.Background(), "latest Go release", []string{"go release", "golang version"})
+	if err != nil {
+		t.Fatalf("searchWeb() error = %v", err)
+	}
+
+	if gotRequest.Query != "latest Go release" || gotRequest.NumResults != 3 || gotRequest.Type != "auto" {
+		t.Errorf("unexpected request %+v", gotRequest)
+	}
+	if gotRequest.Contents.Highlights.Query != "go release golang version" {
+		t.Errorf("expected keyword queries to steer highlights, got %q", gotRequest.Contents.Highlights.Query)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected empty result to be dropped, got %d results", len(results))
+	}
+	got := results[0]
+	if got.Title != "Go 1.27 released" || got.URL != "https://go.dev/blog/go1.27" || got.PublishDate != "2026-08-12" {
+		t.Errorf("unexpected mapped result %+v", got)
+	}
+	if len(got.Excerpts) != maxParallelExcerptsPerItem {
+		t.Errorf("expected excerpts capped at %d, got %d", maxParallelExcerptsPerItem, len(got.Excerpts))
+	}
+}
+
+func TestExaWebSearcher_ErrorsAndCache(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	searcher := newTestExaWebSearcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(exaSearchResponse{Results: []exaSearchResult{{Title: "t", Highlights: []string{"h"}}}})
+	})
+	searcher.cache = newTTLCache[[]promptWebResult]("test", time.Minute, 10)
+
+	if _, err := searcher.searchWeb(context.Background(), "objective", nil); err == nil {
+		t.Fatal("expected non-200 to fail")
+	}
+	for range 2 {
+		if results, err := searcher.searchWeb(context.Background(), "objective", nil); err != nil || len(results) != 1 {
+			t.Fatalf("searchWeb() = %v, %v", results, err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected the successful search to be cached, got %d calls", got)
+	}
+
+	var nilSearcher *exaWebSearcher
+	if _, err := nilSearcher.searchWeb(context.Background(), "objective", nil); err == nil {
+		t.Fatal("expected nil searcher error")
+	}
+	if _, err := searcher.searchWeb(context.Background(), "  ", nil); err == nil {
+		t.Fatal("expected empty objective error")
+	}
+}
+
+func TestNewExaWebSearcher_SharesCacheAcrossRequests(t *testing.T) {
+	orig := exaWebSearchCache
+	t.Cleanup(func() { exaWebSearchCache = orig })
+	t.Setenv("EXA_API_KEY", "test-key")
+	exaWebSearchCache = newTTLCache[[]promptWebResult]("test", time.Minute, 10)
+
+	var calls atomic.Int32
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(exaSearchResponse{Results: []exaSearchResult{{Title: "t", Highlights: []string{"h"}}}})
+	}))
+	server.Start()
+
+	for range 2 {
+		searcher := newExaWebSearcher()
+		searcher.baseURL = server.URL
+		if _, err := searcher.searchWeb(context.Background(), "objective", nil); err != nil {
+			t.Fatalf("searchWeb() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected the second searcher to hit the shared cache, got %d calls", got)
+	}
+}
+
+// fakeSearchProvider returns results or err and counts calls.
+type fakeSearchProvider struct {
+	name    string
+	results []promptWebResult
+	err     error
+	calls   int
+}
+
+func (f *fakeSearchProvider) providerName() string { return f.name }
+
+func (f *fakeSearchProvider) searchWeb(context.Context, string, []string) ([]promptWebResult, error) {
+	f.calls++
+	return f.results, f.err
+}
+
+func TestSearchWebChainFallsThroughProviders(t *testing.T) {
+	t.Parallel()
+
+	down := &fakeSearchProvider{name: "parallel", err: errors.New("503")}
+	empty := &fakeSearchProvider{name: "empty"}
+	exa := &fakeSearchProvider{name: "exa", results: []promptWebResult{{Title: "from exa"}}}
+	never := &fakeSearchProvider{name: "never", results: []promptWebResult{{Title: "unused"}}}
+
+	results := searchWebChain(context.Background(), []webSearchProvider{down, empty, exa, never}, "objective", nil)
+	if len(results) != 1 || results[0].Title != "from exa" {
+		t.Fatalf("expected the first non-empty provider's results, got %+v", results)
+	}
+	if down.calls != 1 || empty.calls != 1 || exa.calls != 1 || never.calls != 0 {
+		t.Fatalf("unexpected call counts: %d %d %d %d", down.calls, empty.calls, exa.calls, never.calls)
+	}
+
+	if results := searchWebChain(context.Background(), []webSearchProvider{down}, "objective", nil); results != nil {
+		t.Fatalf("expected nil when every provider fails, got %+v", results)
+	}
+}
+
+func TestLoadWebSearchProviders(t *testing.T) {
+	t.Setenv("PARALLEL_API_KEY", "parallel-key")
+	t.Setenv("EXA_API_KEY", "exa-key")
+
+	names := func() []string {
+		var out []string
+		for _, p := range loadWebSearchProviders() {
+			out = append(out, p.providerName())
+		}
+		return out
+	}
+
+	t.Setenv("WEB_SEARCH_PROVIDERS", "")
+	if got := names(); len(got) != 2 || got[0] != "parallel" || got[1] != "exa" {
+		t.Fatalf("default chain = %v", got)
+	}
+
+	t.Setenv("WEB_SEARCH_PROVIDERS", " Exa , bing, exa, parallel")
+	if got := names(); len(got) != 2 || got[0] != "exa" || got[1] != "parallel" {
+		t.Fatalf("configured chain = %v", got)
+	}
+
+	t.Setenv("WEB_SEARCH_PROVIDERS", "")
+	t.Setenv("PARALLEL_API_KEY", "")
+	if got := names(); len(got) != 1 || got[0] != "exa" {
+		t.Fatalf("expected unconfigured providers to be skipped, got %v", got)
+	}
+}
+
+func TestExplainWithWebResultsUsesPromptShape(t *testing.T) {
+	t.Parallel()
+
+	gen := &capturingGenerator{}
+	explainer := &geminiExplainer{generator: gen}
+	results := []promptWebResult{{Title: "Exa title", URL: "https://example.com", Excerpts: []string{"excerpt"}}}
+
+	if _, err := explainer.explainWithWebResults(context.Background(), "", "latest news?", results, false); err != nil {
+		t.Fatalf("explainWithWebResults() error = %v", err)
+	}
+	payload := extractPromptPayload(t, gen.capturedContents[0].Parts[0].Text)
+	if len(payload.WebResults) != 1 || payload.WebResults[0].Title != "Exa title" {
+		t.Fatalf("expected web results in payload, got %+v", payload.WebResults)
+	}
+}
@@ -0,0 +1,513 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultYouTubeWatchURL          = "https://www.youtube.com/watch"
+	defaultYouTubeTranscriptTimeout = 15 * time.Second
+
+	// maxYouTubePageBytes bounds the watch page read; the player response
+	// sits near the top of a page that is typically around 1 MiB.
+	maxYouTubePageBytes = 4 << 20
+	// maxTranscriptBytes bounds the caption track read (hours of speech).
+	maxTranscriptBytes = 4 << 20
+
+	// transcriptExcerptWindow groups caption cues into excerpts of about
+	// this much video time, each prefixed with its start timestamp.
+	transcriptExcerptWindow = time.Minute
+	maxTranscriptExcerpts   = 10
+)
+
+var (
+	errNoCaptions = errors.New("video has no usable captions")
+
+	youtubeVideoIDRegexp       = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	youtubePlayerResponseRegex = regexp.MustCompile(`ytInitialPlayerResponse\s*=\s*\{`)
+)
+
+// youtubeVideoID returns the video ID of a youtube.com or youtu.be link:
+// watch?v=, youtu.be/<id>, /shorts/, /embed/, /live/ and /v/ forms, on any
+// youtube.com subdomain (www, m, music).
+func youtubeVideoID(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	var id string
+	switch {
+	case host == "youtu.be":
+		id, _, _ = strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	case host == "youtube.com" || strings.HasSuffix(host, ".youtube.com"):
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range []string{"/shorts/", "/embed/", "/live/", "/v/"} {
+			if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+				id, _, _ = strings.Cut(rest, "/")
+				break
+			}
+		}
+	default:
+		return "", false
+	}
+
+	if !youtubeVideoIDRegexp.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// splitYouTubeURLs separates video links, which are answered from their
+// transcript, from ordinary pages, keeping each group's order.
+func splitYouTubeURLs(urls []string) (videos []string, pages []string) {
+	for _, u := range urls {
+		if _, ok := youtubeVideoID(u); ok {
+			videos = append(videos, u)
+		} else {
+			pages = append(pages, u)
+		}
+	}
+	return videos, pages
+}
+
+// transcriptLanguages is the caption language preference: the language the
+// bot will answer in first, then the other one.
+func transcriptLanguages(respondInBurmese bool) []string {
+	if respondInBurmese {
+		return []string{"my", "en"}
+	}
+	return []string{"en", "my"}
+}
+
+// youtubePlayerResponse is the slice of ytInitialPlayerResponse the
+// transcript fetcher reads.
+type youtubePlayerResponse struct {
+	PlayabilityStatus struct {
+		Status string `json:"status"`
+	} `json:"playabilityStatus"`
+	VideoDetails struct {
+		Title  string `json:"title"`
+		Author string `json:"author"`
+	} `json:"videoDetails"`
+	Captions struct {
+		Tracklist struct {
+			CaptionTracks []youtubeCaptionTrack `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+	Microformat struct {
+		Renderer struct {
+			PublishDate string `json:"publishDate"`
+		} `json:"playerMicroformatRenderer"`
+	} `json:"microformat"`
+}
+
+type youtubeCaptionTrack struct {
+	BaseURL        string `json:"baseUrl"`      //nolint:tagliatelle // YouTube uses camelCase.
+	LanguageCode   string `json:"languageCode"` //nolint:tagliatelle // YouTube uses camelCase.
+	Kind           string `json:"kind"`
+	IsTranslatable bool   `json:"isTranslatable"` //nolint:tagliatelle // YouTube uses camelCase.
+}
+
+// transcriptCue is one timed caption line.
+type transcriptCue struct {
+	start time.Duration
+	text  string
+}
+
+// youtubeTranscriptFetcher turns a video link into timestamped transcript
+// excerpts shaped like a page extract, so the grounded explainer treats a
+// video like any other page. Configuration is captured at construction so
+// tests can inject a local server.
+type youtubeTranscriptFetcher struct {
+	client   *http.Client
+	watchURL string
+	timeout  time.Duration
+}
+
+// newYouTubeTranscriptFetcher returns nil when EXTRACT_ENABLED or
+// YOUTUBE_TRANSCRIPTS_ENABLED is explicitly false.
+func newYouTubeTranscriptFetcher() *youtubeTranscriptFetcher {
+	if !loadExtractEnabled() || !loadYouTubeTranscriptsEnabled() {
+		return nil
+	}
+	return &youtubeTranscriptFetcher{
+		client:   httpClient,
+		watchURL: defaultYouTubeWatchURL,
+		timeout:  defaultYouTubeTranscriptTimeout,
+	}
+}
+
+// fetchTranscripts fetches every video's transcript concurrently and keeps
+// the ones that worked, in request order. Failures (no captions, private
+// or unavailable videos) are logged and skipped.
+func (f *youtubeTranscriptFetcher) fetchTranscripts(ctx context.Context, videoURLs []string, objective string, languages []string) []parallelExtractResult {
+	if f == nil || len(videoURLs) == 0 {
+		return nil
+	}
+
+	results := make([]parallelExtractResult, len(videoURLs))
+	errs := make([]error, len(videoURLs))
+	var wg sync.WaitGroup
+	for i, u := range videoURLs {
+		wg.Go(func() {
+			results[i], errs[i] = f.fetchTranscript(ctx, u, objective, languages)
+		})
+	}
+	wg.Wait()
+
+	var found []parallelExtractResult
+	for i, result := range results {
+		if errs[i] != nil {
+			// Only the video ID is logged: the posted URL can carry tracking
+			// or playlist parameters.
+			videoID, _ := youtubeVideoID(videoURLs[i])
+			log.Warn().
+				Err(errs[i]).
+				Str("video_id", videoID).
+				Msg("YouTube transcript unavailable")
+			continue
+		}
+		found = append(found, result)
+	}
+	return found
+}
+
+// fetchTranscript reads the video's caption track list from its watch page,
+// picks the best track for languages, and cuts the transcript into
+// excerpts relevant to the objective.
+func (f *youtubeTranscriptFetcher) fetchTranscript(ctx context.Context, videoURL string, objective string, languages []string) (result parallelExtractResult, err error) {
+	videoID, ok := youtubeVideoID(videoURL)
+	if !ok {
+		return result, errors.New("not a YouTube video URL")
+	}
+
+	ctx, span := tracer().Start(
+		ctx, "youtube.transcript",
+		trace.WithAttributes(attribute.String("youtube.video_id", videoID)),
+	)
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(f.timeout, defaultYouTubeTranscriptTimeout))
+	defer cancel()
+
+	watchURL, err := url.Parse(cmp.Or(f.watchURL, defaultYouTubeWatchURL))
+	if err != nil {
+		return result, fmt.Errorf("parse watch URL: %w", err)
+	}
+	watchURL.RawQuery = url.Values{"v": {videoID}, "hl": {"en"}}.Encode()
+
+	page, err := f.get(ctx, watchURL.String(), maxYouTubePageBytes)
+	if err != nil {
+		return result, fmt.Errorf("fetch watch page: %w", err)
+	}
+	player, err := parseYouTubePlayerResponse(page)
+	if err != nil {
+		return result, err
+	}
+	if status := player.PlayabilityStatus.Status; status != "" && status != "OK" {
+		return result, fmt.Errorf("video is not playable: %s", status)
+	}
+
+	track, translateTo, ok := pickCaptionTrack(player.Captions.Tracklist.CaptionTracks, languages)
+	if !ok {
+		return result, errNoCaptions
+	}
+	captionURL, err := captionTrackURL(watchURL, track.BaseURL, translateTo)
+	if err != nil {
+		return result, err
+	}
+
+	transcript, err := f.get(ctx, captionURL, maxTranscriptBytes)
+	if err != nil {
+		return result, fmt.Errorf("fetch caption track: %w", err)
+	}
+	cues, err := parseTimedText(strings.NewReader(transcript))
+	if err != nil {
+		return result, err
+	}
+	if len(cues) == 0 {
+		return result, errNoCaptions
+	}
+
+	excerpts := selectTranscriptExcerpts(transcriptExcerpts(cues, transcriptExcerptWindow), objective, maxTranscriptExcerpts)
+	for i, e := range excerpts {
+		excerpts[i] = sanitizeForPrompt(e, maxParallelExtractExcerptRuneLen)
+	}
+
+	span.SetAttributes(
+		attribute.String("youtube.caption_language", cmp.Or(translateTo, track.LanguageCode)),
+		attribute.Bool("youtube.caption_asr", track.Kind == "asr"),
+		attribute.Int("youtube.cues_count", len(cues)),
+		attribute.Int("youtube.excerpts_count", len(excerpts)),
+	)
+
+	title := player.VideoDetails.Title
+	if author := strings.TrimSpace(player.VideoDetails.Author); author != "" && title != "" {
+		title += " (" + author + ")"
+	}
+	return parallelExtractResult{
+		URL:         videoURL,
+		Title:       sanitizeForPrompt(title, maxTitleRuneLen),
+		PublishDate: player.Microformat.Renderer.PublishDate,
+		Excerpts:    excerpts,
+	}, nil
+}
+
+func (f *youtubeTranscriptFetcher) get(ctx context.Context, rawURL string, maxBytes int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", localExtractUserAgent)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// Skips the EU cookie consent interstitial that replaces the watch page.
+	req.Header.Set("Cookie", "CONSENT=YES+1")
+
+	resp, err := cmp.Or(f.client, httpClient).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+	return string(body), nil
+}
+
+// parseYouTubePlayerResponse decodes the ytInitialPlayerResponse object
+// embedded in a watch page. The decoder stops at the end of the object, so
+// the script text that follows it does not matter.
+func parseYouTubePlayerResponse(page string) (*youtubePlayerResponse, error) {
+	loc := youtubePlayerResponseRegex.FindStringIndex(page)
+	if loc == nil {
+		return nil, errors.New("watch page has no player response")
+	}
+	var player youtubePlayerResponse
+	if err := json.NewDecoder(strings.NewReader(page[loc[1]-1:])).Decode(&player); err != nil {
+		return nil, fmt.Errorf("decode player response: %w", err)
+	}
+	return &player, nil
+}
+
+// pickCaptionTrack chooses a caption track for the first language in
+// languages that has one, preferring uploaded captions over automatic
+// (asr) ones. When no track is in a preferred language, a translatable
+// track is machine-translated into the first preference; translateTo is
+// then the target language.
+func pickCaptionTrack(tracks []youtubeCaptionTrack, languages []string) (track youtubeCaptionTrack, translateTo string, ok bool) {
+	for _, lang := range languages {
+		for _, wantASR := range []bool{false, true} {
+			for _, t := range tracks {
+				if (t.Kind == "asr") == wantASR && captionLanguageMatches(t.LanguageCode, lang) {
+					return t, "", true
+				}
+			}
+		}
+	}
+	if len(languages) > 0 {
+		for _, t := range tracks {
+			if t.IsTranslatable {
+				return t, languages[0], true
+			}
+		}
+	}
+	return youtubeCaptionTrack{}, "", false
+}
+
+// captionLanguageMatches accepts regional variants: "en-GB" matches "en".
+func captionLanguageMatches(code, lang string) bool {
+	code = strings.ToLower(code)
+	return code == lang || strings.HasPrefix(code, lang+"-")
+}
+
+// captionTrackURL resolves a track's baseUrl against the watch page and
+// refuses hosts other than YouTube's (or the watch page's own), so a
+// tampered player response cannot point the fetch elsewhere.
+func captionTrackURL(watchURL *url.URL, baseURL string, translateTo string) (string, error) {
+	u, err := watchURL.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse caption URL: %w", err)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != watchURL.Hostname() && host != "youtube.com" && !strings.HasSuffix(host, ".youtube.com") {
+		return "", fmt.Errorf("unexpected caption host %q", host)
+	}
+	if translateTo != "" {
+		q := u.Query()
+		q.Set("tlang", translateTo)
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
+
+// parseTimedText reads YouTube's timedtext XML in either the classic
+// <text start="1.5"> form or the srv3 <p t="1500"> form.
+func parseTimedText(r io.Reader) ([]transcriptCue, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	var cues []transcriptCue
+	var current *transcriptCue
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return cues, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse transcript: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if start, ok := cueStart(t); ok {
+				current = &transcriptCue{start: start}
+				text.Reset()
+			}
+		case xml.CharData:
+			if current != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if current != nil && (t.Name.Local == "text" || t.Name.Local == "p") {
+				// Caption text is often entity-encoded twice ("&amp;#39;").
+				current.text = strings.Join(strings.Fields(html.UnescapeString(text.String())), " ")
+				if current.text != "" {
+					cues = append(cues, *current)
+				}
+				current = nil
+			}
+		}
+	}
+}
+
+func cueStart(el xml.StartElement) (time.Duration, bool) {
+	for _, attr := range el.Attr {
+		switch {
+		case el.Name.Local == "text" && attr.Name.Local == "start":
+			seconds, err := strconv.ParseFloat(attr.Value, 64)
+			if err != nil {
+				return 0, false
+			}
+			return time.Duration(seconds * float64(time.Second)), true
+		case el.Name.Local == "p" && attr.Name.Local == "t":
+			millis, err := strconv.ParseInt(attr.Value, 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			return time.Duration(millis) * time.Millisecond, true
+		}
+	}
+	return 0, false
+}
+
+// transcriptExcerpts joins cues into excerpts covering about window of
+// video each, prefixed with the start timestamp ("[12:34] ...") so answers
+// can point at the moment something was said.
+func transcriptExcerpts(cues []transcriptCue, window time.Duration) []string {
+	var excerpts []string
+	var sb strings.Builder
+	var excerptStart time.Duration
+	for _, cue := range cues {
+		if sb.Len() > 0 && cue.start-excerptStart >= window {
+			excerpts = append(excerpts, sb.String())
+			sb.Reset()
+		}
+		if sb.Len() == 0 {
+			excerptStart = cue.start
+			sb.WriteString("[" + formatTranscriptTimestamp(cue.start) + "]")
+		}
+		sb.WriteString(" " + cue.text)
+	}
+	if sb.Len() > 0 {
+		excerpts = append(excerpts, sb.String())
+	}
+	return excerpts
+}
+
+func formatTranscriptTimestamp(d time.Duration) string {
+	total := int(d / time.Second)
+	hours, minutes, seconds := total/3600, total/60%60, total%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// selectTranscriptExcerpts keeps the excerpts that mention the objective's
+// terms (see selectExcerpts). A summary request, or a question nothing
+// matches, gets excerpts spread evenly across the video instead of only its
+// opening minutes.
+func selectTranscriptExcerpts(excerpts []string, objective string, limit int) []string {
+	if len(excerpts) <= limit {
+		return excerpts
+	}
+	if objective != defaultExtractObjective && mentionsAnyTerm(excerpts, objectiveTerms(objective)) {
+		return selectExcerpts(excerpts, objective, limit)
+	}
+
+	sampled := make([]string, 0, limit)
+	for i := range limit {
+		sampled = append(sampled, excerpts[i*len(excerpts)/limit])
+	}
+	return sampled
+}
+
+func mentionsAnyTerm(excerpts []string, terms []string) bool {
+	for _, e := range excerpts {
+		lower := strings.ToLower(e)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadYouTubeTranscriptsEnabled reads YOUTUBE_TRANSCRIPTS_ENABLED,
+// defaulting to enabled.
+func loadYouTubeTranscriptsEnabled() bool {
+	raw := getenvTrim("YOUTUBE_TRANSCRIPTS_ENABLED")
+	if raw == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Warn().Str("value", raw).Msg("Invalid YOUTUBE_TRANSCRIPTS_ENABLED value; defaulting to enabled")
+		return true
+	}
+	return enabled
+}
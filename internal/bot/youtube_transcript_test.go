@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYouTubeVideoID(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ":            "dQw4w9WgXcQ",
+		"https://m.youtube.com/watch?v=dQw4w9WgXcQ&t=42s":        "dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?si=abc":                    "dQw4w9WgXcQ",
+		"https://www.youtube.com/shorts/dQw4w9WgXcQ":             "dQw4w9WgXcQ",
+		"https://www.youtube.com/embed/dQw4w9WgXcQ":              "dQw4w9WgXcQ",
+		"https://www.youtube.com/live/dQw4w9WgXcQ?feature=share": "dQw4w9WgXcQ",
+		"https://music.youtube.com/watch?v=dQw4w9WgXcQ":          "dQw4w9WgXcQ",
+		"https://www.youtube.com/@somechannel":                   "",
+		"https://www.youtube.com/watch?v=short":                  "",
+		"https://notyoutube.com/watch?v=dQw4w9WgXcQ":             "",
+		"https://example.com/dQw4w9WgXcQ":                        "",
+	}
+	for raw, want := range tests {
+		got, ok := youtubeVideoID(raw)
+		if got != want || ok != (want != "") {
+			t.Errorf("youtubeVideoID(%q) = %q, %v; want %q", raw, got, ok, want)
+		}
+	}
+
+	videos, pages := splitYouTubeURLs([]string{"https://example.com/a", "https://youtu.be/dQw4w9WgXcQ", "https://example.com/b"})
+	if len(videos) != 1 || len(pages) != 2 || pages[1] != "https://example.com/b" {
+		t.Fatalf("splitYouTubeURLs() = %v, %v", videos, pages)
+	}
+}
+
+func TestPickCaptionTrack(t *testing.T) {
+	t.Parallel()
+
+	tracks := []youtubeCaptionTrack{
+		{BaseURL: "en-asr", LanguageCode: "en", Kind: "asr"},
+		{BaseURL: "en-gb", LanguageCode: "en-GB"},
+		{BaseURL: "my", LanguageCode: "my"},
+	}
+
+	if track, translate, ok := pickCaptionTrack(tracks, transcriptLanguages(false)); !ok || track.BaseURL != "en-gb" || translate != "" {
+		t.Errorf("expected uploaded English captions first, got %+v %q", track, translate)
+	}
+	if track, _, ok := pickCaptionTrack(tracks, transcriptLanguages(true)); !ok || track.BaseURL != "my" {
+		t.Errorf("expected Burmese captions for a Burmese answer, got %+v", track)
+	}
+	if track, _, ok := pickCaptionTrack(tracks[:1], []string{"en"}); !ok || track.BaseURL != "en-asr" {
+		t.Errorf("expected automatic captions when nothing else exists, got %+v", track)
+	}
+
+	japanese := []youtubeCaptionTrack{{BaseURL: "ja", LanguageCode: "ja", IsTranslatable: true}}
+	if track, translate, ok := pickCaptionTrack(japanese, []string{"my", "en"}); !ok || track.BaseURL != "ja" || translate != "my" {
+		t.Errorf("expected a translated track, got %+v %q", track, translate)
+	}
+	if _, _, ok := pickCaptionTrack([]youtubeCaptionTrack{{LanguageCode: "ja"}}, []string{"en"}); ok {
+		t.Error("expected no track without a preferred or translatable one")
+	}
+}
+
+func TestParseTimedText(t *testing.T) {
+	t.Parallel()
+
+	classic := `<?xml version="1.0" encoding="utf-8" ?><transcript>` +
+		`<text start="0.5" dur="2.1">Hello &amp;amp; welcome</text>` +
+		`<text start="61.25" dur="3">it&amp;#39;s   here</text>` +
+		`<text start="70" dur="1"> </text></transcript>`
+	cues, err := parseTimedText(strings.NewReader(classic))
+	if err != nil {
+		t.Fatalf("parseTimedText() error = %v", err)
+	}
+	if len(cues) != 2 || cues[0].text != "Hello & welcome" || cues[1].text != "it's here" || cues[1].start != 61250*time.Millisecond {
+		t.Fatalf("unexpected classic cues %+v", cues)
+	}
+
+	srv3 := `<timedtext format="3"><body><p t="1500" d="2000"><s>first</s><s t="500"> words</s></p>` +
+		`<p t="3723000" d="1000">later</p></body></timedtext>`
+	cues, err = parseTimedText(strings.NewReader(srv3))
+	if err != nil {
+		t.Fatalf("parseTimedText() error = %v", err)
+	}
+	if len(cues) != 2 || cues[0].text != "first words" || cues[1].start != 3723*time.Second {
+		t.Fatalf("unexpected srv3 cues %+v", cues)
+	}
+}
+
+func TestTranscriptExcerpts(t *testing.T) {
+	t.Parallel()
+
+	cues := []transcriptCue{
+		{start: 0, text: "intro"},
+		{start: 30 * time.Second, text: "setup"},
+		{start: 65 * time.Second, text: "main point"},
+		{start: time.Hour + 2*time.Minute + 3*time.Second, text: "outro"},
+	}
+	got := transcriptExcerpts(cues, time.Minute)
+	want := []string{"[0:00] intro setup", "[1:05] main point", "[1:02:03] outro"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("transcriptExcerpts() = %q, want %q", got, want)
+	}
+}
+
+func TestSelectTranscriptExcerpts(t *testing.T) {
+	t.Parallel()
+
+	excerpts := make([]string, 20)
+	for i := range excerpts {
+		excerpts[i] = "[" + formatTranscriptTimestamp(time.Duration(i)*time.Minute) + "] general chatter about the topic at hand"
+	}
+	excerpts[15] = "[15:00] here we finally discuss the pricing of the product in detail"
+
+	sampled := selectTranscriptExcerpts(excerpts, defaultExtractObjective, 4)
+	if len(sampled) != 4 || sampled[0] != excerpts[0] || sampled[3] != excerpts[15] {
+		t.Fatalf("expected summary excerpts spread across the video, got %q", sampled)
+	}
+
+	matched := selectTranscriptExcerpts(excerpts, "what is the pricing?", 4)
+	if len(matched) == 0 || matched[0] != excerpts[15] {
+		t.Fatalf("expected the matching excerpt first, got %q", matched)
+	}
+
+	if got := selectTranscriptExcerpts(excerpts[:3], "anything", 4); len(got) != 3 {
+		t.Fatalf("expected short transcripts kept whole, got %d", len(got))
+	}
+}
+
+func TestCaptionTrackURL(t *testing.T) {
+	t.Parallel()
+
+	watch, _ := url.Parse("https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	got, err := captionTrackURL(watch, "/api/timedtext?v=dQw4w9WgXcQ&lang=ja", "en")
+	if err != nil || got != "https://www.youtube.com/api/timedtext?lang=ja&tlang=en&v=dQw4w9WgXcQ" {
+		t.Fatalf("captionTrackURL() = %q, %v", got, err)
+	}
+	if _, err := captionTrackURL(watch, "https://attacker.example/timedtext", ""); err == nil {
+		t.Fatal("expected a foreign caption host to be refused")
+	}
+}
+
+const testWatchPage = `<html><script>var ytInitialPlayerResponse = {
+"playabilityStatus": {"status": "OK"},
+"videoDetails": {"title": "Go concurrency patterns", "author": "GopherCon"},
+"captions": {"playerCaptionsTracklistRenderer": {"captionTracks": [
+	{"baseUrl": "/api/timedtext?v=dQw4w9WgXcQ&lang=en", "languageCode": "en", "kind": "asr"}
+]}},
+"microformat": {"playerMicroformatRenderer": {"publishDate": "2024-05-01"}}
+};var meta = {"unrelated": true};</script></html>`
+
+func TestYouTubeTranscriptFetcherFetchesTranscript(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			if r.URL.Query().Get("v") != "dQw4w9WgXcQ" {
+				t.Errorf("unexpected watch query %q", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(testWatchPage))
+		case "/api/timedtext":
+			_, _ = w.Write([]byte(`<transcript><text start="0" dur="2">channels connect goroutines</text>` +
+				`<text start="75" dur="2">select waits on many channels</text></transcript>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	server.Start()
+
+	fetcher := &youtubeTranscriptFetcher{client: server.Client(), watchURL: server.URL + "/watch", timeout: 5 * time.Second}
+	results := fetcher.fetchTranscripts(context.Background(),
+		[]string{"https://youtu.be/dQw4w9WgXcQ", "https://youtu.be/invalid"}, defaultExtractObjective, []string{"en"})
+
+	if len(results) != 1 {
+		t.Fatalf("expected one transcript, got %+v", results)
+	}
+	got := results[0]
+	if got.URL != "https://youtu.be/dQw4w9WgXcQ" || got.Title != "Go concurrency patterns (GopherCon)" || got.PublishDate != "2024-05-01" {
+		t.Errorf("unexpected result metadata %+v", got)
+	}
+	if len(got.Excerpts) != 2 || got.Excerpts[1] != "[1:15] select waits on many channels" {
+		t.Errorf("unexpected excerpts %q", got.Excerpts)
+	}
+}
+
+func TestYouTubeTranscriptFetcherNoCaptions(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"playabilityStatus": {"status": "OK"}};</script>`))
+	}))
+	server.Start()
+
+	fetcher := &youtubeTranscriptFetcher{client: server.Client(), watchURL: server.URL + "/watch"}
+	if _, err := fetcher.fetchTranscript(context.Background(), "https://youtu.be/dQw4w9WgXcQ", defaultExtractObjective, []string{"en"}); !errors.Is(err, errNoCaptions) {
+		t.Fatalf("expected errNoCaptions, got %v", err)
+	}
+
+	var nilFetcher *youtubeTranscriptFetcher
+	if results := nilFetcher.fetchTranscripts(context.Background(), []string{"https://youtu.be/dQw4w9WgXcQ"}, "objective", nil); results != nil {
+		t.Fatalf("expected a disabled fetcher to return nothing, got %+v", results)
+	}
+}
+
+func TestLoadYouTubeTranscriptsEnabled(t *testing.T) {
+	t.Setenv("EXTRACT_ENABLED", "")
+	t.Setenv("YOUTUBE_TRANSCRIPTS_ENABLED", "")
+	if newYouTubeTranscriptFetcher() == nil {
+		t.Fatal("expected transcripts enabled by default")
+	}
+	t.Setenv("YOUTUBE_TRANSCRIPTS_ENABLED", "false")
+	if newYouTubeTranscriptFetcher() != nil {
+		t.Fatal("expected YOUTUBE_TRANSCRIPTS_ENABLED=false to disable transcripts")
+	}
+}
+
+func TestExplainWithVideoSendsVideoURI(t *testing.T) {
+	t.Parallel()
+
+	gen := &capturingGenerator{}
+	explainer := &geminiExplainer{generator: gen}
+
+	if _, err := explainer.explainWithVideo(context.Background(), "https://youtu.be/dQw4w9WgXcQ", "", "what is the main point?", false); err != nil {
+		t.Fatalf("explainWithVideo() error = %v", err)
+	}
+	parts := gen.capturedContents[0].Parts
+	if len(parts) != 2 || parts[1].FileData == nil || parts[1].FileData.FileURI != "https://youtu.be/dQw4w9WgXcQ" {
+		t.Fatalf("expected the video URI as a file part, got %+v", parts)
+	}
+	payload := extractPromptPayload(t, parts[0].Text)
+	if payload.Question != "what is the main point?" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+
+	if _, err := explainer.explainWithVideo(context.Background(), " ", "", "q", false); err == nil {
+		t.Fatal("expected an empty video URL to fail")
+	}
+}
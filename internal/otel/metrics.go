@@ -26,10 +26,16 @@ func newMeterProvider(res *resource.Resource, exporter sdkmetric.Exporter) *sdkm
 // meter via newInstruments. When telemetry is disabled, a noop-meter-backed
 // set is returned so callers always get valid instruments.
 type InstrumentSet struct {
-	CommandsTotal    metric.Int64Counter
-	CommandDuration  metric.Float64Histogram
-	RateLimitedTotal metric.Int64Counter
-	GenAITokenUsage  metric.Float64Histogram
+	CommandsTotal        metric.Int64Counter
+	CommandDuration      metric.Float64Histogram
+	RateLimitedTotal     metric.Int64Counter
+	GenAITokenUsage      metric.Float64Histogram
+	CacheLookupsTotal    metric.Int64Counter
+	CacheBytesSavedTotal metric.Int64Counter
+
+	SearchDecisionsTotal           metric.Int64Counter
+	SearchClassifierAgreementTotal metric.Int64Counter
+	ExtractURLsDeniedTotal         metric.Int64Counter
 }
 
 // GenAI token types.
@@ -77,11 +83,62 @@ func newInstruments(meter metric.Meter) (*InstrumentSet, error) {
 		return nil, err
 	}
 
+	cacheLookupsTotal, err := meter.Int64Counter(
+		"bot.cache.lookups.total",
+		metric.WithUnit("1"),
+		metric.WithDescription("Number of cache lookups, by cache and result (hit or miss)."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheBytesSavedTotal, err := meter.Int64Counter(
+		"bot.cache.bytes_saved.total",
+		metric.WithUnit("By"),
+		metric.WithDescription("Estimated upstream response bytes served from cache instead of downloaded, by cache."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	searchDecisionsTotal, err := meter.Int64Counter(
+		"bot.search.decisions.total",
+		metric.WithUnit("1"),
+		metric.WithDescription("Web search decisions for ask questions, by decision source (rules or gemini) and verdict."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	searchClassifierAgreementTotal, err := meter.Int64Counter(
+		"bot.search.classifier_agreement.total",
+		metric.WithUnit("1"),
+		metric.WithDescription("Sampled rule decisions re-checked by Gemini, by rule verdict and whether Gemini agreed."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	extractURLsDeniedTotal, err := meter.Int64Counter(
+		"bot.extract.urls_denied.total",
+		metric.WithUnit("1"),
+		metric.WithDescription("Question links kept out of grounding by the domain allow/deny lists, by reason."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &InstrumentSet{
-		CommandsTotal:    commandsTotal,
-		CommandDuration:  commandDuration,
-		RateLimitedTotal: rateLimitedTotal,
-		GenAITokenUsage:  genAITokenUsage,
+		CommandsTotal:        commandsTotal,
+		CommandDuration:      commandDuration,
+		RateLimitedTotal:     rateLimitedTotal,
+		GenAITokenUsage:      genAITokenUsage,
+		CacheLookupsTotal:    cacheLookupsTotal,
+		CacheBytesSavedTotal: cacheBytesSavedTotal,
+
+		SearchDecisionsTotal:           searchDecisionsTotal,
+		SearchClassifierAgreementTotal: searchClassifierAgreementTotal,
+		ExtractURLsDeniedTotal:         extractURLsDeniedTotal,
 	}, nil
 }
 
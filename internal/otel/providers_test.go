@@ -142,4 +142,9 @@ func TestInstruments_NonNil(t *testing.T) {
 	require.NotNil(t, inst.CommandDuration)
 	require.NotNil(t, inst.RateLimitedTotal)
 	require.NotNil(t, inst.GenAITokenUsage)
+	require.NotNil(t, inst.CacheLookupsTotal)
+	require.NotNil(t, inst.CacheBytesSavedTotal)
+	require.NotNil(t, inst.SearchDecisionsTotal)
+	require.NotNil(t, inst.SearchClassifierAgreementTotal)
+	require.NotNil(t, inst.ExtractURLsDeniedTotal)
 }